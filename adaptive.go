@@ -0,0 +1,42 @@
+package gorl
+
+import (
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/internal/algorithms"
+)
+
+// NewAdaptive creates a limiter whose limit follows downstream health: report the outcome of
+// admitted work with Report, and the limit grows additively on success and shrinks
+// multiplicatively on errors or slow responses, between cfg.MinLimit and cfg.MaxLimit.
+// With Redis the limit is shared by every instance using the same cfg.Name.
+func NewAdaptive(cfg core.AdaptiveConfig) (core.AdaptiveLimiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Metrics = normalizeMetrics(cfg.Metrics)
+
+	constructor, ok := lookupStrategy(cfg.Strategy)
+	if !ok {
+		return nil, core.ErrUnknownStrategy
+	}
+
+	store, err := newStore(cfg.RedisURL, cfg.Clock)
+	if err != nil {
+		return nil, err
+	}
+
+	build := func(limit int) core.Limiter {
+		return constructor(core.Config{
+			Strategy: cfg.Strategy,
+			Limit:    limit,
+			Window:   cfg.Policy.Window,
+			Period:   cfg.Policy.Period,
+			Location: cfg.Location,
+			RedisURL: cfg.RedisURL,
+			FailOpen: cfg.FailOpen,
+			Metrics:  cfg.Metrics,
+			Clock:    cfg.Clock,
+		}, wrapSharedStore(store))
+	}
+	return algorithms.NewAdaptiveLimiter(cfg, store, build), nil
+}
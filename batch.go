@@ -0,0 +1,17 @@
+package gorl
+
+import (
+	"context"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/internal/algorithms"
+)
+
+// AllowMany checks many (key, cost) pairs against limiter in one call and returns one
+// core.BatchResult per request, in order. Limiters implementing core.BatchLimiter, which the
+// built-in strategies do, send the scripts of a whole batch to Redis in one pipelined round
+// trip; other limiters, and every limiter on the in-memory store, decide one request at a time.
+// Each request is decided on its own and reports its own error.
+func AllowMany(ctx context.Context, limiter core.Limiter, reqs []core.BatchRequest) []core.BatchResult {
+	return algorithms.AllowMany(ctx, limiter, reqs)
+}
@@ -0,0 +1,69 @@
+package gorl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+func TestAllowMany_ResultPerRequest(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    2,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	results := AllowMany(context.Background(), limiter, []core.BatchRequest{
+		{Key: "a", Cost: 1},
+		{Key: "a", Cost: 2},
+		{Key: "b", Cost: 2},
+		{Key: "c", Cost: 0},
+	})
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if !results[0].Allowed || results[1].Allowed || !results[2].Allowed {
+		t.Fatalf("expected allow, deny, allow; got %+v", results[:3])
+	}
+	if !errors.Is(results[3].Err, core.ErrInvalidCost) {
+		t.Fatalf("expected ErrInvalidCost for the zero cost, got %v", results[3].Err)
+	}
+}
+
+func TestAllowMany_Shadow(t *testing.T) {
+	var denied []string
+	limiter, err := New(core.Config{
+		Strategy: core.GCRA,
+		Limit:    1,
+		Window:   time.Minute,
+		Shadow:   true,
+		OnShadowDeny: func(_ context.Context, _, key string, _ core.Result) {
+			denied = append(denied, key)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, ok := limiter.(core.BatchLimiter); !ok {
+		t.Fatal("expected the shadow limiter to implement core.BatchLimiter")
+	}
+	results := AllowMany(context.Background(), limiter, []core.BatchRequest{
+		{Key: "k", Cost: 1},
+		{Key: "k", Cost: 1},
+	})
+	if !results[1].Allowed || !results[1].ShadowDenied {
+		t.Fatalf("expected a shadow admission, got %+v", results[1])
+	}
+	if len(denied) != 1 || denied[0] != "k" {
+		t.Fatalf("expected 1 would-be denial for k, got %v", denied)
+	}
+}
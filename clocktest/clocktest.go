@@ -0,0 +1,43 @@
+// Package clocktest provides a manually driven core.Clock for tests and simulations.
+//
+//	clock := clocktest.NewManual(time.Now())
+//	limiter, _ := gorl.New(core.Config{..., Clock: clock})
+//	clock.Advance(time.Minute) // the next window starts without sleeping
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a core.Clock that only moves when told to. It is safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManual returns a ManualClock stopped at start.
+func NewManual(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t, which may be in the past.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
@@ -0,0 +1,27 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+var _ core.Clock = (*ManualClock)(nil)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManual(start)
+
+	if !clock.Now().Equal(start) {
+		t.Fatalf("expected %v, got %v", start, clock.Now())
+	}
+	clock.Advance(90 * time.Second)
+	if want := start.Add(90 * time.Second); !clock.Now().Equal(want) {
+		t.Fatalf("expected %v after Advance, got %v", want, clock.Now())
+	}
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Fatalf("expected %v after Set, got %v", start, clock.Now())
+	}
+}
@@ -0,0 +1,101 @@
+package gorl
+
+import (
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/internal/algorithms"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// NewComposite creates a limiter that enforces every policy in cfg on the same key.
+// A request is charged against all policies or none, and the most restrictive Result is reported.
+// With Redis, all policies are checked and charged by one atomic script.
+// Supported strategies: FixedWindow, TokenBucket, SlidingWindow, LeakyBucket, GCRA, CalendarQuota.
+func NewComposite(cfg core.CompositeConfig) (core.Limiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Metrics = normalizeMetrics(cfg.Metrics)
+
+	store, members, err := newPolicyMembers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := algorithms.NewCompositeLimiter(core.Config{
+		FailOpen: cfg.FailOpen,
+		Metrics:  cfg.Metrics,
+		Clock:    cfg.Clock,
+	}, store, members)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	return limiter, nil
+}
+
+// NewHierarchical creates a limiter where a request must pass every level in cfg, such as a
+// per-user limit inside a per-tenant limit inside a global one. Level keys come from the
+// Descriptor passed to each call. With Redis, all levels are checked and charged by one atomic script.
+// Supported strategies are the same as for NewComposite.
+func NewHierarchical(cfg core.HierarchicalConfig) (core.HierarchicalLimiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Metrics = normalizeMetrics(cfg.Metrics)
+
+	policies := make([]core.ResourcePolicy, len(cfg.Levels))
+	for i, level := range cfg.Levels {
+		policies[i] = level.Policy
+	}
+	store, members, err := newPolicyMembers(core.CompositeConfig{
+		Strategy: cfg.Strategy,
+		Policies: policies,
+		RedisURL: cfg.RedisURL,
+		Location: cfg.Location,
+		Clock:    cfg.Clock,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := algorithms.NewHierarchicalLimiter(core.Config{
+		FailOpen: cfg.FailOpen,
+		Metrics:  cfg.Metrics,
+		Clock:    cfg.Clock,
+	}, store, cfg.Levels, members)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	return limiter, nil
+}
+
+// newPolicyMembers opens the store for cfg and builds one limiter per policy on top of it.
+// Members share the store and leave metrics and fail-open handling to the combining limiter.
+func newPolicyMembers(cfg core.CompositeConfig) (storage.Storage, []core.Limiter, error) {
+	constructor, ok := lookupStrategy(cfg.Strategy)
+	if !ok {
+		return nil, nil, core.ErrUnknownStrategy
+	}
+
+	store, err := newStore(cfg.RedisURL, cfg.Clock)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	members := make([]core.Limiter, len(cfg.Policies))
+	for i, policy := range cfg.Policies {
+		members[i] = constructor(core.Config{
+			Strategy: cfg.Strategy,
+			Limit:    policy.Limit,
+			Window:   policy.Window,
+			Period:   policy.Period,
+			Burst:    policy.Burst,
+			Rate:     policy.Rate,
+			Location: cfg.Location,
+			Metrics:  &core.NoopMetrics{},
+			Clock:    cfg.Clock,
+		}, wrapSharedStore(store))
+	}
+	return store, members, nil
+}
@@ -16,52 +16,137 @@ type resourceConfigDocument struct {
 	Strategy  core.StrategyType                 `json:"strategy" yaml:"strategy"`
 	RedisURL  string                            `json:"redis_url" yaml:"redis_url"`
 	FailOpen  bool                              `json:"fail_open" yaml:"fail_open"`
+	Location  string                            `json:"location" yaml:"location"`
 	Default   resourcePolicyDocument            `json:"default" yaml:"default"`
 	Resources map[string]resourcePolicyDocument `json:"resources" yaml:"resources"`
+	Rules     []resourceRuleDocument            `json:"rules" yaml:"rules"`
+	Allow     accessListDocument                `json:"allow" yaml:"allow"`
+	Deny      accessListDocument                `json:"deny" yaml:"deny"`
+	Fallback  fallbackDocument                  `json:"fallback" yaml:"fallback"`
 }
 
 type resourceConfigEnvelope struct {
 	GoRL *resourceConfigDocument `json:"gorl" yaml:"gorl"`
 }
 
+type accessListDocument struct {
+	Keys     []string `json:"keys" yaml:"keys"`
+	Prefixes []string `json:"prefixes" yaml:"prefixes"`
+	CIDRs    []string `json:"cidrs" yaml:"cidrs"`
+}
+
+type fallbackDocument struct {
+	Instances      int    `json:"instances" yaml:"instances"`
+	ErrorThreshold int    `json:"error_threshold" yaml:"error_threshold"`
+	Cooldown       string `json:"cooldown" yaml:"cooldown"`
+}
+
+// resourceRuleDocument is a rule whose policy fields sit next to its matching fields.
+type resourceRuleDocument struct {
+	ruleFields
+	Policy resourcePolicyDocument
+}
+
+type ruleFields struct {
+	Name    string         `json:"name" yaml:"name"`
+	Match   core.MatchType `json:"match" yaml:"match"`
+	Pattern string         `json:"pattern" yaml:"pattern"`
+	Method  string         `json:"method" yaml:"method"`
+}
+
+func (r *resourceRuleDocument) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.ruleFields); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.Policy)
+}
+
+func (r *resourceRuleDocument) UnmarshalYAML(data []byte) error {
+	if err := yaml.Unmarshal(data, &r.ruleFields); err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, &r.Policy)
+}
+
 type resourcePolicyDocument struct {
-	Limit  int    `json:"limit" yaml:"limit"`
-	Window string `json:"window" yaml:"window"`
+	Limit  int     `json:"limit" yaml:"limit"`
+	Window string  `json:"window" yaml:"window"`
+	Period string  `json:"period" yaml:"period"`
+	Burst  int     `json:"burst" yaml:"burst"`
+	Rate   float64 `json:"rate" yaml:"rate"`
+	Shadow bool    `json:"shadow" yaml:"shadow"`
+	// Overrides of the top-level settings
+	Strategy core.StrategyType `json:"strategy" yaml:"strategy"`
+	FailOpen *bool             `json:"fail_open" yaml:"fail_open"`
 }
 
 // LoadResourceConfig loads a resource-scoped limiter configuration from a JSON or YAML file.
 func LoadResourceConfig(path string) (core.ResourceConfig, error) {
+	doc, err := readDocument(path)
+	if err != nil {
+		return core.ResourceConfig{}, err
+	}
+	return doc.toCore()
+}
+
+// LoadBypassPolicy loads only the allow and deny lists of a JSON or YAML configuration file,
+// for use as core.Config.Bypass. The file has the same layout as for LoadResourceConfig.
+func LoadBypassPolicy(path string) (core.BypassPolicy, error) {
+	doc, err := readDocument(path)
+	if err != nil {
+		return core.BypassPolicy{}, err
+	}
+	policy := doc.bypassPolicy()
+	if err := policy.Validate(); err != nil {
+		return core.BypassPolicy{}, err
+	}
+	return policy, nil
+}
+
+func readDocument(path string) (resourceConfigDocument, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return core.ResourceConfig{}, fmt.Errorf("read config: %w", err)
+		return resourceConfigDocument{}, fmt.Errorf("read config: %w", err)
+	}
+	return parseDocument(path, data)
+}
+
+// parseResourceConfig is LoadResourceConfig for the content of path already read into data.
+func parseResourceConfig(path string, data []byte) (core.ResourceConfig, error) {
+	doc, err := parseDocument(path, data)
+	if err != nil {
+		return core.ResourceConfig{}, err
 	}
+	return doc.toCore()
+}
 
+// parseDocument decodes data in the format given by the extension of path.
+func parseDocument(path string, data []byte) (resourceConfigDocument, error) {
 	var doc resourceConfigDocument
 	var envelope resourceConfigEnvelope
 	switch strings.ToLower(filepath.Ext(path)) {
 	case ".json":
 		if err := json.Unmarshal(data, &doc); err != nil {
-			return core.ResourceConfig{}, fmt.Errorf("parse json config: %w", err)
+			return resourceConfigDocument{}, fmt.Errorf("parse json config: %w", err)
 		}
 		if err := json.Unmarshal(data, &envelope); err != nil {
-			return core.ResourceConfig{}, fmt.Errorf("parse json config envelope: %w", err)
+			return resourceConfigDocument{}, fmt.Errorf("parse json config envelope: %w", err)
 		}
 	case ".yaml", ".yml":
 		if err := yaml.Unmarshal(data, &doc); err != nil {
-			return core.ResourceConfig{}, fmt.Errorf("parse yaml config: %w", err)
+			return resourceConfigDocument{}, fmt.Errorf("parse yaml config: %w", err)
 		}
 		if err := yaml.Unmarshal(data, &envelope); err != nil {
-			return core.ResourceConfig{}, fmt.Errorf("parse yaml config envelope: %w", err)
+			return resourceConfigDocument{}, fmt.Errorf("parse yaml config envelope: %w", err)
 		}
 	default:
-		return core.ResourceConfig{}, fmt.Errorf("unsupported config format %q", filepath.Ext(path))
+		return resourceConfigDocument{}, fmt.Errorf("unsupported config format %q", filepath.Ext(path))
 	}
 
 	if envelope.GoRL != nil {
 		doc = *envelope.GoRL
 	}
-
-	return doc.toCore()
+	return doc, nil
 }
 
 func (d resourceConfigDocument) toCore() (core.ResourceConfig, error) {
@@ -79,12 +164,41 @@ func (d resourceConfigDocument) toCore() (core.ResourceConfig, error) {
 		resources[resource] = policy
 	}
 
+	var rules []core.ResourceRule
+	for _, ruleDoc := range d.Rules {
+		policy, err := ruleDoc.Policy.toCore(fmt.Sprintf("rule %q", ruleDoc.Name))
+		if err != nil {
+			return core.ResourceConfig{}, err
+		}
+		rules = append(rules, core.ResourceRule{
+			Name:    ruleDoc.Name,
+			Match:   ruleDoc.Match,
+			Pattern: ruleDoc.Pattern,
+			Method:  ruleDoc.Method,
+			Policy:  policy,
+		})
+	}
+
 	cfg := core.ResourceConfig{
 		Strategy:      d.Strategy,
 		DefaultPolicy: defaultPolicy,
 		Resources:     resources,
+		Rules:         rules,
 		RedisURL:      d.RedisURL,
 		FailOpen:      d.FailOpen,
+		Bypass:        d.bypassPolicy(),
+	}
+
+	if cfg.Fallback, err = d.Fallback.toCore(); err != nil {
+		return core.ResourceConfig{}, err
+	}
+
+	if d.Location != "" {
+		loc, err := time.LoadLocation(d.Location)
+		if err != nil {
+			return core.ResourceConfig{}, fmt.Errorf("location: %w", err)
+		}
+		cfg.Location = loc
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -93,13 +207,44 @@ func (d resourceConfigDocument) toCore() (core.ResourceConfig, error) {
 	return cfg, nil
 }
 
+func (d resourceConfigDocument) bypassPolicy() core.BypassPolicy {
+	return core.BypassPolicy{Allow: d.Allow.toCore(), Deny: d.Deny.toCore()}
+}
+
+func (l accessListDocument) toCore() core.AccessList {
+	return core.AccessList{Keys: l.Keys, Prefixes: l.Prefixes, CIDRs: l.CIDRs}
+}
+
+func (f fallbackDocument) toCore() (core.FallbackPolicy, error) {
+	policy := core.FallbackPolicy{Instances: f.Instances, ErrorThreshold: f.ErrorThreshold}
+	if f.Cooldown != "" {
+		cooldown, err := time.ParseDuration(f.Cooldown)
+		if err != nil {
+			return core.FallbackPolicy{}, fmt.Errorf("fallback cooldown: %w", err)
+		}
+		policy.Cooldown = cooldown
+	}
+	return policy, nil
+}
+
 func (p resourcePolicyDocument) toCore(label string) (core.ResourcePolicy, error) {
-	window, err := time.ParseDuration(p.Window)
-	if err != nil {
-		return core.ResourcePolicy{}, fmt.Errorf("%s window: %w", label, err)
+	policy := core.ResourcePolicy{
+		Limit:    p.Limit,
+		Period:   core.Period(p.Period),
+		Burst:    p.Burst,
+		Rate:     p.Rate,
+		Shadow:   p.Shadow,
+		Strategy: p.Strategy,
+		FailOpen: p.FailOpen,
+	}
+
+	// Calendar policies and token buckets shaped by burst and rate may leave the window out.
+	if p.Window != "" || (p.Period == "" && (p.Burst == 0 || p.Rate == 0)) {
+		window, err := time.ParseDuration(p.Window)
+		if err != nil {
+			return core.ResourcePolicy{}, fmt.Errorf("%s window: %w", label, err)
+		}
+		policy.Window = window
 	}
-	return core.ResourcePolicy{
-		Limit:  p.Limit,
-		Window: window,
-	}, nil
+	return policy, nil
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -21,7 +22,9 @@ func TestLoadResourceConfig_JSON(t *testing.T) {
   "resources": {
     "login": {
       "limit": 5,
-      "window": "1m"
+      "window": "1m",
+      "strategy": "sliding_log",
+      "fail_open": false
     },
     "search": {
       "limit": 50,
@@ -50,6 +53,12 @@ func TestLoadResourceConfig_JSON(t *testing.T) {
 	if cfg.Resources["login"].Limit != 5 {
 		t.Fatalf("unexpected login limit: %+v", cfg.Resources["login"])
 	}
+	if login := cfg.Resources["login"]; login.Strategy != core.SlidingLog || login.FailOpen == nil || *login.FailOpen {
+		t.Fatalf("expected login to override strategy and fail_open, got %+v", login)
+	}
+	if cfg.Resources["search"].FailOpen != nil {
+		t.Fatal("expected search to inherit fail_open")
+	}
 	if cfg.Resources["search"].Window != time.Second {
 		t.Fatalf("unexpected search window: %+v", cfg.Resources["search"])
 	}
@@ -66,6 +75,7 @@ gorl:
     outbound-api:
       limit: 10
       window: 1s
+      shadow: true
 `)
 
 	cfg, err := LoadResourceConfig(path)
@@ -79,7 +89,7 @@ gorl:
 	if cfg.DefaultPolicy.Window != 30*time.Second {
 		t.Fatalf("unexpected default window: %v", cfg.DefaultPolicy.Window)
 	}
-	if cfg.Resources["outbound-api"].Limit != 10 {
+	if cfg.Resources["outbound-api"].Limit != 10 || !cfg.Resources["outbound-api"].Shadow {
 		t.Fatalf("unexpected named resource policy: %+v", cfg.Resources["outbound-api"])
 	}
 }
@@ -130,6 +140,75 @@ default:
 	}
 }
 
+func TestLoadResourceConfig_CalendarQuota(t *testing.T) {
+	path := writeTempConfig(t, "resource-config.yaml", `
+strategy: calendar_quota
+location: Europe/Istanbul
+default:
+  limit: 10000
+  period: month
+resources:
+  export:
+    limit: 50
+    period: day
+`)
+
+	cfg, err := LoadResourceConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Location == nil || cfg.Location.String() != "Europe/Istanbul" {
+		t.Fatalf("unexpected location: %v", cfg.Location)
+	}
+	if cfg.DefaultPolicy.Period != core.PeriodMonth || cfg.DefaultPolicy.Window != 0 {
+		t.Fatalf("unexpected default policy: %+v", cfg.DefaultPolicy)
+	}
+	if cfg.Resources["export"].Period != core.PeriodDay {
+		t.Fatalf("unexpected export policy: %+v", cfg.Resources["export"])
+	}
+}
+
+func TestLoadResourceConfig_TokenBucketBurstAndRate(t *testing.T) {
+	path := writeTempConfig(t, "resource-config.yaml", `
+strategy: token_bucket
+default:
+  burst: 50
+  rate: 5
+resources:
+  search:
+    limit: 100
+    window: 1m
+    burst: 20
+`)
+
+	cfg, err := LoadResourceConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DefaultPolicy.Burst != 50 || cfg.DefaultPolicy.Rate != 5 || cfg.DefaultPolicy.Window != 0 {
+		t.Fatalf("unexpected default policy: %+v", cfg.DefaultPolicy)
+	}
+	if search := cfg.Resources["search"]; search.Burst != 20 || search.Window != time.Minute {
+		t.Fatalf("unexpected search policy: %+v", search)
+	}
+}
+
+func TestLoadResourceConfig_UnknownLocation(t *testing.T) {
+	path := writeTempConfig(t, "resource-config.yaml", `
+strategy: calendar_quota
+location: Mars/Olympus_Mons
+default:
+  limit: 10
+  period: day
+`)
+
+	if _, err := LoadResourceConfig(path); err == nil {
+		t.Fatal("expected unknown location error")
+	}
+}
+
 func TestLoadResourceConfig_UnsupportedExtension(t *testing.T) {
 	path := writeTempConfig(t, "resource-config.txt", "hello")
 
@@ -164,3 +243,110 @@ func writeTempConfig(t *testing.T, name, content string) string {
 	}
 	return path
 }
+
+func TestLoadResourceConfig_AccessLists(t *testing.T) {
+	path := writeTempConfig(t, "resource-config.yaml", `
+strategy: fixed_window
+default:
+  limit: 10
+  window: 1m
+allow:
+  keys: [health-check]
+  cidrs: [10.0.0.0/8]
+deny:
+  prefixes: ["banned:"]
+`)
+
+	cfg, err := LoadResourceConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Bypass.Allow.Keys) != 1 || cfg.Bypass.Allow.CIDRs[0] != "10.0.0.0/8" {
+		t.Fatalf("unexpected allow list: %+v", cfg.Bypass.Allow)
+	}
+
+	policy, err := LoadBypassPolicy(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.Deny.Prefixes) != 1 || policy.Deny.Prefixes[0] != "banned:" {
+		t.Fatalf("unexpected deny list: %+v", policy.Deny)
+	}
+}
+
+func TestLoadBypassPolicy_InvalidRange(t *testing.T) {
+	path := writeTempConfig(t, "lists.json", `{"deny": {"cidrs": ["10.0.0.0/40"]}}`)
+
+	_, err := LoadBypassPolicy(path)
+	if !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid, got %v", err)
+	}
+}
+
+func TestLoadResourceConfig_Rules(t *testing.T) {
+	path := writeTempConfig(t, "resource-config.yaml", `
+strategy: fixed_window
+default:
+  limit: 100
+  window: 1m
+rules:
+  - name: exports
+    match: glob
+    pattern: /api/*/export
+    method: GET
+    limit: 5
+    window: 1h
+  - name: users
+    match: prefix
+    pattern: /api/v1/users/
+    limit: 20
+    window: 1m
+`)
+
+	cfg, err := LoadResourceConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(cfg.Rules))
+	}
+	exports := cfg.Rules[0]
+	if exports.Name != "exports" || exports.Match != core.MatchGlob || exports.Method != "GET" {
+		t.Fatalf("unexpected first rule: %+v", exports)
+	}
+	if exports.Policy.Limit != 5 || exports.Policy.Window != time.Hour {
+		t.Fatalf("unexpected first rule policy: %+v", exports.Policy)
+	}
+}
+
+func TestLoadResourceConfig_Fallback(t *testing.T) {
+	path := writeTempConfig(t, "resource-config.yaml", `
+strategy: sliding_window
+redis_url: redis://localhost:6379/0
+default:
+  limit: 100
+  window: 1m
+fallback:
+  instances: 4
+  error_threshold: 3
+  cooldown: 10s
+`)
+
+	cfg, err := LoadResourceConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := core.FallbackPolicy{Instances: 4, ErrorThreshold: 3, Cooldown: 10 * time.Second}
+	if cfg.Fallback != want {
+		t.Fatalf("expected fallback %+v, got %+v", want, cfg.Fallback)
+	}
+
+	path = writeTempConfig(t, "fail-open.json", `{
+  "default": {"limit": 100, "window": "1m"},
+  "resources": {"login": {"limit": 5, "window": "1m", "fail_open": true}},
+  "fallback": {"instances": 4}
+}`)
+	if _, err := LoadResourceConfig(path); !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for a fail-open policy with fallback, got %v", err)
+	}
+}
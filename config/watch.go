@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// DefaultWatchInterval is how often WatchResourceConfig checks the file when no interval is set.
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchOptions tunes WatchResourceConfig.
+type WatchOptions struct {
+	Interval time.Duration // How often the file is checked (0 -> DefaultWatchInterval)
+	// Optional: called when a changed file cannot be read, loaded or applied.
+	// The limiter keeps its current policies.
+	OnError func(error)
+	// Optional: called after the limiter switched to the policies of a changed file.
+	OnReload func(core.ResourceConfig)
+}
+
+// WatchResourceConfig reloads the policies of limiter whenever the file at path changes.
+// The file is polled, so it works on every platform and with atomic renames used by editors
+// and config management tools. Changed content is parsed as by LoadResourceConfig and applied with
+// limiter.Update; an invalid file is rejected and reported through opts.OnError.
+//
+// The content found when the watch starts is assumed to be applied already. WatchResourceConfig
+// blocks until ctx is done and then returns ctx.Err().
+func WatchResourceConfig(ctx context.Context, path string, limiter core.ReloadableResourceLimiter, opts WatchOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last, readErr := os.ReadFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// Report a missing or unreadable file once, not on every tick.
+			if readErr == nil {
+				opts.report(fmt.Errorf("read config: %w", err))
+			}
+			readErr = err
+			continue
+		}
+		if readErr == nil && bytes.Equal(data, last) {
+			continue
+		}
+		readErr = nil
+		last = data
+
+		cfg, err := parseResourceConfig(path, data)
+		if err != nil {
+			opts.report(err)
+			continue
+		}
+		if err := limiter.Update(cfg); err != nil {
+			opts.report(fmt.Errorf("apply config: %w", err))
+			continue
+		}
+		if opts.OnReload != nil {
+			opts.OnReload(cfg)
+		}
+	}
+}
+
+func (o WatchOptions) report(err error) {
+	if o.OnError != nil {
+		o.OnError(err)
+	}
+}
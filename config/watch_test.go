@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// recordingLimiter captures the configurations passed to Update.
+type recordingLimiter struct {
+	core.ResourceLimiter
+	updates chan core.ResourceConfig
+}
+
+func (l *recordingLimiter) Update(cfg core.ResourceConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	l.updates <- cfg
+	return nil
+}
+
+func TestWatchResourceConfig(t *testing.T) {
+	path := writeTempConfig(t, "limits.yaml", "strategy: fixed_window\ndefault:\n  limit: 10\n  window: 1m\n")
+	limiter := &recordingLimiter{updates: make(chan core.ResourceConfig, 4)}
+	errs := make(chan error, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchResourceConfig(ctx, path, limiter, WatchOptions{
+			Interval: 5 * time.Millisecond,
+			OnError:  func(err error) { errs <- err },
+		})
+	}()
+
+	rewrite := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("rewrite config: %v", err)
+		}
+	}
+
+	// Let the watcher record the initial content before changing it.
+	time.Sleep(50 * time.Millisecond)
+	rewrite("strategy: fixed_window\ndefault:\n  limit: 20\n  window: 1m\n")
+	select {
+	case cfg := <-limiter.updates:
+		if cfg.DefaultPolicy.Limit != 20 {
+			t.Fatalf("expected the new limit to be applied, got %d", cfg.DefaultPolicy.Limit)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the reload")
+	}
+
+	rewrite("strategy: fixed_window\ndefault:\n  limit: 0\n  window: 1m\n")
+	select {
+	case cfg := <-limiter.updates:
+		t.Fatalf("expected the invalid file to be rejected, got limit %d", cfg.DefaultPolicy.Limit)
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the invalid file to be reported")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(limiter.updates) != 0 {
+		t.Fatalf("expected no further updates, got %d", len(limiter.updates))
+	}
+}
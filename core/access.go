@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// AccessList matches rate-limit keys by exact value, by prefix or, for keys that are IP
+// addresses, by network range.
+type AccessList struct {
+	Keys     []string // Keys matched exactly
+	Prefixes []string // Keys starting with any of these prefixes match
+	CIDRs    []string // IPv4 or IPv6 ranges such as "10.0.0.0/8"; a bare address matches only itself
+}
+
+// Empty reports whether the list matches nothing.
+func (l AccessList) Empty() bool {
+	return len(l.Keys) == 0 && len(l.Prefixes) == 0 && len(l.CIDRs) == 0
+}
+
+// Compile parses the ranges of the list into an AccessMatcher.
+func (l AccessList) Compile() (*AccessMatcher, error) {
+	m := &AccessMatcher{
+		keys:     make(map[string]struct{}, len(l.Keys)),
+		prefixes: append([]string(nil), l.Prefixes...),
+	}
+	for _, key := range l.Keys {
+		m.keys[key] = struct{}{}
+	}
+	for _, prefix := range l.Prefixes {
+		if prefix == "" {
+			return nil, fmt.Errorf("%w: access list prefix must not be empty", ErrConfigInvalid)
+		}
+	}
+	for _, cidr := range l.CIDRs {
+		network, err := parseNetwork(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: access list range %q: %v", ErrConfigInvalid, cidr, err)
+		}
+		m.networks = append(m.networks, network)
+	}
+	return m, nil
+}
+
+func parseNetwork(cidr string) (netip.Prefix, error) {
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	network, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if network.Addr().Is4In6() {
+		network = netip.PrefixFrom(network.Addr().Unmap(), network.Bits()-96)
+	}
+	return network.Masked(), nil
+}
+
+// AccessMatcher is a compiled AccessList. A nil matcher matches nothing.
+type AccessMatcher struct {
+	keys     map[string]struct{}
+	prefixes []string
+	networks []netip.Prefix
+}
+
+// Match reports whether key is on the list. IPv4-mapped IPv6 keys match IPv4 ranges.
+func (m *AccessMatcher) Match(key string) bool {
+	if m == nil {
+		return false
+	}
+	if _, ok := m.keys[key]; ok {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	if len(m.networks) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(key)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, network := range m.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// BypassPolicy decides requests by key before any rate limiting takes place: keys on Deny are
+// always rejected and keys on Allow are always admitted. Deny wins when a key is on both.
+// The zero value bypasses nothing.
+type BypassPolicy struct {
+	Allow AccessList // Keys that are never rate limited
+	Deny  AccessList // Keys that are always rejected
+}
+
+// Empty reports whether the policy bypasses nothing.
+func (p BypassPolicy) Empty() bool {
+	return p.Allow.Empty() && p.Deny.Empty()
+}
+
+// Validate checks that every range of the policy parses.
+func (p BypassPolicy) Validate() error {
+	_, err := p.Compile()
+	return err
+}
+
+// Compile parses the policy into a Bypass.
+func (p BypassPolicy) Compile() (*Bypass, error) {
+	allow, err := p.Allow.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("allow list: %w", err)
+	}
+	deny, err := p.Deny.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("deny list: %w", err)
+	}
+	return &Bypass{allow: allow, deny: deny}, nil
+}
+
+// Bypass is a compiled BypassPolicy. A nil Bypass decides nothing.
+type Bypass struct {
+	allow *AccessMatcher
+	deny  *AccessMatcher
+}
+
+// Check returns the decision for key when an access list covers it, with Result.Bypassed set,
+// and false when the key must be rate limited as usual.
+func (b *Bypass) Check(key string) (Result, bool) {
+	if b == nil {
+		return Result{}, false
+	}
+	if b.deny.Match(key) {
+		return Result{Allowed: false, Bypassed: true}, true
+	}
+	if b.allow.Match(key) {
+		return Result{Allowed: true, Bypassed: true}, true
+	}
+	return Result{}, false
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultAdaptiveSyncInterval is how often an adaptive limiter re-reads the shared limit when
+// AdaptiveConfig.SyncInterval is zero.
+const DefaultAdaptiveSyncInterval = time.Second
+
+// AdaptiveConfig holds the configuration for a limiter whose limit follows the health of a
+// downstream dependency with additive-increase/multiplicative-decrease (AIMD).
+type AdaptiveConfig struct {
+	Strategy StrategyType   // Rate limiting algorithm applied with the current limit
+	Policy   ResourcePolicy // Window or Period of the strategy; Limit is the starting limit (0 -> MaxLimit)
+	MinLimit int            // Lowest limit failures can push the limit down to
+	MaxLimit int            // Highest limit successes can raise the limit up to
+	Increase float64        // Added to the limit for each success (0 -> 1)
+	Decrease float64        // Factor the limit is multiplied by for each failure, between 0 and 1 (0 -> 0.5)
+	// Optional: successes slower than this count as failures (0 -> latency is ignored)
+	LatencyThreshold time.Duration
+	Name             string         // Identifies the shared limit, so instances with the same Name adapt together (empty -> "default")
+	SyncInterval     time.Duration  // How often the shared limit is re-read from storage (0 -> DefaultAdaptiveSyncInterval)
+	RedisURL         string         // Redis connection string for distributed mode
+	FailOpen         bool           // If true, allow requests when backend is unavailable
+	Location         *time.Location // Time zone for calendar periods (nil -> UTC)
+	// Optional: metrics collector (nil -> NoopMetrics). Collectors implementing LimitObserver
+	// also receive every change of the limit.
+	Metrics MetricsCollector
+	// Optional: source of the current time (nil -> SystemClock)
+	Clock Clock
+}
+
+// Validate checks the adaptive configuration for common errors.
+func (c AdaptiveConfig) Validate() error {
+	if c.Strategy == Concurrency {
+		return fmt.Errorf("%w: adaptive limits do not apply to the concurrency strategy", ErrConfigInvalid)
+	}
+	if c.MinLimit <= 0 {
+		return fmt.Errorf("%w: min limit must be greater than 0", ErrConfigInvalid)
+	}
+	if c.MaxLimit < c.MinLimit {
+		return fmt.Errorf("%w: max limit must not be below min limit", ErrConfigInvalid)
+	}
+	if c.Policy.Limit != 0 && (c.Policy.Limit < c.MinLimit || c.Policy.Limit > c.MaxLimit) {
+		return fmt.Errorf("%w: starting limit must be between min and max limit", ErrConfigInvalid)
+	}
+	if c.Policy.Burst != 0 || c.Policy.Rate != 0 {
+		return fmt.Errorf("%w: adaptive limits scale Limit, burst and rate must be unset", ErrConfigInvalid)
+	}
+	if c.Increase < 0 {
+		return fmt.Errorf("%w: increase must not be negative", ErrConfigInvalid)
+	}
+	if c.Decrease < 0 || c.Decrease >= 1 {
+		return fmt.Errorf("%w: decrease must be between 0 and 1", ErrConfigInvalid)
+	}
+	if c.LatencyThreshold < 0 {
+		return fmt.Errorf("%w: latency threshold must not be negative", ErrConfigInvalid)
+	}
+	policy := c.Policy
+	policy.Limit = c.MaxLimit
+	return validateSharedPolicy(c.Strategy, policy)
+}
+
+// Outcome describes how one unit of work admitted by an adaptive limiter went downstream.
+type Outcome struct {
+	Err     error         // Non-nil when the work failed
+	Latency time.Duration // Optional: how long the work took
+}
+
+// AdaptiveLimiter is a Limiter whose limit grows while the work it admits succeeds and shrinks
+// when it fails or slows down.
+type AdaptiveLimiter interface {
+	Limiter
+	// Report feeds the outcome of admitted work back into the limit. Failures and successes slower
+	// than the latency threshold multiply it by Decrease; other successes add Increase to it.
+	Report(ctx context.Context, outcome Outcome) error
+	// CurrentLimit returns the effective limit as last seen by this instance.
+	CurrentLimit() int
+}
@@ -0,0 +1,25 @@
+package core
+
+import "context"
+
+// BatchRequest is one (key, cost) pair of a batch, as passed to AllowN.
+type BatchRequest struct {
+	Key  string
+	Cost int // Units to consume; must be positive like the n of AllowN
+}
+
+// BatchResult is the outcome of one BatchRequest: the Result and error AllowN would have
+// returned for it.
+type BatchResult struct {
+	Result
+	Err error
+}
+
+// BatchLimiter is implemented by limiters that can check many keys in one call. With Redis the
+// requests share one pipelined round trip instead of one each. Every request is decided on its
+// own, exactly as by AllowN; the batch as a whole is not atomic.
+type BatchLimiter interface {
+	Limiter
+	// AllowMany checks every request and returns one BatchResult per request, in order.
+	AllowMany(ctx context.Context, reqs []BatchRequest) []BatchResult
+}
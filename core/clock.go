@@ -0,0 +1,17 @@
+package core
+
+import "time"
+
+// Clock tells the current time. Limiters and the in-memory store read the time only through
+// their Clock, so tests and simulations can control it instead of sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by time.Now. It is used when no Clock is configured.
+type SystemClock struct{}
+
+// Now returns the current local time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
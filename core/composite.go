@@ -0,0 +1,33 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// CompositeConfig holds the configuration for a limiter that enforces several policies on the
+// same key, such as "10/second and 1000/hour". A request is only charged when every policy allows it.
+type CompositeConfig struct {
+	Strategy StrategyType     // Rate limiting algorithm applied to every policy
+	Policies []ResourcePolicy // Policies that must all allow a request
+	RedisURL string           // Redis connection string for distributed mode
+	FailOpen bool             // If true, allow requests when backend is unavailable
+	Location *time.Location   // Time zone for calendar periods (nil -> UTC)
+	// Optional: metrics collector (nil -> NoopMetrics)
+	Metrics MetricsCollector
+	// Optional: source of the current time (nil -> SystemClock)
+	Clock Clock
+}
+
+// Validate checks the composite configuration for common errors.
+func (c CompositeConfig) Validate() error {
+	if len(c.Policies) == 0 {
+		return fmt.Errorf("%w: at least one policy is required", ErrConfigInvalid)
+	}
+	for i, policy := range c.Policies {
+		if err := validateSharedPolicy(c.Strategy, policy); err != nil {
+			return fmt.Errorf("policy %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -15,6 +16,18 @@ var (
 	ErrConfigInvalid = errors.New("invalid configuration")
 	// ErrUnknownStrategy indicates that the requested rate limiting strategy is not supported.
 	ErrUnknownStrategy = errors.New("unknown rate limiting strategy")
+	// ErrInvalidCost indicates that a weighted request asked for a non-positive cost.
+	ErrInvalidCost = errors.New("invalid cost")
+	// ErrCostExceedsLimit indicates that a weighted request costs more than the limiter can ever grant.
+	ErrCostExceedsLimit = errors.New("cost exceeds limit")
+	// ErrWouldExceedDeadline indicates that waiting for a permit would outlast the context deadline.
+	ErrWouldExceedDeadline = errors.New("wait would exceed context deadline")
+	// ErrReservationCanceled indicates that a reservation was canceled before its permit was granted.
+	ErrReservationCanceled = errors.New("reservation canceled")
+	// ErrKeyDenied indicates that a denylist rejects the key, so waiting can never grant a permit.
+	ErrKeyDenied = errors.New("key denied")
+	// ErrIncompleteDescriptor indicates that a descriptor lacks a field required by a hierarchical limit.
+	ErrIncompleteDescriptor = errors.New("incomplete descriptor")
 )
 
 // StrategyType represents the available rate limiting algorithms.
@@ -29,6 +42,18 @@ const (
 	TokenBucket StrategyType = "token_bucket"
 	// LeakyBucket is the leaky bucket algorithm.
 	LeakyBucket StrategyType = "leaky_bucket"
+	// SlidingLog keeps every request timestamp and counts the trailing window exactly.
+	// Memory grows with Limit, so it suits strict low-volume limits.
+	SlidingLog StrategyType = "sliding_log"
+	// GCRA is the generic cell rate algorithm: evenly spaced requests with bursts up to Limit,
+	// stored as a single timestamp per key.
+	GCRA StrategyType = "gcra"
+	// CalendarQuota counts requests per calendar period (hour, day, week or month) in a
+	// configurable time zone. It uses Period and Location instead of Window.
+	CalendarQuota StrategyType = "calendar_quota"
+	// Concurrency bounds in-flight work: Limit is the number of leases a key may hold
+	// at once and Window is the lease TTL.
+	Concurrency StrategyType = "concurrency"
 )
 
 // Config holds the configuration for creating a rate limiter.
@@ -38,12 +63,56 @@ type Config struct {
 	Window   time.Duration // Time window duration
 	RedisURL string        // Redis connection string for distributed mode
 	FailOpen bool          // If true, allow requests when backend is unavailable
+	// Calendar period and time zone for the CalendarQuota strategy (nil Location → UTC)
+	Period   Period
+	Location *time.Location
+	// Bucket capacity and sustained refill rate in tokens per second for the TokenBucket
+	// strategy (0 → Limit and Limit per Window)
+	Burst int
+	Rate  float64
+	// Optional: temporary bans for keys that keep getting denied (zero → disabled)
+	Penalty PenaltyPolicy
+	// Optional: keys that are always admitted or always rejected (zero → none)
+	Bypass BypassPolicy
+	// Optional: evaluate and update state but always allow; would-be denials go to Metrics
+	// collectors implementing ShadowObserver and to OnShadowDeny
+	Shadow       bool
+	OnShadowDeny ShadowHook
+	// Optional: lease permits from the shared budget in batches and spend them locally
+	// (zero → disabled)
+	PermitLease PermitLeasePolicy
+	// Optional: decide requests on a local in-memory limiter while Redis fails, instead of
+	// failing open or closed (zero → disabled; excludes FailOpen)
+	Fallback FallbackPolicy
 	// Optional: metrics collector (nil → NoopMetrics)
 	Metrics MetricsCollector
+	// Optional: source of the current time (nil → SystemClock)
+	Clock Clock
 }
 
 // Validate checks the configuration for common errors.
 func (c Config) Validate() error {
+	if err := c.Penalty.Validate(); err != nil {
+		return err
+	}
+	if err := c.Bypass.Validate(); err != nil {
+		return err
+	}
+	if err := c.validatePermitLease(); err != nil {
+		return err
+	}
+	if err := c.Fallback.Validate(); err != nil {
+		return err
+	}
+	if c.Fallback.Instances > 0 && c.FailOpen {
+		return fmt.Errorf("%w: fallback and fail-open are mutually exclusive", ErrConfigInvalid)
+	}
+	switch c.Strategy {
+	case CalendarQuota:
+		return validateLimitPeriod(c.Limit, c.Period)
+	case TokenBucket:
+		return validateBucket(c.Limit, c.Window, c.Burst, c.Rate)
+	}
 	return validateLimitWindow(c.Limit, c.Window)
 }
 
@@ -54,12 +123,47 @@ type Result struct {
 	Remaining  int           // Remaining whole-request capacity after this decision
 	Reset      time.Duration // Time until the limiter fully resets or refills if no further requests are made
 	RetryAfter time.Duration // Earliest reliable delay before the next denied request may be allowed again
+	Bypassed   bool          // True if an allowlist or denylist decided the request without rate limiting
+	// Shadow mode: Shadow is true for every result of a limiter in shadow mode, which always
+	// allows; ShadowDenied is true when the strategy would have denied the request
+	Shadow       bool
+	ShadowDenied bool
+	Fallback     bool // True if a local fallback limiter decided the request while the backend was failing
 }
 
 // Limiter defines the interface that all rate limiting strategies must implement.
 type Limiter interface {
 	// Allow returns a Result indicating if the request is permitted and metadata about the state.
 	Allow(ctx context.Context, key string) (Result, error)
+	// AllowN is like Allow but consumes n units of capacity at once.
+	// Nothing is consumed when the request is denied.
+	AllowN(ctx context.Context, key string, n int) (Result, error)
+	// Peek reports the current state for key without consuming capacity.
+	// Allowed tells whether a single-unit request would be permitted right now.
+	Peek(ctx context.Context, key string) (Result, error)
+	// Refund gives back n previously consumed units for key, for example when an admitted
+	// request fails before doing any work. State never goes beyond full capacity.
+	Refund(ctx context.Context, key string, n int) error
+	// Reset forgets all state stored for key, so its next request starts from full capacity.
+	Reset(ctx context.Context, key string) error
 	// Close releases any resources held by the limiter.
 	Close() error
 }
+
+// Lease identifies a concurrency slot handed out by LeaseLimiter.Acquire.
+// The zero Lease holds nothing and releasing it is a no-op.
+type Lease struct {
+	Key string // Key the slot was acquired for
+	ID  string // Backend-specific lease identifier
+}
+
+// LeaseLimiter is implemented by strategies that hand out slots which must be given back,
+// such as Concurrency. Allow and AllowN still work but acquire anonymous leases that are
+// only returned through Refund or by expiring.
+type LeaseLimiter interface {
+	Limiter
+	// Acquire takes one slot for key. The returned Lease is only meaningful when Result.Allowed is true.
+	Acquire(ctx context.Context, key string) (Lease, Result, error)
+	// Release gives the slot held by lease back before its TTL runs out.
+	Release(ctx context.Context, lease Lease) error
+}
@@ -61,6 +61,91 @@ func TestConfig_Validate_InvalidWindow(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_TokenBucketShape(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"burst and rate without limit", Config{Burst: 50, Rate: 5}, true},
+		{"burst with limit and window", Config{Limit: 5, Window: time.Second, Burst: 50}, true},
+		{"burst without rate or window", Config{Burst: 50}, false},
+		{"negative burst", Config{Limit: 5, Window: time.Second, Burst: -1}, false},
+		{"negative rate", Config{Burst: 50, Rate: -5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Strategy = TokenBucket
+			err := tt.cfg.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("expected valid config, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrConfigInvalid) {
+				t.Fatalf("expected ErrConfigInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_PermitLease(t *testing.T) {
+	lease := func(batch int, ttl time.Duration) PermitLeasePolicy {
+		return PermitLeasePolicy{Batch: batch, TTL: ttl}
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"batch within limit", Config{Strategy: FixedWindow, Limit: 100, Window: time.Minute, PermitLease: lease(10, 0)}, true},
+		{"batch within burst", Config{Strategy: TokenBucket, Burst: 50, Rate: 5, PermitLease: lease(50, time.Second)}, true},
+		{"negative batch", Config{Strategy: FixedWindow, Limit: 100, Window: time.Minute, PermitLease: lease(-1, 0)}, false},
+		{"negative TTL", Config{Strategy: FixedWindow, Limit: 100, Window: time.Minute, PermitLease: lease(10, -time.Second)}, false},
+		{"batch above limit", Config{Strategy: GCRA, Limit: 5, Window: time.Minute, PermitLease: lease(10, 0)}, false},
+		{"TTL above window", Config{Strategy: FixedWindow, Limit: 100, Window: time.Second, PermitLease: lease(10, time.Minute)}, false},
+		{"concurrency", Config{Strategy: Concurrency, Limit: 100, Window: time.Minute, PermitLease: lease(10, 0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("expected valid config, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrConfigInvalid) {
+				t.Fatalf("expected ErrConfigInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Fallback(t *testing.T) {
+	base := Config{Strategy: FixedWindow, Limit: 100, Window: time.Minute}
+	tests := []struct {
+		name     string
+		fallback FallbackPolicy
+		failOpen bool
+		valid    bool
+	}{
+		{"disabled", FallbackPolicy{}, true, true},
+		{"instances", FallbackPolicy{Instances: 4, ErrorThreshold: 3, Cooldown: time.Second}, false, true},
+		{"negative instances", FallbackPolicy{Instances: -1}, false, false},
+		{"negative cooldown", FallbackPolicy{Instances: 4, Cooldown: -time.Second}, false, false},
+		{"with fail-open", FallbackPolicy{Instances: 4}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.Fallback, cfg.FailOpen = tt.fallback, tt.failOpen
+			err := cfg.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("expected valid config, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrConfigInvalid) {
+				t.Fatalf("expected ErrConfigInvalid, got %v", err)
+			}
+		})
+	}
+}
+
 func TestNoopMetrics(t *testing.T) {
 	m := &NoopMetrics{}
 	// Should not panic
@@ -95,3 +180,186 @@ func TestErrorVariables(t *testing.T) {
 		t.Error("ErrUnknownStrategy should not be nil")
 	}
 }
+
+func TestAccessMatcher_Match(t *testing.T) {
+	m, err := AccessList{
+		Keys:     []string{"internal-job"},
+		Prefixes: []string{"partner:"},
+		CIDRs:    []string{"10.0.0.0/8", "2001:db8::/32", "192.0.2.7"},
+	}.Compile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"internal-job", true},
+		{"internal-job-2", false},
+		{"partner:acme", true},
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"192.0.2.7", true},
+		{"192.0.2.8", false},
+		{"10.1.2.3:80", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.key); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestBypassPolicy(t *testing.T) {
+	bypass, err := BypassPolicy{
+		Allow: AccessList{CIDRs: []string{"10.0.0.0/8"}},
+		Deny:  AccessList{Keys: []string{"10.0.0.66"}},
+	}.Compile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res, ok := bypass.Check("10.0.0.1"); !ok || !res.Allowed || !res.Bypassed {
+		t.Fatalf("expected an allowlisted bypass, got %+v, %v", res, ok)
+	}
+	if res, ok := bypass.Check("10.0.0.66"); !ok || res.Allowed || !res.Bypassed {
+		t.Fatalf("expected the denylist to win, got %+v, %v", res, ok)
+	}
+	if _, ok := bypass.Check("192.0.2.1"); ok {
+		t.Fatal("expected unlisted keys to be rate limited")
+	}
+
+	err = Config{
+		Strategy: FixedWindow,
+		Limit:    1,
+		Window:   time.Second,
+		Bypass:   BypassPolicy{Deny: AccessList{CIDRs: []string{"10.0.0.0/33"}}},
+	}.Validate()
+	if !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for a bad range, got %v", err)
+	}
+}
+
+func TestResourceConfig_Validate_PolicyStrategy(t *testing.T) {
+	cfg := ResourceConfig{
+		Strategy:      FixedWindow,
+		DefaultPolicy: ResourcePolicy{Limit: 10, Window: time.Minute},
+		Resources: map[string]ResourcePolicy{
+			"exports": {Limit: 100, Period: PeriodDay, Strategy: CalendarQuota},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected the calendar override to be validated with its own strategy, got %v", err)
+	}
+
+	err := CompositeConfig{
+		Strategy: FixedWindow,
+		Policies: []ResourcePolicy{{Limit: 10, Window: time.Minute, Strategy: SlidingLog}},
+	}.Validate()
+	if !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected composite limits to reject overrides, got %v", err)
+	}
+}
+
+func TestResourceMatcher_Match(t *testing.T) {
+	m, err := CompileRules([]ResourceRule{
+		{Name: "exports", Match: MatchGlob, Pattern: "/api/*/export"},
+		{Name: "user-writes", Match: MatchRegex, Pattern: `/api/v\d+/users/\d+`, Method: "POST"},
+		{Name: "users", Match: MatchPrefix, Pattern: "/api/v1/users/"},
+		{Name: "static", Match: MatchGlob, Pattern: "/static/**.js"},
+		{Name: "cafe", Match: MatchGlob, Pattern: "/api/café/*"},
+		{Name: "initials", Match: MatchGlob, Pattern: "/people/?"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		resource string
+		want     int
+	}{
+		{"/api/v1/export", 0},
+		{"GET /api/v2/export", 0},
+		{"/api/v1/users/export", 2},
+		{"POST /api/v1/users/123", 1},
+		{"GET /api/v1/users/123", 2},
+		{"/api/v1/users/123", 2},
+		{"/static/js/app/main.js", 3},
+		{"/static/main.css", -1},
+		{"/api/café/menu", 4},
+		{"/api/cafe/menu", -1},
+		{"/people/é", 5},
+		{"/people/éa", -1},
+		{"/health", -1},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.resource); got != tt.want {
+			t.Errorf("Match(%q) = %d, want %d", tt.resource, got, tt.want)
+		}
+	}
+}
+
+func TestResourceConfig_Validate_Fallback(t *testing.T) {
+	failOpen := true
+	cfg := ResourceConfig{
+		Strategy:      FixedWindow,
+		DefaultPolicy: ResourcePolicy{Limit: 10, Window: time.Second},
+		Fallback:      FallbackPolicy{Instances: 2},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	withFailOpen := cfg
+	withFailOpen.FailOpen = true
+	if err := withFailOpen.Validate(); !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid with fail-open, got %v", err)
+	}
+
+	withOverride := cfg
+	withOverride.Rules = []ResourceRule{{
+		Name: "api", Match: MatchPrefix, Pattern: "/api/",
+		Policy: ResourcePolicy{Limit: 10, Window: time.Second, FailOpen: &failOpen},
+	}}
+	if err := withOverride.Validate(); !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid with a fail-open rule, got %v", err)
+	}
+
+	negative := cfg
+	negative.Fallback.Instances = -1
+	if err := negative.Validate(); !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for negative instances, got %v", err)
+	}
+}
+
+func TestResourceConfig_Validate_Rules(t *testing.T) {
+	base := ResourceConfig{
+		Strategy:      FixedWindow,
+		DefaultPolicy: ResourcePolicy{Limit: 10, Window: time.Minute},
+		Resources:     map[string]ResourcePolicy{"login": {Limit: 5, Window: time.Minute}},
+	}
+	policy := ResourcePolicy{Limit: 1, Window: time.Second}
+	tests := []struct {
+		name string
+		rule ResourceRule
+	}{
+		{"empty name", ResourceRule{Match: MatchPrefix, Pattern: "/a", Policy: policy}},
+		{"name of a resource", ResourceRule{Name: "login", Match: MatchPrefix, Pattern: "/a", Policy: policy}},
+		{"bad regex", ResourceRule{Name: "r", Match: MatchRegex, Pattern: "(", Policy: policy}},
+		{"unknown match", ResourceRule{Name: "r", Match: "suffix", Pattern: "/a", Policy: policy}},
+		{"invalid policy", ResourceRule{Name: "r", Match: MatchPrefix, Pattern: "/a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.Rules = []ResourceRule{tt.rule}
+			if err := cfg.Validate(); !errors.Is(err, ErrConfigInvalid) {
+				t.Fatalf("expected ErrConfigInvalid, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultPolicyCacheTTL is how long a resolved policy is reused when DynamicConfig.CacheTTL is zero.
+const DefaultPolicyCacheTTL = time.Minute
+
+// PolicyResolver returns the policy that applies to key, e.g. from the plan of the customer behind it.
+type PolicyResolver func(ctx context.Context, key string) (ResourcePolicy, error)
+
+// DynamicConfig holds the configuration for a limiter whose limit and window are resolved per key
+// at request time, under one strategy and one store.
+type DynamicConfig struct {
+	Strategy StrategyType   // Rate limiting algorithm applied to every key
+	Resolver PolicyResolver // Returns the policy of a key
+	CacheTTL time.Duration  // How long a resolved policy is reused for a key (0 -> DefaultPolicyCacheTTL, negative disables caching)
+	RedisURL string         // Redis connection string for distributed mode
+	FailOpen bool           // If true, allow requests when backend or resolver is unavailable
+	Location *time.Location // Time zone for calendar periods (nil -> UTC)
+	// Optional: metrics collector (nil -> NoopMetrics)
+	Metrics MetricsCollector
+	// Optional: source of the current time (nil -> SystemClock)
+	Clock Clock
+}
+
+// Validate checks the dynamic configuration for common errors.
+// Resolved policies are validated when they are first used.
+func (c DynamicConfig) Validate() error {
+	if c.Resolver == nil {
+		return fmt.Errorf("%w: resolver must not be nil", ErrConfigInvalid)
+	}
+	if c.Strategy == Concurrency {
+		return fmt.Errorf("%w: dynamic limits do not apply to the concurrency strategy", ErrConfigInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults for the zero fields of FallbackPolicy.
+const (
+	// DefaultFallbackErrorThreshold is the number of consecutive backend errors that trips the breaker.
+	DefaultFallbackErrorThreshold = 5
+	// DefaultFallbackCooldown is how long the breaker stays open before probing the backend.
+	DefaultFallbackCooldown = 5 * time.Second
+)
+
+// FallbackPolicy keeps enforcing approximate limits while the Redis backend fails. A circuit
+// breaker counts consecutive backend errors; once it trips, requests are decided by a local
+// in-memory limiter enforcing Limit / Instances (and Burst and Rate likewise) on each instance,
+// and their Results have Fallback set. After Cooldown one request probes Redis again: success
+// closes the breaker, failure keeps it open for another Cooldown. The zero value disables it.
+type FallbackPolicy struct {
+	Instances int // Expected number of instances sharing the limit (0 disables the fallback)
+	// Optional: consecutive backend errors that trip the breaker (0 -> DefaultFallbackErrorThreshold)
+	ErrorThreshold int
+	// Optional: how long the breaker stays open before a request probes the backend
+	// (0 -> DefaultFallbackCooldown)
+	Cooldown time.Duration
+}
+
+// Validate checks the fallback policy for common errors. A zero Instances is always valid.
+func (p FallbackPolicy) Validate() error {
+	if p.Instances < 0 {
+		return fmt.Errorf("%w: fallback instances must not be negative", ErrConfigInvalid)
+	}
+	if p.ErrorThreshold < 0 || p.Cooldown < 0 {
+		return fmt.Errorf("%w: fallback error threshold and cooldown must not be negative", ErrConfigInvalid)
+	}
+	return nil
+}
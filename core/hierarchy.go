@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Descriptor identifies the caller of a hierarchical limit, mapping descriptor fields
+// such as "tenant" and "user" to their values for one request.
+type Descriptor map[string]string
+
+// HierarchyLevel is one level of a hierarchical limit, such as global, tenant or user.
+type HierarchyLevel struct {
+	Name   string         // Level name, reported when this level rejects a request
+	Field  string         // Descriptor field keying this level; empty for one counter shared by everyone
+	Policy ResourcePolicy // Limit applied at this level
+}
+
+// HierarchicalConfig holds the configuration for a limiter where a request must pass every level
+// of a tree, e.g. a user inside a tenant inside a global cap.
+type HierarchicalConfig struct {
+	Strategy StrategyType     // Rate limiting algorithm applied to every level
+	Levels   []HierarchyLevel // Levels from the outermost (e.g. global) to the innermost (e.g. user)
+	RedisURL string           // Redis connection string for distributed mode
+	FailOpen bool             // If true, allow requests when backend is unavailable
+	Location *time.Location   // Time zone for calendar periods (nil -> UTC)
+	// Optional: metrics collector (nil -> NoopMetrics)
+	Metrics MetricsCollector
+	// Optional: source of the current time (nil -> SystemClock)
+	Clock Clock
+}
+
+// Validate checks the hierarchical configuration for common errors.
+func (c HierarchicalConfig) Validate() error {
+	if len(c.Levels) == 0 {
+		return fmt.Errorf("%w: at least one level is required", ErrConfigInvalid)
+	}
+	names := make(map[string]bool, len(c.Levels))
+	for i, level := range c.Levels {
+		if level.Name == "" {
+			return fmt.Errorf("%w: level %d: name must not be empty", ErrConfigInvalid, i)
+		}
+		if names[level.Name] {
+			return fmt.Errorf("%w: duplicate level name %q", ErrConfigInvalid, level.Name)
+		}
+		names[level.Name] = true
+		if err := validateSharedPolicy(c.Strategy, level.Policy); err != nil {
+			return fmt.Errorf("level %q: %w", level.Name, err)
+		}
+	}
+	return nil
+}
+
+// HierarchicalResult is a Result together with the level it describes: the level that rejected
+// the request when denied, or the level with the least remaining capacity when allowed.
+type HierarchicalResult struct {
+	Result
+	Level string
+}
+
+// HierarchicalLimiter checks a request against every level of a hierarchy.
+// A request is charged at every level or at none of them.
+type HierarchicalLimiter interface {
+	// Allow checks a single request described by d against every level.
+	Allow(ctx context.Context, d Descriptor) (HierarchicalResult, error)
+	// AllowN is like Allow but consumes n units of capacity at every level.
+	AllowN(ctx context.Context, d Descriptor, n int) (HierarchicalResult, error)
+	// Peek reports the most restrictive level for d without consuming capacity.
+	Peek(ctx context.Context, d Descriptor) (HierarchicalResult, error)
+	// Refund gives back n previously consumed units at every level for d.
+	Refund(ctx context.Context, d Descriptor, n int) error
+	// Reset forgets the state stored at the named level for d.
+	Reset(ctx context.Context, level string, d Descriptor) error
+	// Close releases any resources held by the limiter.
+	Close() error
+}
@@ -15,3 +15,15 @@ type NoopMetrics struct{}
 func (_ *NoopMetrics) IncAllow()                      {}
 func (_ *NoopMetrics) IncDeny()                       {}
 func (_ *NoopMetrics) ObserveLatency(_ time.Duration) {}
+
+// LimitObserver is implemented by metrics collectors that track the effective limit of
+// limiters whose limit changes at runtime, such as adaptive limiters.
+type LimitObserver interface {
+	ObserveLimit(limit int) // record the current effective limit
+}
+
+// ShadowObserver is implemented by metrics collectors that count the requests shadow mode
+// admitted although the strategy denied them. Those requests are counted as allowed as well.
+type ShadowObserver interface {
+	IncShadowDeny() // increment would-be denied requests counter
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultOffenceMemory is how long past bans count towards escalation when
+// PenaltyPolicy.OffenceMemory is zero.
+const DefaultOffenceMemory = 24 * time.Hour
+
+// PenaltyPolicy bans keys that keep getting denied, similar to fail2ban. The zero value disables it.
+type PenaltyPolicy struct {
+	Threshold   int           // Denials within Period that trigger a ban (0 disables the penalty box)
+	Period      time.Duration // Window in which denials are counted, starting at the first denial
+	BanDuration time.Duration // Length of a first ban
+	Escalation  float64       // Factor applied to the ban length for each repeat offence (0 or 1 -> no escalation)
+	// Optional: upper bound for escalated bans (0 -> unbounded)
+	MaxBanDuration time.Duration
+	// Optional: how long a ban counts as a previous offence (0 -> DefaultOffenceMemory)
+	OffenceMemory time.Duration
+}
+
+// Validate checks the penalty policy for common errors. A zero Threshold is always valid.
+func (p PenaltyPolicy) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("%w: penalty threshold must not be negative", ErrConfigInvalid)
+	}
+	if p.Threshold == 0 {
+		return nil
+	}
+	if p.Period <= 0 {
+		return fmt.Errorf("%w: penalty period must be greater than 0", ErrConfigInvalid)
+	}
+	if p.BanDuration <= 0 {
+		return fmt.Errorf("%w: ban duration must be greater than 0", ErrConfigInvalid)
+	}
+	if p.Escalation != 0 && p.Escalation < 1 {
+		return fmt.Errorf("%w: ban escalation must be at least 1", ErrConfigInvalid)
+	}
+	if p.MaxBanDuration < 0 || p.OffenceMemory < 0 {
+		return fmt.Errorf("%w: max ban duration and offence memory must not be negative", ErrConfigInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period is a calendar period used by the CalendarQuota strategy.
+type Period string
+
+const (
+	// PeriodHour starts at the top of every local hour.
+	PeriodHour Period = "hour"
+	// PeriodDay starts at local midnight.
+	PeriodDay Period = "day"
+	// PeriodWeek starts at local midnight on Monday (ISO 8601 weeks).
+	PeriodWeek Period = "week"
+	// PeriodMonth starts at local midnight on the first day of the month.
+	PeriodMonth Period = "month"
+)
+
+// Validate reports whether p is one of the supported calendar periods.
+func (p Period) Validate() error {
+	switch p {
+	case PeriodHour, PeriodDay, PeriodWeek, PeriodMonth:
+		return nil
+	case "":
+		return fmt.Errorf("%w: period must be set", ErrConfigInvalid)
+	default:
+		return fmt.Errorf("%w: unknown period %q", ErrConfigInvalid, p)
+	}
+}
+
+// Bounds returns the start and end of the period containing t, using calendar rules in loc.
+// A nil loc means UTC. Periods follow wall-clock time, so a day spanning a DST change
+// lasts 23 or 25 hours and months have their real length.
+func (p Period) Bounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	year, month, day := t.Date()
+
+	switch p {
+	case PeriodHour:
+		start := time.Date(year, month, day, t.Hour(), 0, 0, 0, loc)
+		return start, start.Add(time.Hour)
+	case PeriodWeek:
+		sinceMonday := (int(t.Weekday()) + 6) % 7
+		start := time.Date(year, month, day-sinceMonday, 0, 0, 0, 0, loc)
+		return start, time.Date(year, month, day-sinceMonday+7, 0, 0, 0, 0, loc)
+	case PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc), time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc), time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	}
+}
+
+func validateLimitPeriod(limit int, period Period) error {
+	if limit <= 0 {
+		return fmt.Errorf("%w: limit must be greater than 0", ErrConfigInvalid)
+	}
+	return period.Validate()
+}
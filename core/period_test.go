@@ -0,0 +1,86 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPeriod_Bounds(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	tests := []struct {
+		name      string
+		period    Period
+		loc       *time.Location
+		at        time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "hour follows half-hour offsets",
+			period:    PeriodHour,
+			loc:       ist,
+			at:        time.Date(2024, 3, 10, 14, 45, 0, 0, ist),
+			wantStart: time.Date(2024, 3, 10, 14, 0, 0, 0, ist),
+			wantEnd:   time.Date(2024, 3, 10, 15, 0, 0, 0, ist),
+		},
+		{
+			name:      "day starts at local midnight",
+			period:    PeriodDay,
+			loc:       ist,
+			at:        time.Date(2024, 3, 9, 20, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 3, 10, 0, 0, 0, 0, ist),
+			wantEnd:   time.Date(2024, 3, 11, 0, 0, 0, 0, ist),
+		},
+		{
+			name:      "week starts on monday",
+			period:    PeriodWeek,
+			loc:       time.UTC,
+			at:        time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), // Sunday
+			wantStart: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "month has its real length",
+			period:    PeriodMonth,
+			loc:       nil,
+			at:        time.Date(2024, 2, 29, 23, 59, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.period.Bounds(tt.at, tt.loc)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("expected [%v, %v), got [%v, %v)", tt.wantStart, tt.wantEnd, start, end)
+			}
+		})
+	}
+}
+
+func TestPeriod_BoundsAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	start, end := PeriodDay.Bounds(time.Date(2024, 3, 10, 12, 0, 0, 0, loc), loc)
+	if got := end.Sub(start); got != 23*time.Hour {
+		t.Fatalf("expected the spring-forward day to last 23h, got %v", got)
+	}
+}
+
+func TestConfig_Validate_CalendarQuota(t *testing.T) {
+	cfg := Config{Strategy: CalendarQuota, Limit: 10000, Period: PeriodMonth}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected calendar quota without window to be valid, got %v", err)
+	}
+
+	for _, period := range []Period{"", "fortnight"} {
+		cfg.Period = period
+		if err := cfg.Validate(); !errors.Is(err, ErrConfigInvalid) {
+			t.Fatalf("period %q: expected ErrConfigInvalid, got %v", period, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPermitLeaseTTL is how long leased permits may be spent when PermitLeasePolicy.TTL is
+// zero. It is shortened to the Window of strategies with a shorter one.
+const DefaultPermitLeaseTTL = time.Second
+
+// PermitLeasePolicy lets each instance take permits for a key from the shared budget in batches
+// and spend them locally, so that most requests need no round trip to the store. Leased permits
+// are charged to the shared budget when they are leased; the ones still unspent when the lease
+// expires, or when the limiter is closed, are refunded. The zero value disables leasing.
+//
+// Leasing never admits more requests in total than the strategy grants, but it moves admissions
+// in time: a lease taken late in one window can still be spent, or refunded, early in the next.
+// Within any Window each instance can therefore admit at most Batch requests on top of the
+// limit, for an overshoot bounded by instances × Batch. Symmetrically, a key may be denied
+// while up to Batch permits per other instance sit unspent.
+type PermitLeasePolicy struct {
+	Batch int // Permits leased per round trip (0 disables leasing)
+	// Optional: how long leased permits may be spent before the rest is refunded
+	// (0 -> DefaultPermitLeaseTTL); must not exceed Window
+	TTL time.Duration
+}
+
+// Validate checks the leasing policy for common errors. A zero Batch is always valid.
+func (p PermitLeasePolicy) Validate() error {
+	if p.Batch < 0 {
+		return fmt.Errorf("%w: lease batch must not be negative", ErrConfigInvalid)
+	}
+	if p.TTL < 0 {
+		return fmt.Errorf("%w: lease TTL must not be negative", ErrConfigInvalid)
+	}
+	return nil
+}
+
+// validatePermitLease checks that the leasing policy of c fits its strategy.
+func (c Config) validatePermitLease() error {
+	if err := c.PermitLease.Validate(); err != nil {
+		return err
+	}
+	if c.PermitLease.Batch == 0 {
+		return nil
+	}
+	if c.Strategy == Concurrency {
+		return fmt.Errorf("%w: permit leasing does not apply to the concurrency strategy", ErrConfigInvalid)
+	}
+	capacity := c.Limit
+	if c.Strategy == TokenBucket && c.Burst > 0 {
+		capacity = c.Burst
+	}
+	if c.PermitLease.Batch > capacity {
+		return fmt.Errorf("%w: lease batch %d is greater than limit %d", ErrConfigInvalid, c.PermitLease.Batch, capacity)
+	}
+	if c.Window > 0 && c.PermitLease.TTL > c.Window {
+		return fmt.Errorf("%w: lease TTL must not exceed the window", ErrConfigInvalid)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -10,6 +11,20 @@ import (
 type ResourcePolicy struct {
 	Limit  int           // Maximum allowed requests/tokens per window
 	Window time.Duration // Time window duration
+	Period Period        // Calendar period, used instead of Window by the CalendarQuota strategy
+	Burst  int           // Bucket capacity for the TokenBucket strategy (0 -> Limit)
+	Rate   float64       // Refill rate in tokens per second for the TokenBucket strategy (0 -> Limit per Window)
+	Shadow bool          // Evaluate and update state but always allow, see Config.Shadow
+	// Optional overrides of the ResourceConfig settings for this resource, honoured by resource
+	// limiters only (empty Strategy, nil FailOpen and nil Metrics -> inherited)
+	Strategy StrategyType
+	FailOpen *bool
+	Metrics  MetricsCollector
+}
+
+// overridesConfig reports whether the policy sets anything that only resource limiters honour.
+func (p ResourcePolicy) overridesConfig() bool {
+	return p.Shadow || p.Strategy != "" || p.FailOpen != nil || p.Metrics != nil
 }
 
 // Validate checks the resource policy for common errors.
@@ -19,39 +34,176 @@ func (p ResourcePolicy) Validate() error {
 
 // ResourceConfig holds the configuration for creating a resource-scoped limiter.
 type ResourceConfig struct {
-	Strategy      StrategyType              // Rate limiting algorithm to use across all resources
-	DefaultPolicy ResourcePolicy            // Fallback policy for resources not present in Resources
+	Strategy      StrategyType              // Rate limiting algorithm for policies that do not set their own
+	DefaultPolicy ResourcePolicy            // Fallback policy for resources matched by neither Resources nor Rules
 	Resources     map[string]ResourcePolicy // Per-resource policy overrides
 	RedisURL      string                    // Redis connection string for distributed mode
-	FailOpen      bool                      // If true, allow requests when backend is unavailable
-	// Optional: metrics collector (nil -> NoopMetrics)
+	FailOpen      bool                      // If true, allow requests when backend is unavailable, unless a policy overrides it
+	Location      *time.Location            // Time zone for calendar periods (nil -> UTC)
+	// Optional: policies for resources matching a pattern, tried in order after Resources and
+	// before DefaultPolicy
+	Rules []ResourceRule
+	// Optional: keys that are always admitted or always rejected on every resource (zero -> none)
+	Bypass BypassPolicy
+	// Optional: local limits every policy enforces while the Redis backend fails, see
+	// Config.Fallback (zero -> none)
+	Fallback FallbackPolicy
+	// Optional: called for requests admitted by a policy in shadow mode that its strategy denied
+	OnShadowDeny ShadowHook
+	// Optional: metrics collector for policies that do not set their own (nil -> NoopMetrics)
 	Metrics MetricsCollector
+	// Optional: source of the current time (nil -> SystemClock)
+	Clock Clock
 }
 
 // Validate checks the resource-scoped configuration for common errors.
 func (c ResourceConfig) Validate() error {
-	if err := c.DefaultPolicy.Validate(); err != nil {
+	if err := c.Bypass.Validate(); err != nil {
+		return err
+	}
+	if err := c.validateFallback(); err != nil {
+		return err
+	}
+	if err := validatePolicy(c.StrategyFor(c.DefaultPolicy), c.DefaultPolicy); err != nil {
 		return fmt.Errorf("default policy: %w", err)
 	}
 	for resource, policy := range c.Resources {
 		if resource == "" {
 			return fmt.Errorf("%w: resource name must not be empty", ErrConfigInvalid)
 		}
-		if err := policy.Validate(); err != nil {
+		if err := validatePolicy(c.StrategyFor(policy), policy); err != nil {
 			return fmt.Errorf("resource %q: %w", resource, err)
 		}
 	}
+	names := make(map[string]bool, len(c.Rules))
+	for _, rule := range c.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("%w: rule name must not be empty", ErrConfigInvalid)
+		}
+		if _, ok := c.Resources[rule.Name]; ok || names[rule.Name] {
+			return fmt.Errorf("%w: rule name %q is already used", ErrConfigInvalid, rule.Name)
+		}
+		names[rule.Name] = true
+		if err := validatePolicy(c.StrategyFor(rule.Policy), rule.Policy); err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+	}
+	_, err := CompileRules(c.Rules)
+	return err
+}
+
+// validateFallback checks the fallback policy and that no policy fails open along with it.
+func (c ResourceConfig) validateFallback() error {
+	if err := c.Fallback.Validate(); err != nil {
+		return err
+	}
+	if c.Fallback.Instances == 0 {
+		return nil
+	}
+	policies := []ResourcePolicy{c.DefaultPolicy}
+	for _, policy := range c.Resources {
+		policies = append(policies, policy)
+	}
+	for _, rule := range c.Rules {
+		policies = append(policies, rule.Policy)
+	}
+	for _, policy := range policies {
+		if c.FailOpenFor(policy) {
+			return fmt.Errorf("%w: fallback and fail-open are mutually exclusive", ErrConfigInvalid)
+		}
+	}
 	return nil
 }
 
+// StrategyFor returns the strategy enforcing policy: its own Strategy if set, c.Strategy otherwise.
+func (c ResourceConfig) StrategyFor(policy ResourcePolicy) StrategyType {
+	if policy.Strategy != "" {
+		return policy.Strategy
+	}
+	return c.Strategy
+}
+
+// FailOpenFor reports whether policy fails open: its own FailOpen if set, c.FailOpen otherwise.
+func (c ResourceConfig) FailOpenFor(policy ResourcePolicy) bool {
+	if policy.FailOpen != nil {
+		return *policy.FailOpen
+	}
+	return c.FailOpen
+}
+
+// validateSharedPolicy checks a policy of a limiter that applies one strategy and one set of
+// settings to all of its policies, so per-resource overrides are rejected rather than ignored.
+func validateSharedPolicy(strategy StrategyType, p ResourcePolicy) error {
+	if p.overridesConfig() {
+		return fmt.Errorf("%w: shadow mode and strategy, fail-open and metrics overrides are only supported by resource limiters", ErrConfigInvalid)
+	}
+	return validatePolicy(strategy, p)
+}
+
+// validatePolicy checks a policy against what strategy needs: a period for calendar quotas,
+// a bucket shape for token buckets and a window for everything else.
+func validatePolicy(strategy StrategyType, p ResourcePolicy) error {
+	switch strategy {
+	case CalendarQuota:
+		return validateLimitPeriod(p.Limit, p.Period)
+	case TokenBucket:
+		return validateBucket(p.Limit, p.Window, p.Burst, p.Rate)
+	}
+	return p.Validate()
+}
+
 // ResourceLimiter defines the interface for resource-scoped rate limiting.
 type ResourceLimiter interface {
 	// AllowResource returns a Result indicating if the request is permitted for the given resource and key.
 	AllowResource(ctx context.Context, resource, key string) (Result, error)
+	// AllowResourceN is like AllowResource but consumes n units of capacity at once.
+	AllowResourceN(ctx context.Context, resource, key string, n int) (Result, error)
+	// PeekResource reports the current state for the given resource and key without consuming capacity.
+	PeekResource(ctx context.Context, resource, key string) (Result, error)
+	// RefundResource gives back n previously consumed units for the given resource and key.
+	RefundResource(ctx context.Context, resource, key string, n int) error
+	// ResetResource forgets all state stored for the given resource and key.
+	ResetResource(ctx context.Context, resource, key string) error
 	// Close releases any resources held by the limiter.
 	Close() error
 }
 
+// ReloadableResourceLimiter is a ResourceLimiter whose policies can be replaced while it serves
+// requests. The limiter returned by gorl.NewResourceLimiter implements it.
+type ReloadableResourceLimiter interface {
+	ResourceLimiter
+	// Update validates cfg and atomically swaps in its strategy and policies. Stored state and the
+	// storage backend are kept, so RedisURL must not change; nil Metrics and Clock keep the current ones.
+	// Policies that change get a new limiter, dropping state their strategy keeps in process memory.
+	Update(cfg ResourceConfig) error
+}
+
+// ResourceLeaseLimiter is a ResourceLimiter whose resources may use lease-based strategies such
+// as Concurrency. The limiter returned by gorl.NewResourceLimiter implements it.
+type ResourceLeaseLimiter interface {
+	ResourceLimiter
+	// AcquireResource takes one slot for key on resource. For resources whose strategy does not
+	// hand out leases it consumes one unit, like AllowResource, and returns the zero Lease.
+	AcquireResource(ctx context.Context, resource, key string) (Lease, Result, error)
+	// ReleaseResource gives the slot held by lease on resource back before its TTL runs out.
+	ReleaseResource(ctx context.Context, resource string, lease Lease) error
+}
+
+// validateBucket checks a token bucket shape. Burst and Rate may replace Limit and Window;
+// when either is left at zero, Limit and Window must be valid to supply the default.
+func validateBucket(limit int, window time.Duration, burst int, rate float64) error {
+	if burst < 0 {
+		return fmt.Errorf("%w: burst must not be negative", ErrConfigInvalid)
+	}
+	if rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return fmt.Errorf("%w: rate must be a finite, non-negative number", ErrConfigInvalid)
+	}
+	if burst > 0 && rate > 0 {
+		return nil
+	}
+	return validateLimitWindow(limit, window)
+}
+
 func validateLimitWindow(limit int, window time.Duration) error {
 	if limit <= 0 {
 		return fmt.Errorf("%w: limit must be greater than 0", ErrConfigInvalid)
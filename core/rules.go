@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MatchType selects how a ResourceRule pattern is compared with resources.
+type MatchType string
+
+const (
+	// MatchPrefix matches resources that start with the pattern.
+	MatchPrefix MatchType = "prefix"
+	// MatchGlob matches resources against a path glob: "*" matches within one "/"-separated
+	// segment, "**" matches across segments and "?" matches one character other than "/".
+	MatchGlob MatchType = "glob"
+	// MatchRegex matches resources against a regular expression, anchored at both ends.
+	MatchRegex MatchType = "regex"
+)
+
+// ResourceRule applies a policy to every resource matching a pattern, such as all paths under
+// "/api/v1/users/". Requests matching a rule share the rule's Name as their storage namespace,
+// so "/api/v1/users/1" and "/api/v1/users/2" count against the same limit per key.
+type ResourceRule struct {
+	Name    string    // Storage namespace of the matched requests; unique among rules and Resources
+	Match   MatchType // How Pattern is matched
+	Pattern string    // Prefix, glob or regular expression the resource is matched against
+	// Optional: only match resources qualified with this method, as in "GET /api/v1/users"
+	// (empty -> any method, and unqualified resources)
+	Method string
+	Policy ResourcePolicy // Policy applied to the matched requests
+}
+
+// ResourceMatcher finds the first rule matching a resource among compiled ResourceRules.
+// A nil matcher matches nothing.
+type ResourceMatcher struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	method string
+	prefix string
+	re     *regexp.Regexp
+}
+
+// CompileRules compiles the patterns of rules for matching.
+func CompileRules(rules []ResourceRule) (*ResourceMatcher, error) {
+	m := &ResourceMatcher{rules: make([]compiledRule, len(rules))}
+	for i, rule := range rules {
+		compiled := compiledRule{method: strings.ToUpper(rule.Method)}
+		switch rule.Match {
+		case MatchPrefix:
+			if rule.Pattern == "" {
+				return nil, fmt.Errorf("%w: rule %q: prefix must not be empty", ErrConfigInvalid, rule.Name)
+			}
+			compiled.prefix = rule.Pattern
+		case MatchGlob:
+			compiled.re = regexp.MustCompile(globToRegexp(rule.Pattern))
+		case MatchRegex:
+			re, err := regexp.Compile(`^(?:` + rule.Pattern + `)$`)
+			if err != nil {
+				return nil, fmt.Errorf("%w: rule %q: %v", ErrConfigInvalid, rule.Name, err)
+			}
+			compiled.re = re
+		default:
+			return nil, fmt.Errorf("%w: rule %q: unknown match type %q", ErrConfigInvalid, rule.Name, rule.Match)
+		}
+		m.rules[i] = compiled
+	}
+	return m, nil
+}
+
+// globToRegexp translates a path glob into an anchored regular expression. The glob is read
+// rune by rune, so '?' matches one character even outside ASCII.
+func globToRegexp(glob string) string {
+	runes := []rune(glob)
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Match returns the index of the first rule matching resource, or -1 if none does. A resource of
+// the form "METHOD target" is matched by rules for that method, and by rules without a method,
+// on its target; other resources are only matched by rules without a method.
+func (m *ResourceMatcher) Match(resource string) int {
+	if m == nil {
+		return -1
+	}
+	method, target := splitMethod(resource)
+	for i, rule := range m.rules {
+		if rule.method != "" && rule.method != method {
+			continue
+		}
+		if rule.re != nil {
+			if rule.re.MatchString(target) {
+				return i
+			}
+		} else if strings.HasPrefix(target, rule.prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitMethod splits a resource qualified as "METHOD target". Unqualified resources have an
+// empty method.
+func splitMethod(resource string) (string, string) {
+	method, target, ok := strings.Cut(resource, " ")
+	if !ok || method == "" || strings.ToUpper(method) != method {
+		return "", resource
+	}
+	return method, target
+}
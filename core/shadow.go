@@ -0,0 +1,9 @@
+package core
+
+import "context"
+
+// ShadowHook is called for every request that shadow mode admits although the strategy denied
+// it. res is the admitted result, with the strategy's Limit, Remaining, Reset and RetryAfter.
+// resource is empty for limiters that are not resource-scoped. The hook runs on the request
+// path, so it should return quickly.
+type ShadowHook func(ctx context.Context, resource, key string, res Result)
@@ -0,0 +1,201 @@
+package gorl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/strategy"
+)
+
+// NewDynamic creates a limiter whose policy is resolved per key at request time by cfg.Resolver,
+// so free, pro and enterprise customers can get different limits under the same strategy.
+// Resolutions are cached per key for cfg.CacheTTL. One limiter is kept per distinct policy on a
+// shared store, so with Redis the resolved limit and window reach the scripts like a static Config.
+func NewDynamic(cfg core.DynamicConfig) (core.Limiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Metrics = normalizeMetrics(cfg.Metrics)
+
+	constructor, ok := lookupStrategy(cfg.Strategy)
+	if !ok {
+		return nil, core.ErrUnknownStrategy
+	}
+
+	store, err := newStore(cfg.RedisURL, cfg.Clock)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = core.DefaultPolicyCacheTTL
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = core.SystemClock{}
+	}
+
+	return &dynamicLimiter{
+		cfg:         cfg,
+		constructor: constructor,
+		store:       store,
+		clock:       clock,
+		ttl:         ttl,
+		policies:    make(map[string]cachedPolicy),
+		limiters:    make(map[limiterSpec]core.Limiter),
+		lastSweep:   clock.Now(),
+	}, nil
+}
+
+type cachedPolicy struct {
+	policy  core.ResourcePolicy
+	expires time.Time
+}
+
+type dynamicLimiter struct {
+	cfg         core.DynamicConfig
+	constructor StrategyConstructor
+	store       storage.Storage
+	clock       core.Clock
+	ttl         time.Duration
+
+	mu        sync.Mutex
+	policies  map[string]cachedPolicy
+	limiters  map[limiterSpec]core.Limiter // Keyed by the settings a policy builds with
+	lastSweep time.Time
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (d *dynamicLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return d.AllowN(ctx, key, 1)
+}
+
+// AllowN resolves the policy of key and admits the request under it.
+// Resolver errors and invalid policies follow FailOpen like backend errors.
+func (d *dynamicLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	start := time.Now()
+	limiter, err := d.limiterFor(ctx, key)
+	if res, err, done := strategy.HandleFailure(start, err, d.cfg.FailOpen, d.cfg.Metrics, 0); done {
+		return res, err
+	}
+	return limiter.AllowN(ctx, key, n)
+}
+
+func (d *dynamicLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	limiter, err := d.limiterFor(ctx, key)
+	if err != nil {
+		return core.Result{}, err
+	}
+	return limiter.Peek(ctx, key)
+}
+
+func (d *dynamicLimiter) Refund(ctx context.Context, key string, n int) error {
+	limiter, err := d.limiterFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	return limiter.Refund(ctx, key, n)
+}
+
+func (d *dynamicLimiter) Reset(ctx context.Context, key string) error {
+	limiter, err := d.limiterFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	return limiter.Reset(ctx, key)
+}
+
+func (d *dynamicLimiter) Close() error {
+	d.closeOnce.Do(func() {
+		d.closeErr = d.store.Close()
+	})
+	return d.closeErr
+}
+
+// limiterFor returns the limiter enforcing the current policy of key, building it on first use.
+func (d *dynamicLimiter) limiterFor(ctx context.Context, key string) (core.Limiter, error) {
+	policy, err := d.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Strategy != "" || policy.FailOpen != nil || policy.Metrics != nil {
+		return nil, fmt.Errorf("policy for key %q: %w: strategy, fail-open and metrics overrides are only supported by resource limiters", key, core.ErrConfigInvalid)
+	}
+
+	cfg := core.Config{
+		Strategy: d.cfg.Strategy,
+		Limit:    policy.Limit,
+		Window:   policy.Window,
+		Period:   policy.Period,
+		Burst:    policy.Burst,
+		Rate:     policy.Rate,
+		Shadow:   policy.Shadow,
+		Location: d.cfg.Location,
+		RedisURL: d.cfg.RedisURL,
+		FailOpen: d.cfg.FailOpen,
+		Metrics:  d.cfg.Metrics,
+		Clock:    d.cfg.Clock,
+	}
+	spec := specOf(cfg)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if limiter, ok := d.limiters[spec]; ok {
+		return limiter, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("policy for key %q: %w", key, err)
+	}
+	limiter := buildLimiter(d.constructor, cfg, wrapSharedStore(d.store))
+	d.limiters[spec] = limiter
+	return limiter, nil
+}
+
+// resolve returns the cached policy of key or asks the resolver for it.
+// The resolver runs without holding the lock, so a slow lookup does not block other keys.
+func (d *dynamicLimiter) resolve(ctx context.Context, key string) (core.ResourcePolicy, error) {
+	if d.ttl < 0 {
+		return d.callResolver(ctx, key)
+	}
+
+	now := d.clock.Now()
+	d.mu.Lock()
+	cached, ok := d.policies[key]
+	d.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.policy, nil
+	}
+
+	policy, err := d.callResolver(ctx, key)
+	if err != nil {
+		return core.ResourcePolicy{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policies[key] = cachedPolicy{policy: policy, expires: now.Add(d.ttl)}
+	if now.Sub(d.lastSweep) >= d.ttl {
+		for k, c := range d.policies {
+			if !now.Before(c.expires) {
+				delete(d.policies, k)
+			}
+		}
+		d.lastSweep = now
+	}
+	return policy, nil
+}
+
+func (d *dynamicLimiter) callResolver(ctx context.Context, key string) (core.ResourcePolicy, error) {
+	policy, err := d.cfg.Resolver(ctx, key)
+	if err != nil {
+		return core.ResourcePolicy{}, fmt.Errorf("resolve policy for key %q: %w", key, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,192 @@
+package gorl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// planResolver gives keys prefixed with "pro:" a larger limit and counts its calls.
+func planResolver(calls *atomic.Int32) core.PolicyResolver {
+	return func(_ context.Context, key string) (core.ResourcePolicy, error) {
+		calls.Add(1)
+		if strings.HasPrefix(key, "pro:") {
+			return core.ResourcePolicy{Limit: 5, Window: time.Minute}, nil
+		}
+		return core.ResourcePolicy{Limit: 2, Window: time.Minute}, nil
+	}
+}
+
+func TestNewDynamic_LimitsFollowResolvedPolicy(t *testing.T) {
+	strategies := []core.StrategyType{core.FixedWindow, core.SlidingWindow, core.TokenBucket, core.GCRA}
+
+	for _, s := range strategies {
+		t.Run(string(s), func(t *testing.T) {
+			var calls atomic.Int32
+			limiter, err := NewDynamic(core.DynamicConfig{Strategy: s, Resolver: planResolver(&calls)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer limiter.Close()
+			ctx := context.Background()
+
+			for key, limit := range map[string]int{"free:alice": 2, "pro:bob": 5} {
+				for i := 0; i < limit; i++ {
+					res, err := limiter.Allow(ctx, key)
+					if err != nil || !res.Allowed {
+						t.Fatalf("%s req %d: expected allowed, got %v, err %v", key, i+1, res.Allowed, err)
+					}
+					if res.Limit != limit {
+						t.Fatalf("%s: expected limit %d, got %d", key, limit, res.Limit)
+					}
+				}
+				if res, _ := limiter.Allow(ctx, key); res.Allowed {
+					t.Fatalf("%s: expected denied after %d requests", key, limit)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDynamic_CachesResolutions(t *testing.T) {
+	var calls atomic.Int32
+	clock := clocktest.NewManual(time.Now())
+	limiter, err := NewDynamic(core.DynamicConfig{
+		Strategy: core.FixedWindow,
+		Resolver: planResolver(&calls),
+		CacheTTL: 30 * time.Second,
+		Clock:    clock,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "free:alice")
+	limiter.Peek(ctx, "free:alice")
+	if calls.Load() != 1 {
+		t.Fatalf("expected one resolver call within the TTL, got %d", calls.Load())
+	}
+
+	clock.Advance(30 * time.Second)
+	limiter.Allow(ctx, "free:alice")
+	if calls.Load() != 2 {
+		t.Fatalf("expected the policy to be resolved again after the TTL, got %d calls", calls.Load())
+	}
+}
+
+func TestNewDynamic_NegativeCacheTTLDisablesCache(t *testing.T) {
+	var calls atomic.Int32
+	limiter, err := NewDynamic(core.DynamicConfig{
+		Strategy: core.FixedWindow,
+		Resolver: planResolver(&calls),
+		CacheTTL: -1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	for i := 0; i < 3; i++ {
+		limiter.Allow(context.Background(), "free:alice")
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected a resolver call per request, got %d", calls.Load())
+	}
+}
+
+func TestNewDynamic_ResolverErrors(t *testing.T) {
+	errPlans := errors.New("plans unavailable")
+	resolver := func(context.Context, string) (core.ResourcePolicy, error) {
+		return core.ResourcePolicy{}, errPlans
+	}
+	ctx := context.Background()
+
+	closed, err := NewDynamic(core.DynamicConfig{Strategy: core.FixedWindow, Resolver: resolver})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer closed.Close()
+	if res, err := closed.Allow(ctx, "k"); !errors.Is(err, errPlans) || res.Allowed {
+		t.Fatalf("expected a denial with the resolver error, got %v, err %v", res.Allowed, err)
+	}
+
+	open, err := NewDynamic(core.DynamicConfig{Strategy: core.FixedWindow, Resolver: resolver, FailOpen: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer open.Close()
+	if res, err := open.Allow(ctx, "k"); err != nil || !res.Allowed {
+		t.Fatalf("expected fail-open allow, got %v, err %v", res.Allowed, err)
+	}
+}
+
+func TestNewDynamic_InvalidResolvedPolicy(t *testing.T) {
+	limiter, err := NewDynamic(core.DynamicConfig{
+		Strategy: core.FixedWindow,
+		Resolver: func(context.Context, string) (core.ResourcePolicy, error) {
+			return core.ResourcePolicy{Limit: 0, Window: time.Minute}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, err := limiter.Allow(context.Background(), "k"); !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid, got %v", err)
+	}
+}
+
+// taggedMetrics is a collector of an uncomparable type.
+type taggedMetrics struct {
+	*core.NoopMetrics
+	tags []string
+}
+
+func TestNewDynamic_SharesLimitersPerPolicy(t *testing.T) {
+	metrics := taggedMetrics{NoopMetrics: &core.NoopMetrics{}, tags: []string{"tenant"}}
+	limiter, err := NewDynamic(core.DynamicConfig{
+		Strategy: core.FixedWindow,
+		Resolver: func(_ context.Context, key string) (core.ResourcePolicy, error) {
+			if key == "custom" {
+				return core.ResourcePolicy{Limit: 2, Window: time.Minute, Metrics: metrics}, nil
+			}
+			return core.ResourcePolicy{Limit: 2, Window: time.Minute}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "alice")
+	limiter.Allow(ctx, "bob")
+	if n := len(limiter.(*dynamicLimiter).limiters); n != 1 {
+		t.Fatalf("expected keys with equal policies to share one limiter, got %d", n)
+	}
+	if _, err := limiter.Allow(ctx, "custom"); !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for a metrics override, got %v", err)
+	}
+}
+
+func TestNewDynamic_InvalidConfig(t *testing.T) {
+	if _, err := NewDynamic(core.DynamicConfig{Strategy: core.FixedWindow}); !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for a nil resolver, got %v", err)
+	}
+	var calls atomic.Int32
+	if _, err := NewDynamic(core.DynamicConfig{Strategy: "nope", Resolver: planResolver(&calls)}); !errors.Is(err, core.ErrUnknownStrategy) {
+		t.Fatalf("expected ErrUnknownStrategy, got %v", err)
+	}
+	if _, err := NewDynamic(core.DynamicConfig{Strategy: core.Concurrency, Resolver: planResolver(&calls)}); !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for the concurrency strategy, got %v", err)
+	}
+}
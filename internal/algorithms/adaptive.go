@@ -0,0 +1,290 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// adaptiveStateTTL bounds how long a shared limit outlives the last feedback.
+// Without feedback for this long, the limit starts over from the configured starting limit.
+const adaptiveStateTTL = 24 * time.Hour
+
+// AdaptiveLimiter enforces a limit that moves between MinLimit and MaxLimit with
+// additive-increase/multiplicative-decrease. The limit is kept in the store, so instances
+// sharing a Redis store and a Name adapt together; requests are admitted by a member limiter
+// whose limit follows the current whole limit. Built-in strategies have their limit changed in
+// place, keeping the state they hold in process memory; other strategies get one member limiter
+// per distinct limit.
+type AdaptiveLimiter struct {
+	store    storage.Storage
+	build    func(limit int) core.Limiter
+	clock    core.Clock
+	key      string
+	start    float64
+	min      float64
+	max      float64
+	increase float64
+	decrease float64
+	slow     time.Duration
+	interval time.Duration
+	metrics  core.MetricsCollector
+
+	mu       sync.Mutex
+	limit    float64
+	syncedAt time.Time
+	limiters map[int]core.Limiter // Member limiters by limit, when the strategy is no limitSetter
+
+	// memberMu is held for reading by requests on member and for writing to change its limit.
+	memberMu    sync.RWMutex
+	member      limitSetter
+	memberLimit int
+}
+
+// limitSetter is implemented by strategies whose limit an AdaptiveLimiter can change in place.
+// setLimit is only called while no other call is running on the limiter.
+type limitSetter interface {
+	core.Limiter
+	setLimit(limit int)
+}
+
+// NewAdaptiveLimiter creates an adaptive limiter for a validated cfg. build returns a limiter
+// enforcing the policy of cfg with the given limit on store. It is called once, or once per
+// distinct limit when the limiter it returns cannot change its limit.
+func NewAdaptiveLimiter(cfg core.AdaptiveConfig, store storage.Storage, build func(limit int) core.Limiter) core.AdaptiveLimiter {
+	start := cfg.Policy.Limit
+	if start == 0 {
+		start = cfg.MaxLimit
+	}
+	increase := cfg.Increase
+	if increase == 0 {
+		increase = 1
+	}
+	decrease := cfg.Decrease
+	if decrease == 0 {
+		decrease = 0.5
+	}
+	name := cfg.Name
+	if name == "" {
+		name = "default"
+	}
+	interval := cfg.SyncInterval
+	if interval <= 0 {
+		interval = core.DefaultAdaptiveSyncInterval
+	}
+	metrics := cfg.Metrics
+	if metrics == nil {
+		metrics = &core.NoopMetrics{}
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = core.SystemClock{}
+	}
+
+	a := &AdaptiveLimiter{
+		store:    store,
+		build:    build,
+		clock:    clock,
+		key:      "gorl:adaptive:" + name,
+		start:    float64(start),
+		min:      float64(cfg.MinLimit),
+		max:      float64(cfg.MaxLimit),
+		increase: increase,
+		decrease: decrease,
+		slow:     cfg.LatencyThreshold,
+		interval: interval,
+		metrics:  metrics,
+		limit:    float64(start),
+	}
+	limiter := build(start)
+	if member, ok := limiter.(limitSetter); ok {
+		a.member, a.memberLimit = member, start
+	} else {
+		a.limiters = map[int]core.Limiter{start: limiter}
+	}
+	a.observe(a.limit)
+	return a
+}
+
+// Allow checks a single request under the current limit.
+func (a *AdaptiveLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return a.AllowN(ctx, key, 1)
+}
+
+// AllowN checks n units under the current limit.
+func (a *AdaptiveLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	limiter, release := a.current(ctx)
+	defer release()
+	return limiter.AllowN(ctx, key, n)
+}
+
+// Peek reports the state of key under the current limit without consuming capacity.
+func (a *AdaptiveLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	limiter, release := a.current(ctx)
+	defer release()
+	return limiter.Peek(ctx, key)
+}
+
+// Refund gives n units back to key.
+func (a *AdaptiveLimiter) Refund(ctx context.Context, key string, n int) error {
+	limiter, release := a.current(ctx)
+	defer release()
+	return limiter.Refund(ctx, key, n)
+}
+
+// Reset forgets the state stored for key. The shared limit is left as it is.
+func (a *AdaptiveLimiter) Reset(ctx context.Context, key string) error {
+	limiter, release := a.current(ctx)
+	defer release()
+	return limiter.Reset(ctx, key)
+}
+
+// Close releases the store.
+func (a *AdaptiveLimiter) Close() error {
+	return a.store.Close()
+}
+
+// CurrentLimit returns the effective limit as last seen by this instance.
+func (a *AdaptiveLimiter) CurrentLimit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
+
+// Report applies one AIMD step to the shared limit. With Redis the step runs in one script,
+// so concurrent reports from every instance are all counted.
+func (a *AdaptiveLimiter) Report(ctx context.Context, outcome core.Outcome) error {
+	success := outcome.Err == nil && (a.slow == 0 || outcome.Latency <= a.slow)
+
+	var limit float64
+	if runner, ok := a.store.(redisScriptRunner); ok {
+		var signal int64
+		if success {
+			signal = 1
+		}
+		values, err := runner.EvalScript(ctx, redisScriptAdaptiveLimit, []string{a.key},
+			signal,
+			int64(a.start),
+			int64(a.min),
+			int64(a.max),
+			int64(math.Round(a.increase*1e6)),
+			int64(math.Round(a.decrease*1e6)),
+			durationToMilliseconds(adaptiveStateTTL),
+		)
+		if err != nil {
+			return err
+		}
+		if len(values) != 1 {
+			return fmt.Errorf("unexpected redis script result length: %d", len(values))
+		}
+		limit = float64(values[0]) / 1e6
+	} else {
+		// Without scripting the step is only atomic within this process.
+		a.mu.Lock()
+		stored, err := a.store.Get(ctx, a.key)
+		if err == nil {
+			limit = a.step(stored, success)
+			err = a.store.Set(ctx, a.key, limit, adaptiveStateTTL)
+		}
+		a.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	a.limit = limit
+	a.syncedAt = a.clock.Now()
+	a.mu.Unlock()
+	a.observe(limit)
+	return nil
+}
+
+// step returns the limit after one AIMD step from stored, where 0 means no limit is stored yet.
+func (a *AdaptiveLimiter) step(stored float64, success bool) float64 {
+	limit := stored
+	if limit == 0 {
+		limit = a.start
+	}
+	if success {
+		limit += a.increase
+	} else {
+		limit *= a.decrease
+	}
+	return math.Min(a.max, math.Max(a.min, limit))
+}
+
+// current returns the member limiter for the current limit and a function to call once the
+// request on it is done. The shared limit is re-read when the local copy is older than the sync
+// interval; read errors keep the local copy.
+func (a *AdaptiveLimiter) current(ctx context.Context) (core.Limiter, func()) {
+	limit := a.refresh(ctx)
+	if a.member == nil {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		limiter, ok := a.limiters[limit]
+		if !ok {
+			limiter = a.build(limit)
+			a.limiters[limit] = limiter
+		}
+		return limiter, func() {}
+	}
+
+	a.memberMu.RLock()
+	if a.memberLimit != limit {
+		a.memberMu.RUnlock()
+		a.memberMu.Lock()
+		if a.memberLimit != limit {
+			a.member.setLimit(limit)
+			a.memberLimit = limit
+		}
+		a.memberMu.Unlock()
+		a.memberMu.RLock()
+	}
+	return a.member, a.memberMu.RUnlock
+}
+
+// refresh re-reads the shared limit when the local copy is older than the sync interval and
+// returns the current whole limit.
+func (a *AdaptiveLimiter) refresh(ctx context.Context) int {
+	now := a.clock.Now()
+	a.mu.Lock()
+	stale := now.Sub(a.syncedAt) >= a.interval
+	if stale {
+		// Claim the refresh so concurrent callers keep using the local copy meanwhile.
+		a.syncedAt = now
+	}
+	a.mu.Unlock()
+
+	if stale {
+		if stored, err := a.store.Get(ctx, a.key); err == nil {
+			limit := a.start
+			if stored != 0 {
+				limit = math.Min(a.max, math.Max(a.min, stored))
+			}
+			a.mu.Lock()
+			changed := limit != a.limit
+			a.limit = limit
+			a.mu.Unlock()
+			if changed {
+				a.observe(limit)
+			}
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
+
+func (a *AdaptiveLimiter) observe(limit float64) {
+	if observer, ok := a.metrics.(core.LimitObserver); ok {
+		observer.ObserveLimit(int(limit))
+	}
+}
@@ -0,0 +1,188 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// limitRecorder is a metrics collector that also records the adaptive limit.
+type limitRecorder struct {
+	core.NoopMetrics
+	limits []int
+}
+
+func (r *limitRecorder) ObserveLimit(limit int) {
+	r.limits = append(r.limits, limit)
+}
+
+func newTestAdaptive(store storage.Storage, cfg core.AdaptiveConfig) core.AdaptiveLimiter {
+	if cfg.Policy.Window == 0 {
+		cfg.Policy.Window = time.Minute
+	}
+	return NewAdaptiveLimiter(cfg, store, func(limit int) core.Limiter {
+		return NewFixedWindowLimiter(core.Config{
+			Limit: limit, Window: cfg.Policy.Window, Metrics: &core.NoopMetrics{}, Clock: cfg.Clock,
+		}, store)
+	})
+}
+
+// TestAdaptive_AIMD checks multiplicative decrease, additive increase and the bounds.
+func TestAdaptive_AIMD(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	metrics := &limitRecorder{}
+	limiter := newTestAdaptive(store, core.AdaptiveConfig{
+		Policy: core.ResourcePolicy{Limit: 10}, MinLimit: 2, MaxLimit: 12, Metrics: metrics,
+	})
+	ctx := context.Background()
+	failure := core.Outcome{Err: errors.New("db timeout")}
+
+	steps := []struct {
+		outcome core.Outcome
+		want    int
+	}{
+		{failure, 5},
+		{failure, 2},
+		{failure, 2},
+		{core.Outcome{}, 3},
+		{core.Outcome{}, 4},
+	}
+	for i, step := range steps {
+		if err := limiter.Report(ctx, step.outcome); err != nil {
+			t.Fatalf("step %d: report: %v", i+1, err)
+		}
+		if got := limiter.CurrentLimit(); got != step.want {
+			t.Fatalf("step %d: expected limit %d, got %d", i+1, step.want, got)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		limiter.Report(ctx, core.Outcome{})
+	}
+	if got := limiter.CurrentLimit(); got != 12 {
+		t.Fatalf("expected the limit to stop at max 12, got %d", got)
+	}
+	if len(metrics.limits) == 0 || metrics.limits[len(metrics.limits)-1] != 12 {
+		t.Fatalf("expected the current limit to be reported to metrics, got %v", metrics.limits)
+	}
+}
+
+// TestAdaptive_EnforcesCurrentLimit ensures admission follows the adapted limit.
+func TestAdaptive_EnforcesCurrentLimit(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := newTestAdaptive(store, core.AdaptiveConfig{MinLimit: 1, MaxLimit: 8})
+	ctx := context.Background()
+
+	limiter.Report(ctx, core.Outcome{Err: errors.New("overloaded")})
+	limiter.Report(ctx, core.Outcome{Err: errors.New("overloaded")})
+
+	for i := 0; i < 2; i++ {
+		if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed, got %v, err %v", i+1, res.Allowed, err)
+		}
+	}
+	res, err := limiter.Allow(ctx, "k")
+	if err != nil || res.Allowed || res.Limit != 2 {
+		t.Fatalf("expected denial at the reduced limit of 2, got allowed=%v limit=%d err=%v", res.Allowed, res.Limit, err)
+	}
+}
+
+// TestAdaptive_LatencyThreshold ensures slow successes shrink the limit.
+func TestAdaptive_LatencyThreshold(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := newTestAdaptive(store, core.AdaptiveConfig{
+		MinLimit: 1, MaxLimit: 100, Decrease: 0.9, LatencyThreshold: 200 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	limiter.Report(ctx, core.Outcome{Latency: 150 * time.Millisecond})
+	if got := limiter.CurrentLimit(); got != 100 {
+		t.Fatalf("expected a fast success to keep the limit at max, got %d", got)
+	}
+	limiter.Report(ctx, core.Outcome{Latency: 500 * time.Millisecond})
+	if got := limiter.CurrentLimit(); got != 90 {
+		t.Fatalf("expected a slow success to count as a failure, got %d", got)
+	}
+}
+
+// TestAdaptive_SharedAcrossInstances checks that instances on one store converge on one limit.
+func TestAdaptive_SharedAcrossInstances(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	cfg := core.AdaptiveConfig{MinLimit: 1, MaxLimit: 16, Name: "db", SyncInterval: time.Second, Clock: clock}
+	a := newTestAdaptive(store, cfg)
+	b := newTestAdaptive(store, cfg)
+	ctx := context.Background()
+
+	b.Allow(ctx, "warmup")
+	a.Report(ctx, core.Outcome{Err: errors.New("overloaded")})
+	if got := b.CurrentLimit(); got != 16 {
+		t.Fatalf("expected b to keep its copy within the sync interval, got %d", got)
+	}
+
+	clock.Advance(time.Second)
+	b.Allow(ctx, "warmup")
+	if got := b.CurrentLimit(); got != 8 {
+		t.Fatalf("expected b to pick up the shared limit, got %d", got)
+	}
+	b.Report(ctx, core.Outcome{Err: errors.New("overloaded")})
+	if got := b.CurrentLimit(); got != 4 {
+		t.Fatalf("expected b to decrease from the shared limit, got %d", got)
+	}
+}
+
+// TestAdaptive_KeepsMemberState checks that a limit change adjusts the member limiter in place,
+// so state it keeps in process memory carries over.
+func TestAdaptive_KeepsMemberState(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	builds := 0
+	limiter := NewAdaptiveLimiter(core.AdaptiveConfig{
+		Policy: core.ResourcePolicy{Limit: 10, Window: time.Minute}, MinLimit: 1, MaxLimit: 10,
+		SyncInterval: time.Nanosecond,
+	}, store, func(limit int) core.Limiter {
+		builds++
+		return NewSlidingLogLimiter(core.Config{Limit: limit, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		limiter.Allow(ctx, "k")
+	}
+	if err := limiter.Report(ctx, core.Outcome{Err: errors.New("overloaded")}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if res, _ := limiter.Allow(ctx, "k"); !res.Allowed || res.Limit != 5 || res.Remaining != 0 {
+		t.Fatalf("expected the fifth request to fill the new limit of 5, got %+v", res)
+	}
+	if res, _ := limiter.Allow(ctx, "k"); res.Allowed {
+		t.Fatal("expected requests logged before the change to count against the new limit")
+	}
+	if builds != 1 {
+		t.Fatalf("expected one member limiter, got %d builds", builds)
+	}
+}
+
+// TestAdaptive_ShadowMetricsObserveLimit checks that ShadowMetrics passes limits on.
+func TestAdaptive_ShadowMetricsObserveLimit(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	metrics := &limitRecorder{}
+	limiter := newTestAdaptive(store, core.AdaptiveConfig{
+		Policy: core.ResourcePolicy{Limit: 4}, MinLimit: 1, MaxLimit: 8, Metrics: ShadowMetrics(metrics),
+	})
+
+	limiter.Report(context.Background(), core.Outcome{Err: errors.New("overloaded")})
+	if len(metrics.limits) != 2 || metrics.limits[0] != 4 || metrics.limits[1] != 2 {
+		t.Fatalf("expected limits 4 then 2 to be observed, got %v", metrics.limits)
+	}
+}
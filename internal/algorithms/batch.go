@@ -0,0 +1,125 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// AllowMany checks every request against limiter, in one call when limiter is a
+// core.BatchLimiter and one request at a time otherwise.
+func AllowMany(ctx context.Context, limiter core.Limiter, reqs []core.BatchRequest) []core.BatchResult {
+	if batcher, ok := limiter.(core.BatchLimiter); ok {
+		return batcher.AllowMany(ctx, reqs)
+	}
+	results := make([]core.BatchResult, len(reqs))
+	for i, req := range reqs {
+		res, err := limiter.AllowN(ctx, req.Key, req.Cost)
+		results[i] = core.BatchResult{Result: res, Err: err}
+	}
+	return results
+}
+
+// allowFunc decides one request, running its script through runner when runner is not nil.
+type allowFunc func(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error)
+
+// allowBatch decides every request with allow. When store can pipeline scripts, the requests
+// are decided concurrently and their scripts are sent together in one round trip; otherwise
+// they are decided one after another.
+func allowBatch(ctx context.Context, store storage.Storage, reqs []core.BatchRequest, allow allowFunc) []core.BatchResult {
+	results := make([]core.BatchResult, len(reqs))
+	pipeliner, ok := store.(storage.ScriptPipeliner)
+	if !ok || len(reqs) < 2 {
+		runner, _ := store.(redisScriptRunner)
+		for i, req := range reqs {
+			res, err := allow(ctx, runner, req.Key, req.Cost)
+			results[i] = core.BatchResult{Result: res, Err: err}
+		}
+		return results
+	}
+
+	batch := &scriptBatch{ctx: ctx, pipeliner: pipeliner, active: len(reqs)}
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer batch.leave()
+			res, err := allow(ctx, batch, req.Key, req.Cost)
+			results[i] = core.BatchResult{Result: res, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// scriptBatch is the script runner shared by the requests of one batch. It holds back every
+// script call until each request still deciding is waiting on one, then sends them all through
+// a single EvalScripts call.
+type scriptBatch struct {
+	ctx       context.Context
+	pipeliner storage.ScriptPipeliner
+
+	mu      sync.Mutex
+	active  int
+	pending []*batchCall
+}
+
+type batchCall struct {
+	call  storage.ScriptCall
+	reply storage.ScriptReply
+	done  chan struct{}
+}
+
+// EvalScript queues the call and waits for the round trip that carries it.
+func (b *scriptBatch) EvalScript(ctx context.Context, name string, keys []string, args ...int64) ([]int64, error) {
+	c := &batchCall{
+		call: storage.ScriptCall{Name: name, Keys: keys, Args: args},
+		done: make(chan struct{}),
+	}
+	b.mu.Lock()
+	b.pending = append(b.pending, c)
+	calls := b.ready()
+	b.mu.Unlock()
+	b.flush(calls)
+
+	<-c.done
+	return c.reply.Values, c.reply.Err
+}
+
+// leave marks a request as decided, which may complete the next round trip.
+func (b *scriptBatch) leave() {
+	b.mu.Lock()
+	b.active--
+	calls := b.ready()
+	b.mu.Unlock()
+	b.flush(calls)
+}
+
+// ready takes the pending calls once every active request is waiting. b.mu must be held.
+func (b *scriptBatch) ready() []*batchCall {
+	if len(b.pending) == 0 || len(b.pending) < b.active {
+		return nil
+	}
+	calls := b.pending
+	b.pending = nil
+	return calls
+}
+
+func (b *scriptBatch) flush(calls []*batchCall) {
+	if len(calls) == 0 {
+		return
+	}
+	scriptCalls := make([]storage.ScriptCall, len(calls))
+	for i, c := range calls {
+		scriptCalls[i] = c.call
+	}
+	replies := b.pipeliner.EvalScripts(b.ctx, scriptCalls)
+	for i, c := range calls {
+		c.reply = replies[i]
+		close(c.done)
+	}
+}
@@ -0,0 +1,158 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// pipelineStore emulates the fixed window script on top of the in-memory store and records
+// how scripts reach it.
+type pipelineStore struct {
+	storage.Storage
+	mu         sync.Mutex
+	roundTrips int
+	calls      int
+}
+
+func (s *pipelineStore) EvalScript(ctx context.Context, name string, keys []string, args ...int64) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roundTrips++
+	return s.run(ctx, storage.ScriptCall{Name: name, Keys: keys, Args: args})
+}
+
+func (s *pipelineStore) EvalScripts(ctx context.Context, calls []storage.ScriptCall) []storage.ScriptReply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roundTrips++
+	replies := make([]storage.ScriptReply, len(calls))
+	for i, call := range calls {
+		replies[i].Values, replies[i].Err = s.run(ctx, call)
+	}
+	return replies
+}
+
+func (s *pipelineStore) run(ctx context.Context, call storage.ScriptCall) ([]int64, error) {
+	s.calls++
+	if call.Name != redisScriptFixedWindow {
+		return nil, fmt.Errorf("unknown script %q", call.Name)
+	}
+	limit, cost := call.Args[0], call.Args[4]
+	count, err := s.Get(ctx, call.Keys[0])
+	if err != nil {
+		return nil, err
+	}
+	allowed := int64(0)
+	if int64(count)+cost <= limit {
+		allowed = 1
+		if count, err = s.IncrBy(ctx, call.Keys[0], float64(cost), time.Minute); err != nil {
+			return nil, err
+		}
+	}
+	return []int64{allowed, max(limit-int64(count), 0), 0, 0}, nil
+}
+
+func TestAllowMany_InMemory(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewTokenBucketLimiter(core.Config{
+		Limit: 3, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+
+	results := AllowMany(context.Background(), limiter, []core.BatchRequest{
+		{Key: "a", Cost: 2},
+		{Key: "a", Cost: 2},
+		{Key: "b", Cost: 0},
+		{Key: "b", Cost: 3},
+	})
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if !results[0].Allowed || results[0].Err != nil {
+		t.Fatalf("first request: expected allowed, got %+v", results[0])
+	}
+	if results[1].Allowed || results[1].Err != nil {
+		t.Fatalf("second request: expected denied, got %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, core.ErrInvalidCost) {
+		t.Fatalf("zero cost: expected ErrInvalidCost, got %v", results[2].Err)
+	}
+	if !results[3].Allowed || results[3].Remaining != 0 {
+		t.Fatalf("other key: expected allowed with nothing left, got %+v", results[3])
+	}
+}
+
+func TestAllowMany_PipelinesScripts(t *testing.T) {
+	store := &pipelineStore{Storage: inmem.NewInMemoryStore()}
+	defer store.Close()
+	limiter := NewFixedWindowLimiter(core.Config{
+		Limit: 2, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store).(core.BatchLimiter)
+
+	reqs := []core.BatchRequest{
+		{Key: "a", Cost: 1},
+		{Key: "a", Cost: 1},
+		{Key: "a", Cost: 1},
+		{Key: "b", Cost: 2},
+		{Key: "c", Cost: -1},
+	}
+	results := limiter.AllowMany(context.Background(), reqs)
+
+	if store.roundTrips != 1 || store.calls != 4 {
+		t.Fatalf("expected 4 scripts in 1 round trip, got %d in %d", store.calls, store.roundTrips)
+	}
+	allowedA := 0
+	for _, res := range results[:3] {
+		if res.Err != nil {
+			t.Fatalf("unexpected error: %v", res.Err)
+		}
+		if res.Allowed {
+			allowedA++
+		}
+	}
+	if allowedA != 2 {
+		t.Fatalf("expected 2 of 3 requests for a allowed, got %d", allowedA)
+	}
+	if !results[3].Allowed {
+		t.Fatalf("expected b allowed, got %+v", results[3])
+	}
+	if !errors.Is(results[4].Err, core.ErrInvalidCost) {
+		t.Fatalf("expected ErrInvalidCost for c, got %v", results[4].Err)
+	}
+}
+
+func TestAllowMany_Bypass(t *testing.T) {
+	store := &pipelineStore{Storage: inmem.NewInMemoryStore()}
+	defer store.Close()
+	inner := NewFixedWindowLimiter(core.Config{
+		Limit: 1, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	bypass, err := core.BypassPolicy{Deny: core.AccessList{Keys: []string{"blocked"}}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := NewBypassLimiter(inner, bypass)
+
+	results := AllowMany(context.Background(), limiter, []core.BatchRequest{
+		{Key: "blocked", Cost: 1},
+		{Key: "a", Cost: 1},
+		{Key: "b", Cost: 1},
+	})
+	if results[0].Allowed || !results[0].Bypassed {
+		t.Fatalf("expected blocked key denied by the bypass, got %+v", results[0])
+	}
+	if !results[1].Allowed || !results[2].Allowed {
+		t.Fatalf("expected a and b allowed, got %+v and %+v", results[1], results[2])
+	}
+	if store.roundTrips != 1 || store.calls != 2 {
+		t.Fatalf("expected 2 scripts in 1 round trip, got %d in %d", store.calls, store.roundTrips)
+	}
+}
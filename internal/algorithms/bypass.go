@@ -0,0 +1,101 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// BypassLimiter wraps a limiter and decides keys on its allowlist or denylist itself, without
+// consulting the wrapped limiter or touching storage. Every other key goes to the wrapped limiter.
+type BypassLimiter struct {
+	inner  core.Limiter
+	bypass *core.Bypass
+}
+
+// bypassLeaseLimiter keeps Acquire and Release available when the wrapped limiter hands out leases.
+type bypassLeaseLimiter struct {
+	*BypassLimiter
+	leaser core.LeaseLimiter
+}
+
+// NewBypassLimiter wraps inner with the compiled bypass policy. When inner is a
+// core.LeaseLimiter, so is the returned limiter.
+func NewBypassLimiter(inner core.Limiter, bypass *core.Bypass) core.Limiter {
+	b := &BypassLimiter{inner: inner, bypass: bypass}
+	if leaser, ok := inner.(core.LeaseLimiter); ok {
+		return &bypassLeaseLimiter{BypassLimiter: b, leaser: leaser}
+	}
+	return b
+}
+
+// Allow checks a single request for key.
+func (b *BypassLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return b.AllowN(ctx, key, 1)
+}
+
+// AllowN returns the bypass decision for listed keys and asks the wrapped limiter otherwise.
+func (b *BypassLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	if res, ok := b.bypass.Check(key); ok {
+		return res, nil
+	}
+	return b.inner.AllowN(ctx, key, n)
+}
+
+// AllowMany returns the bypass decision for listed keys and passes the other requests to the
+// wrapped limiter in one batch.
+func (b *BypassLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	results := make([]core.BatchResult, len(reqs))
+	rest := make([]core.BatchRequest, 0, len(reqs))
+	restIdx := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		if res, ok := b.bypass.Check(req.Key); ok {
+			results[i] = core.BatchResult{Result: res}
+			continue
+		}
+		rest = append(rest, req)
+		restIdx = append(restIdx, i)
+	}
+	for i, res := range AllowMany(ctx, b.inner, rest) {
+		results[restIdx[i]] = res
+	}
+	return results
+}
+
+// Peek returns the bypass decision for listed keys and the wrapped limiter's state otherwise.
+func (b *BypassLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if res, ok := b.bypass.Check(key); ok {
+		return res, nil
+	}
+	return b.inner.Peek(ctx, key)
+}
+
+// Refund gives n units back to key in the wrapped limiter.
+func (b *BypassLimiter) Refund(ctx context.Context, key string, n int) error {
+	return b.inner.Refund(ctx, key, n)
+}
+
+// Reset forgets the state of key in the wrapped limiter.
+func (b *BypassLimiter) Reset(ctx context.Context, key string) error {
+	return b.inner.Reset(ctx, key)
+}
+
+// Close closes the wrapped limiter.
+func (b *BypassLimiter) Close() error {
+	return b.inner.Close()
+}
+
+// Acquire returns the zero Lease with the bypass decision for listed keys, and takes a slot from
+// the wrapped limiter otherwise.
+func (b *bypassLeaseLimiter) Acquire(ctx context.Context, key string) (core.Lease, core.Result, error) {
+	if res, ok := b.bypass.Check(key); ok {
+		return core.Lease{}, res, nil
+	}
+	return b.leaser.Acquire(ctx, key)
+}
+
+// Release gives a slot back to the wrapped limiter.
+func (b *bypassLeaseLimiter) Release(ctx context.Context, lease core.Lease) error {
+	return b.leaser.Release(ctx, lease)
+}
@@ -0,0 +1,28 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// NewCalendarQuotaLimiter creates a fixed window limiter whose windows follow calendar periods
+// (cfg.Period) in cfg.Location instead of being aligned to the Unix epoch.
+// Each counter expires at the end of its period, and Reset reports the real time left until then.
+func NewCalendarQuotaLimiter(cfg core.Config, store storage.Storage) core.Limiter {
+	period, loc := cfg.Period, cfg.Location
+	return &FixedWindowLimiter{
+		limit:    cfg.Limit,
+		store:    store,
+		prefix:   "gorl:cq",
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+		failOpen: cfg.FailOpen,
+		windowAt: func(now time.Time) counterWindow {
+			start, end := period.Bounds(now, loc)
+			return counterWindow{id: start.Unix(), end: end, ttl: end.Sub(now)}
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// TestCalendarQuota_ResetReportsPeriodBoundary verifies that Reset and RetryAfter count down to the
+// real end of the calendar period in the configured location.
+func TestCalendarQuota_ResetReportsPeriodBoundary(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	loc := time.FixedZone("UTC+3", 3*3600)
+	limiter := NewCalendarQuotaLimiter(core.Config{
+		Limit: 2, Period: core.PeriodMonth, Location: loc, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	_, end := core.PeriodMonth.Bounds(time.Now(), loc)
+	untilEnd := time.Until(end)
+
+	res, err := limiter.AllowN(ctx, "customer", 2)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected quota request to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if diff := untilEnd - res.Reset; diff < 0 || diff > time.Second {
+		t.Fatalf("expected reset of %v until month end, got %v", untilEnd, res.Reset)
+	}
+
+	denied, err := limiter.Allow(ctx, "customer")
+	if err != nil || denied.Allowed {
+		t.Fatalf("expected exhausted quota to deny, got %v, err %v", denied.Allowed, err)
+	}
+	if denied.RetryAfter != denied.Reset || denied.Remaining != 0 {
+		t.Fatalf("expected retry_after to wait for the period boundary, got %+v", denied)
+	}
+}
+
+// TestCalendarQuota_KeyedByPeriodStart ensures counters are named after the local period start,
+// so every instance sharing a store agrees on the current period.
+func TestCalendarQuota_KeyedByPeriodStart(t *testing.T) {
+	store := &setFailAfterNStore{data: make(map[string]float64), failAfter: 1 << 30}
+	loc := time.FixedZone("UTC-5", -5*3600)
+	limiter := NewCalendarQuotaLimiter(core.Config{
+		Limit: 10, Period: core.PeriodDay, Location: loc, Metrics: &core.NoopMetrics{},
+	}, store)
+
+	limiter.Allow(context.Background(), "customer")
+
+	start, _ := core.PeriodDay.Bounds(time.Now(), loc)
+	key := fmt.Sprintf("gorl:cq:customer:%d", start.Unix())
+	if store.data[key] != 1 {
+		t.Fatalf("expected counter under %q, got %v", key, store.data)
+	}
+}
@@ -1,23 +1,65 @@
 package algorithms
 
 import (
-	"context"
-	"fmt"
 	"time"
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/strategy"
 )
 
-type redisScriptRunner interface {
-	EvalScript(ctx context.Context, name string, keys []string, args ...int64) ([]int64, error)
-}
+type redisScriptRunner = storage.ScriptRunner
 
 const (
+	redisScriptFixedWindow   = "fixed_window"
 	redisScriptSlidingWindow = "sliding_window"
 	redisScriptTokenBucket   = "token_bucket"
 	redisScriptLeakyBucket   = "leaky_bucket"
+	redisScriptConcurrency   = "concurrency"
+	redisScriptGCRA          = "gcra"
+	redisScriptSlidingLog    = "sliding_log"
+	redisScriptComposite     = "composite"
+	redisScriptAdaptiveLimit = "adaptive_limit"
+	redisScriptPenaltyStrike = "penalty_strike"
+
+	redisScriptSlidingWindowPeek = "sliding_window_peek"
+	redisScriptTokenBucketPeek   = "token_bucket_peek"
+	redisScriptLeakyBucketPeek   = "leaky_bucket_peek"
+	redisScriptConcurrencyPeek   = "concurrency_peek"
+	redisScriptGCRAPeek          = "gcra_peek"
+	redisScriptSlidingLogPeek    = "sliding_log_peek"
+
+	redisScriptFixedWindowRefund   = "fixed_window_refund"
+	redisScriptSlidingWindowRefund = "sliding_window_refund"
+	redisScriptTokenBucketRefund   = "token_bucket_refund"
+	redisScriptLeakyBucketRefund   = "leaky_bucket_refund"
+	redisScriptConcurrencyRefund   = "concurrency_refund"
+	redisScriptGCRARefund          = "gcra_refund"
+	redisScriptSlidingLogRefund    = "sliding_log_refund"
+
+	redisScriptConcurrencyRelease = "concurrency_release"
 )
 
+// sweepBatch bounds how many keys a request examines when limiters that keep state in process
+// memory evict idle keys. Map iteration starts at a random position, so every idle key is
+// reached eventually.
+const sweepBatch = 8
+
+// hashTag wraps key in braces so that the keys a script builds from it share one Redis Cluster
+// slot. The key is always wrapped, even if it contains braces itself, so that "k" and "{k}" keep
+// separate state.
+func hashTag(key string) string {
+	return "{" + key + "}"
+}
+
+// clockOf returns the clock configured in cfg, defaulting to the system clock.
+func clockOf(cfg core.Config) core.Clock {
+	if cfg.Clock == nil {
+		return core.SystemClock{}
+	}
+	return cfg.Clock
+}
+
 func clampDuration(d time.Duration) time.Duration {
 	if d < 0 {
 		return 0
@@ -47,43 +89,11 @@ func durationToMicros(d time.Duration) int64 {
 	return int64(us)
 }
 
-func microsToDuration(us int64) time.Duration {
-	if us <= 0 {
-		return 0
-	}
-	return time.Duration(us) * time.Microsecond
-}
-
-func buildRedisScriptResult(limit int, values []int64) (core.Result, error) {
-	if len(values) != 4 {
-		return core.Result{}, fmt.Errorf("unexpected redis script result length: %d", len(values))
-	}
-	return core.Result{
-		Allowed:    values[0] == 1,
-		Limit:      limit,
-		Remaining:  int(values[1]),
-		Reset:      microsToDuration(values[2]),
-		RetryAfter: microsToDuration(values[3]),
-	}, nil
-}
-
-// failOpenHandler centralizes fail-open logic.
-//   - start: timestamp when Allow began (for latency metrics)
-//   - err: storage/algorithm error
-//   - failOpen: cfg.FailOpen flag
-//   - m: metrics collector
-//
-// Returns (result, done):
-//   - done=true: caller should return immediately with (result, retErr)
-//   - done=false: no error, continue normal flow
-func failOpenHandler(start time.Time, err error, failOpen bool, m core.MetricsCollector, limit int) (core.Result, error, bool) {
-	if err == nil {
-		return core.Result{}, nil, false
-	}
-	if failOpen {
-		m.ObserveLatency(time.Since(start))
-		m.IncAllow()
-		return core.Result{Allowed: true, Limit: limit}, nil, true
-	}
-	return core.Result{Allowed: false, Limit: limit}, err, true
-}
+// Shared helpers, published in the strategy package for third-party algorithms.
+var (
+	buildRedisScriptResult = strategy.ScriptResult
+	validateCost           = strategy.ValidateCost
+	validateRefund         = strategy.ValidateRefund
+	failOpenHandler        = strategy.HandleFailure
+	recordDecision         = strategy.RecordDecision
+)
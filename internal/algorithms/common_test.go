@@ -1,11 +1,16 @@
 package algorithms
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
 )
 
 // TestFailOpenHandler_NoError verifies that the failOpenHandler helper returns 'false' for done
@@ -52,3 +57,163 @@ func TestFailOpenHandler_ErrorFailClosed(t *testing.T) {
 		t.Fatal("fail-closed should return error")
 	}
 }
+
+// TestAllowN_RejectsInvalidCost ensures every strategy refuses non-positive costs and costs
+// larger than the limit with a descriptive error instead of a silent denial.
+func TestAllowN_RejectsInvalidCost(t *testing.T) {
+	constructors := map[string]func(core.Config, storage.Storage) core.Limiter{
+		"FixedWindow":   NewFixedWindowLimiter,
+		"SlidingWindow": NewSlidingWindowLimiter,
+		"TokenBucket":   NewTokenBucketLimiter,
+		"LeakyBucket":   NewLeakyBucketLimiter,
+		"SlidingLog":    NewSlidingLogLimiter,
+		"GCRA":          NewGCRALimiter,
+	}
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			store := inmem.NewInMemoryStore()
+			defer store.Close()
+			m := &mockMetrics{}
+			limiter := constructor(core.Config{Limit: 5, Window: time.Minute, Metrics: m}, store)
+			ctx := context.Background()
+
+			if _, err := limiter.AllowN(ctx, "k", 6); !errors.Is(err, core.ErrCostExceedsLimit) {
+				t.Fatalf("expected ErrCostExceedsLimit, got %v", err)
+			}
+			if _, err := limiter.AllowN(ctx, "k", 0); !errors.Is(err, core.ErrInvalidCost) {
+				t.Fatalf("expected ErrInvalidCost, got %v", err)
+			}
+			if m.allows+m.denies != 0 {
+				t.Fatalf("invalid costs should not be recorded as decisions, got %d allows and %d denies", m.allows, m.denies)
+			}
+
+			res, err := limiter.AllowN(ctx, "k", 5)
+			if err != nil || !res.Allowed {
+				t.Fatalf("expected a cost equal to the limit to be allowed, got %v, err %v", res.Allowed, err)
+			}
+		})
+	}
+}
+
+// TestReset_RestoresFullCapacity ensures Reset removes every key a strategy writes,
+// so a throttled key is admitted again immediately.
+func TestReset_RestoresFullCapacity(t *testing.T) {
+	constructors := map[string]func(core.Config, storage.Storage) core.Limiter{
+		"FixedWindow":   NewFixedWindowLimiter,
+		"SlidingWindow": NewSlidingWindowLimiter,
+		"TokenBucket":   NewTokenBucketLimiter,
+		"LeakyBucket":   NewLeakyBucketLimiter,
+		"SlidingLog":    NewSlidingLogLimiter,
+		"GCRA":          NewGCRALimiter,
+	}
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			store := &setFailAfterNStore{data: make(map[string]float64), failAfter: math.MaxInt}
+			limiter := constructor(core.Config{Limit: 2, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+			defer limiter.Close()
+			ctx := context.Background()
+
+			limiter.AllowN(ctx, "user", 2)
+			if res, _ := limiter.Allow(ctx, "user"); res.Allowed {
+				t.Fatal("expected key to be throttled before reset")
+			}
+
+			if err := limiter.Reset(ctx, "user"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(store.data) != 0 {
+				t.Fatalf("expected reset to delete every stored key, left %v", store.data)
+			}
+
+			res, err := limiter.Allow(ctx, "user")
+			if err != nil || !res.Allowed {
+				t.Fatalf("expected key to be admitted after reset, got %v, err %v", res.Allowed, err)
+			}
+			if res.Remaining != 1 {
+				t.Fatalf("expected remaining=1 after reset, got %d", res.Remaining)
+			}
+		})
+	}
+}
+
+// TestHashTag_KeysWithBracesStayApart ensures a key that looks like a hash tag does not share
+// state with the key inside its braces.
+func TestHashTag_KeysWithBracesStayApart(t *testing.T) {
+	constructors := map[string]func(core.Config, storage.Storage) core.Limiter{
+		"SlidingWindow": NewSlidingWindowLimiter,
+		"TokenBucket":   NewTokenBucketLimiter,
+		"LeakyBucket":   NewLeakyBucketLimiter,
+	}
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			store := inmem.NewInMemoryStore()
+			limiter := constructor(core.Config{Limit: 2, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+			defer limiter.Close()
+			ctx := context.Background()
+
+			if res, err := limiter.AllowN(ctx, "{victim}", 2); err != nil || !res.Allowed {
+				t.Fatalf("expected {victim} to be admitted, got %v, err %v", res.Allowed, err)
+			}
+			res, err := limiter.Allow(ctx, "victim")
+			if err != nil || !res.Allowed {
+				t.Fatalf("expected victim to be admitted, got %v, err %v", res.Allowed, err)
+			}
+			if res.Remaining != 1 {
+				t.Fatalf("expected victim to keep its own state, remaining=%d", res.Remaining)
+			}
+		})
+	}
+}
+
+// TestRefund_GivesCapacityBack ensures a refunded unit can be consumed again and that
+// refunds never push a key beyond its full capacity.
+func TestRefund_GivesCapacityBack(t *testing.T) {
+	constructors := map[string]func(core.Config, storage.Storage) core.Limiter{
+		"FixedWindow":   NewFixedWindowLimiter,
+		"SlidingWindow": NewSlidingWindowLimiter,
+		"TokenBucket":   NewTokenBucketLimiter,
+		"LeakyBucket":   NewLeakyBucketLimiter,
+		"SlidingLog":    NewSlidingLogLimiter,
+		"GCRA":          NewGCRALimiter,
+	}
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			store := inmem.NewInMemoryStore()
+			defer store.Close()
+			limiter := constructor(core.Config{Limit: 3, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+			ctx := context.Background()
+
+			if err := limiter.Refund(ctx, "user", 1); err != nil {
+				t.Fatalf("unexpected error refunding an untouched key: %v", err)
+			}
+
+			limiter.AllowN(ctx, "user", 3)
+			if err := limiter.Refund(ctx, "user", 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			res, err := limiter.Allow(ctx, "user")
+			if err != nil || !res.Allowed {
+				t.Fatalf("expected refunded unit to be available, got %v, err %v", res.Allowed, err)
+			}
+			if res, _ := limiter.Allow(ctx, "user"); res.Allowed {
+				t.Fatal("expected only the refunded unit to be available")
+			}
+
+			if err := limiter.Refund(ctx, "user", 10); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			peek, err := limiter.Peek(ctx, "user")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if peek.Remaining != 3 {
+				t.Fatalf("expected oversized refund to cap at full capacity, got remaining=%d", peek.Remaining)
+			}
+
+			if err := limiter.Refund(ctx, "user", 0); !errors.Is(err, core.ErrInvalidCost) {
+				t.Fatalf("expected ErrInvalidCost for empty refund, got %v", err)
+			}
+		})
+	}
+}
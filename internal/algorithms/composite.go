@@ -0,0 +1,333 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// Policy kinds understood by the composite Lua script.
+const (
+	compositeFixedWindow int64 = iota + 1
+	compositeSlidingWindow
+	compositeTokenBucket
+	compositeLeakyBucket
+	compositeGCRA
+)
+
+// compositePolicy describes how the composite Lua script evaluates one member for one key.
+// The meaning of args depends on kind and mirrors the member's own script.
+type compositePolicy struct {
+	kind  int64
+	limit int
+	keys  []string
+	args  [2]int64
+}
+
+// compositeMember is implemented by limiters that can take part in a CompositeLimiter.
+type compositeMember interface {
+	core.Limiter
+	compositePolicy(key string, now time.Time) compositePolicy
+}
+
+// taggedMember is implemented by members that wrap keys in a Redis hash tag. A composite tells
+// them that its member keys already carry the tag, so they use those keys as they are.
+type taggedMember interface {
+	useTaggedKeys()
+}
+
+// CompositeLimiter enforces several policies on the same key with all-or-nothing semantics:
+// a request is charged against every policy or against none of them.
+// On Redis, all policies are checked and charged by a single Lua script.
+type CompositeLimiter struct {
+	members  []compositeMember
+	minLimit int
+	store    storage.Storage
+	mu       sync.Mutex
+	metrics  core.MetricsCollector
+	clock    core.Clock
+	failOpen bool
+}
+
+// NewCompositeLimiter combines members into one limiter. Members must share store and should
+// record no metrics of their own; cfg.Metrics and cfg.FailOpen apply to the composite decision.
+// Every member must be a FixedWindow, CalendarQuota, SlidingWindow, TokenBucket, LeakyBucket or GCRA limiter.
+func NewCompositeLimiter(cfg core.Config, store storage.Storage, members []core.Limiter) (core.Limiter, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("%w: at least one policy is required", core.ErrConfigInvalid)
+	}
+
+	c := &CompositeLimiter{
+		members:  make([]compositeMember, len(members)),
+		store:    store,
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+		failOpen: cfg.FailOpen,
+	}
+	now := c.clock.Now()
+	for i, member := range members {
+		m, ok := member.(compositeMember)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T cannot be combined in a composite limiter", core.ErrConfigInvalid, member)
+		}
+		if t, ok := m.(taggedMember); ok {
+			t.useTaggedKeys()
+		}
+		c.members[i] = m
+		if limit := m.compositePolicy("", now).limit; c.minLimit == 0 || limit < c.minLimit {
+			c.minLimit = limit
+		}
+	}
+	return c, nil
+}
+
+// Allow checks a single request against every policy.
+func (c *CompositeLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return c.AllowN(ctx, key, 1)
+}
+
+// AllowN charges n units against every policy if all of them allow it, and against none otherwise.
+// The returned Result is the most restrictive one: the longest RetryAfter among denying policies,
+// or the lowest Remaining when allowed.
+func (c *CompositeLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	res, _, err := c.allowKeys(ctx, c.memberKeys(key), n)
+	return res, err
+}
+
+// allowKeys is AllowN with an explicit storage key per member. Besides the most restrictive
+// Result it returns the index of the member that produced it, or -1 if no member did.
+func (c *CompositeLimiter) allowKeys(ctx context.Context, keys []string, n int) (core.Result, int, error) {
+	if err := validateCost(n, c.minLimit); err != nil {
+		return core.Result{Limit: c.minLimit}, -1, err
+	}
+
+	start := time.Now()
+	if runner, ok := c.store.(redisScriptRunner); ok {
+		return c.allowRedis(ctx, start, runner, keys, n)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.allowGeneric(ctx, start, keys, n)
+}
+
+func (c *CompositeLimiter) allowGeneric(ctx context.Context, start time.Time, keys []string, n int) (core.Result, int, error) {
+	results := make([]core.Result, len(c.members))
+	allowed := true
+	for i, m := range c.members {
+		res, err := m.AllowN(ctx, keys[i], n)
+		if err != nil {
+			res, retErr, _ := failOpenHandler(start, errors.Join(err, c.refundAllowed(ctx, keys, n, results[:i])), c.failOpen, c.metrics, c.minLimit)
+			return res, -1, retErr
+		}
+		results[i] = res
+		allowed = allowed && res.Allowed
+	}
+
+	if !allowed {
+		// Hand back what the allowing policies consumed so a denial charges nothing.
+		err := c.refundAllowed(ctx, keys, n, results)
+		if res, retErr, done := failOpenHandler(start, err, c.failOpen, c.metrics, c.minLimit); done {
+			return res, -1, retErr
+		}
+	}
+
+	c.metrics.ObserveLatency(time.Since(start))
+	if allowed {
+		c.metrics.IncAllow()
+	} else {
+		c.metrics.IncDeny()
+	}
+	res, index := mostRestrictive(results)
+	return res, index, nil
+}
+
+// refundAllowed refunds n units to every member whose result in results was allowed.
+func (c *CompositeLimiter) refundAllowed(ctx context.Context, keys []string, n int, results []core.Result) error {
+	var errs []error
+	for i, res := range results {
+		if res.Allowed {
+			errs = append(errs, c.members[i].Refund(ctx, keys[i], n))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CompositeLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, keys []string, n int) (core.Result, int, error) {
+	now := c.clock.Now()
+	args := []int64{now.UnixMicro(), int64(n), int64(len(c.members))}
+	var scriptKeys []string
+	limits := make([]int, len(c.members))
+	for i, m := range c.members {
+		p := m.compositePolicy(keys[i], now)
+		scriptKeys = append(scriptKeys, p.keys...)
+		args = append(args, p.kind, int64(p.limit), p.args[0], p.args[1])
+		limits[i] = p.limit
+	}
+
+	values, err := runner.EvalScript(ctx, redisScriptComposite, scriptKeys, args...)
+	if err == nil && (len(values) != 5 || values[4] < 0 || values[4] >= int64(len(limits))) {
+		err = fmt.Errorf("unexpected redis script result: %v", values)
+	}
+	if res, retErr, done := failOpenHandler(start, err, c.failOpen, c.metrics, c.minLimit); done {
+		return res, -1, retErr
+	}
+
+	index := int(values[4])
+	res, err := buildRedisScriptResult(limits[index], values[:4])
+	if res2, retErr, done := failOpenHandler(start, err, c.failOpen, c.metrics, c.minLimit); done {
+		return res2, -1, retErr
+	}
+
+	c.metrics.ObserveLatency(time.Since(start))
+	if res.Allowed {
+		c.metrics.IncAllow()
+	} else {
+		c.metrics.IncDeny()
+	}
+	return res, index, nil
+}
+
+// Peek reports the most restrictive state across all policies without consuming capacity.
+func (c *CompositeLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	res, _, err := c.peekKeys(ctx, c.memberKeys(key))
+	return res, err
+}
+
+func (c *CompositeLimiter) peekKeys(ctx context.Context, keys []string) (core.Result, int, error) {
+	results := make([]core.Result, len(c.members))
+	for i, m := range c.members {
+		res, err := m.Peek(ctx, keys[i])
+		if err != nil {
+			return core.Result{Limit: c.minLimit}, -1, err
+		}
+		results[i] = res
+	}
+	res, index := mostRestrictive(results)
+	return res, index, nil
+}
+
+// Refund gives n units back to every policy for key.
+func (c *CompositeLimiter) Refund(ctx context.Context, key string, n int) error {
+	return c.refundKeys(ctx, c.memberKeys(key), n)
+}
+
+func (c *CompositeLimiter) refundKeys(ctx context.Context, keys []string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, m := range c.members {
+		errs = append(errs, m.Refund(ctx, keys[i], n))
+	}
+	return errors.Join(errs...)
+}
+
+// Reset forgets the state of every policy for key.
+func (c *CompositeLimiter) Reset(ctx context.Context, key string) error {
+	var errs []error
+	for i, m := range c.members {
+		errs = append(errs, m.Reset(ctx, memberKey(key, i)))
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases resources held by the limiter.
+func (c *CompositeLimiter) Close() error {
+	return c.store.Close()
+}
+
+// memberKeys scopes key to each policy, so policies sharing a strategy keep separate state.
+func (c *CompositeLimiter) memberKeys(key string) []string {
+	keys := make([]string, len(c.members))
+	for i := range keys {
+		keys[i] = memberKey(key, i)
+	}
+	return keys
+}
+
+// memberKey names the state of key under the policy at index. The hash tag covers key only, so
+// with Redis Cluster the keys of every policy share the slot the composite script runs in.
+func memberKey(key string, index int) string {
+	return fmt.Sprintf("{%s}#%d", key, index)
+}
+
+// mostRestrictive picks the result to report for a composite decision: among denials the one
+// with the longest RetryAfter, otherwise the one with the least Remaining (then the longest Reset).
+// It also returns the index of the picked result.
+func mostRestrictive(results []core.Result) (core.Result, int) {
+	allowed := true
+	for _, res := range results {
+		allowed = allowed && res.Allowed
+	}
+
+	best := -1
+	for i, res := range results {
+		switch {
+		case !allowed:
+			if !res.Allowed && (best < 0 || res.RetryAfter > results[best].RetryAfter) {
+				best = i
+			}
+		case best < 0 || res.Remaining < results[best].Remaining ||
+			(res.Remaining == results[best].Remaining && res.Reset > results[best].Reset):
+			best = i
+		}
+	}
+
+	res := results[best]
+	res.Allowed = allowed
+	return res, best
+}
+
+func (f *FixedWindowLimiter) compositePolicy(key string, now time.Time) compositePolicy {
+	w := f.windowAt(now)
+	return compositePolicy{
+		kind:  compositeFixedWindow,
+		limit: f.limit,
+		keys:  []string{f.storageKey(key, w)},
+		args:  [2]int64{w.end.UnixMicro(), durationToMilliseconds(w.ttl)},
+	}
+}
+
+func (s *SlidingWindowLimiter) compositePolicy(key string, _ time.Time) compositePolicy {
+	return compositePolicy{
+		kind:  compositeSlidingWindow,
+		limit: s.limit,
+		keys:  s.storageKeys(key),
+		args:  [2]int64{durationToMicros(s.window), durationToMilliseconds(s.stateTTL)},
+	}
+}
+
+func (t *TokenBucketLimiter) compositePolicy(key string, _ time.Time) compositePolicy {
+	return compositePolicy{
+		kind:  compositeTokenBucket,
+		limit: t.limit,
+		keys:  t.storageKeys(key),
+		args:  [2]int64{durationToMilliseconds(t.ttl), durationToMicros(time.Duration(t.timePerToken))},
+	}
+}
+
+func (l *LeakyBucketLimiter) compositePolicy(key string, _ time.Time) compositePolicy {
+	return compositePolicy{
+		kind:  compositeLeakyBucket,
+		limit: l.limit,
+		keys:  l.storageKeys(key),
+		args:  [2]int64{durationToMicros(l.window), durationToMilliseconds(l.window)},
+	}
+}
+
+func (g *GCRALimiter) compositePolicy(key string, _ time.Time) compositePolicy {
+	return compositePolicy{
+		kind:  compositeGCRA,
+		limit: g.limit,
+		keys:  []string{g.storageKey(key)},
+		args:  [2]int64{durationToMicros(time.Duration(g.emissionInterval)), 0},
+	}
+}
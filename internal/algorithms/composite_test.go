@@ -0,0 +1,196 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+func newTestComposite(t *testing.T, store storage.Storage, metrics core.MetricsCollector, members ...core.Limiter) core.Limiter {
+	t.Helper()
+	limiter, err := NewCompositeLimiter(core.Config{Metrics: metrics}, store, members)
+	if err != nil {
+		t.Fatalf("NewCompositeLimiter: %v", err)
+	}
+	return limiter
+}
+
+// TestComposite_AllOrNothing verifies that a denial by one policy charges none of the others.
+func TestComposite_AllOrNothing(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	perSecond := NewFixedWindowLimiter(core.Config{Limit: 5, Window: time.Second, Metrics: &core.NoopMetrics{}}, store)
+	perHour := NewFixedWindowLimiter(core.Config{Limit: 2, Window: time.Hour, Metrics: &core.NoopMetrics{}}, store)
+	metrics := &mockMetrics{}
+	limiter := newTestComposite(t, store, metrics, perSecond, perHour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed, got %v, err %v", i+1, res.Allowed, err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		res, err := limiter.Allow(ctx, "k")
+		if err != nil || res.Allowed {
+			t.Fatalf("expected denied by the hourly policy, got %v, err %v", res.Allowed, err)
+		}
+		if res.Limit != 2 {
+			t.Fatalf("expected the hourly policy to be reported, got limit %d", res.Limit)
+		}
+	}
+
+	// The per-second policy must only hold the two admitted requests.
+	res, err := perSecond.Peek(ctx, memberKey("k", 0))
+	if err != nil {
+		t.Fatalf("peek: %v", err)
+	}
+	if res.Remaining != 3 {
+		t.Fatalf("expected denials to leave the per-second policy uncharged, remaining=%d", res.Remaining)
+	}
+	if metrics.allows != 2 || metrics.denies != 3 {
+		t.Fatalf("expected 2 allows and 3 denials, got %d/%d", metrics.allows, metrics.denies)
+	}
+}
+
+// TestComposite_MostRestrictiveResult checks which policy's metadata is reported.
+func TestComposite_MostRestrictiveResult(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	short := NewFixedWindowLimiter(core.Config{Limit: 3, Window: time.Second, Metrics: &core.NoopMetrics{}}, store)
+	long := NewFixedWindowLimiter(core.Config{Limit: 10, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+	limiter := newTestComposite(t, store, &core.NoopMetrics{}, short, long)
+	ctx := context.Background()
+
+	res, err := limiter.AllowN(ctx, "k", 2)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Limit != 3 || res.Remaining != 1 {
+		t.Fatalf("expected the policy with least remaining, got limit=%d remaining=%d", res.Limit, res.Remaining)
+	}
+
+	res, err = limiter.AllowN(ctx, "k", 2)
+	if err != nil || res.Allowed {
+		t.Fatalf("expected denied, got %v, err %v", res.Allowed, err)
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > time.Second {
+		t.Fatalf("expected retry_after from the per-second policy, got %v", res.RetryAfter)
+	}
+}
+
+// TestComposite_CostAboveSmallestLimit ensures costs are validated against the tightest policy.
+func TestComposite_CostAboveSmallestLimit(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := newTestComposite(t, store, &core.NoopMetrics{},
+		NewTokenBucketLimiter(core.Config{Limit: 10, Window: time.Second, Metrics: &core.NoopMetrics{}}, store),
+		NewGCRALimiter(core.Config{Limit: 4, Window: time.Second, Metrics: &core.NoopMetrics{}}, store),
+	)
+
+	_, err := limiter.AllowN(context.Background(), "k", 5)
+	if !errors.Is(err, core.ErrCostExceedsLimit) {
+		t.Fatalf("expected ErrCostExceedsLimit, got %v", err)
+	}
+}
+
+// TestComposite_RejectsUnsupportedMembers ensures strategies without composite support are refused.
+func TestComposite_RejectsUnsupportedMembers(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	member := NewSlidingLogLimiter(core.Config{Limit: 1, Window: time.Second, Metrics: &core.NoopMetrics{}}, store)
+
+	_, err := NewCompositeLimiter(core.Config{Metrics: &core.NoopMetrics{}}, store, []core.Limiter{member})
+	if !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid, got %v", err)
+	}
+}
+
+// TestComposite_RefundAndReset verifies that both operations reach every policy.
+func TestComposite_RefundAndReset(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := newTestComposite(t, store, &core.NoopMetrics{},
+		NewSlidingWindowLimiter(core.Config{Limit: 2, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store),
+		NewLeakyBucketLimiter(core.Config{Limit: 2, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store),
+	)
+	ctx := context.Background()
+
+	limiter.AllowN(ctx, "k", 2)
+	if res, _ := limiter.Allow(ctx, "k"); res.Allowed {
+		t.Fatal("expected denied once both policies are full")
+	}
+
+	if err := limiter.Refund(ctx, "k", 1); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if res, _ := limiter.Allow(ctx, "k"); !res.Allowed {
+		t.Fatal("expected refunded unit to be usable")
+	}
+
+	if err := limiter.Reset(ctx, "k"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	res, err := limiter.Peek(ctx, "k")
+	if err != nil || res.Remaining != 2 {
+		t.Fatalf("expected full capacity after reset, got remaining=%d, err %v", res.Remaining, err)
+	}
+}
+
+// TestComposite_FailOpen ensures backend errors follow the composite's fail-open setting.
+func TestComposite_FailOpen(t *testing.T) {
+	store := &failingStore{}
+	member := NewFixedWindowLimiter(core.Config{Limit: 1, Window: time.Second, Metrics: &core.NoopMetrics{}}, store)
+	limiter, err := NewCompositeLimiter(core.Config{FailOpen: true, Metrics: &core.NoopMetrics{}}, store, []core.Limiter{member})
+	if err != nil {
+		t.Fatalf("NewCompositeLimiter: %v", err)
+	}
+
+	res, err := limiter.Allow(context.Background(), "k")
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected fail-open allow, got %v, err %v", res.Allowed, err)
+	}
+}
+
+// TestComposite_KeysShareHashSlot checks that every key of a composite script carries the same
+// Redis hash tag, so the script does not fail with CROSSSLOT on Redis Cluster.
+func TestComposite_KeysShareHashSlot(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	cfg := core.Config{Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{}}
+	limiter := newTestComposite(t, store, &core.NoopMetrics{},
+		NewFixedWindowLimiter(cfg, store),
+		NewSlidingWindowLimiter(cfg, store),
+		NewTokenBucketLimiter(cfg, store),
+		NewLeakyBucketLimiter(cfg, store),
+		NewGCRALimiter(cfg, store),
+	).(*CompositeLimiter)
+
+	for _, key := range []string{"user-1", "tenant{a}"} {
+		keys := limiter.memberKeys(key)
+		want := redisHashTag(keys[0])
+		for i, m := range limiter.members {
+			for _, k := range m.compositePolicy(keys[i], time.Now()).keys {
+				if tag := redisHashTag(k); tag != want {
+					t.Fatalf("member %d: key %q hashes on %q, want %q", i, k, tag, want)
+				}
+			}
+		}
+	}
+}
+
+// redisHashTag returns the part of key Redis Cluster hashes to pick a slot.
+func redisHashTag(key string) string {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			return key[open+1 : open+1+end]
+		}
+	}
+	return key
+}
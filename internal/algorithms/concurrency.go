@@ -0,0 +1,330 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// ConcurrencyLimiter bounds the number of in-flight leases per key.
+// On Redis, leases live in a sorted set scored by their expiry and are managed by Lua scripts.
+// Other backends cannot express per-lease expiry, so leases are then tracked in process memory.
+type ConcurrencyLimiter struct {
+	limit    int
+	ttl      time.Duration
+	store    storage.Storage
+	prefix   string
+	mu       sync.Mutex
+	leases   map[string][]leaseEntry
+	seq      uint64
+	metrics  core.MetricsCollector
+	clock    core.Clock
+	failOpen bool
+}
+
+// leaseEntry is a lease held in process memory. Entries for a key are kept in expiry order.
+type leaseEntry struct {
+	id        string
+	expiresAt int64
+}
+
+// NewConcurrencyLimiter constructs a new ConcurrencyLimiter.
+// cfg.Limit is the maximum number of concurrent leases per key and cfg.Window is the lease TTL.
+func NewConcurrencyLimiter(cfg core.Config, store storage.Storage) core.Limiter {
+	return &ConcurrencyLimiter{
+		limit:    cfg.Limit,
+		ttl:      cfg.Window,
+		store:    store,
+		prefix:   "gorl:cc",
+		leases:   make(map[string][]leaseEntry),
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+		failOpen: cfg.FailOpen,
+	}
+}
+
+func (c *ConcurrencyLimiter) setLimit(limit int) {
+	c.limit = limit
+}
+
+// Allow acquires an anonymous lease for key. It is only given back by Refund or by expiring.
+func (c *ConcurrencyLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return c.AllowN(ctx, key, 1)
+}
+
+// AllowN acquires n anonymous leases for key if all of them fit.
+func (c *ConcurrencyLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := c.store.(redisScriptRunner)
+	return c.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (c *ConcurrencyLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, c.store, reqs, c.allow)
+}
+
+func (c *ConcurrencyLimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	_, res, err := c.acquire(ctx, runner, key, n)
+	return res, err
+}
+
+// Acquire takes one slot for key and returns the lease to hand back with Release.
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context, key string) (core.Lease, core.Result, error) {
+	runner, _ := c.store.(redisScriptRunner)
+	return c.acquire(ctx, runner, key, 1)
+}
+
+func (c *ConcurrencyLimiter) acquire(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Lease, core.Result, error) {
+	if err := validateCost(n, c.limit); err != nil {
+		return core.Lease{}, core.Result{Limit: c.limit}, err
+	}
+
+	start := time.Now()
+	if runner != nil {
+		return c.acquireRedis(ctx, start, runner, key, n)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.acquireGeneric(start, key, n)
+}
+
+func (c *ConcurrencyLimiter) acquireGeneric(start time.Time, key string, n int) (core.Lease, core.Result, error) {
+	now := c.clock.Now().UnixNano()
+	c.sweep(now)
+	held := c.live(key, now)
+
+	var lease core.Lease
+	allowed := len(held)+n <= c.limit
+	if allowed {
+		expiresAt := now + c.ttl.Nanoseconds()
+		for i := 0; i < n; i++ {
+			c.seq++
+			held = append(held, leaseEntry{id: strconv.FormatUint(c.seq, 10), expiresAt: expiresAt})
+		}
+		c.leases[key] = held
+		lease = core.Lease{Key: key, ID: held[len(held)-n].id}
+	}
+
+	reset, wait := c.timing(held, now, n)
+	res := core.Result{
+		Allowed:   allowed,
+		Limit:     c.limit,
+		Remaining: c.limit - len(held),
+		Reset:     reset,
+	}
+
+	c.metrics.ObserveLatency(time.Since(start))
+	if allowed {
+		c.metrics.IncAllow()
+	} else {
+		c.metrics.IncDeny()
+		res.RetryAfter = wait
+	}
+
+	return lease, res, nil
+}
+
+func (c *ConcurrencyLimiter) acquireRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string, n int) (core.Lease, core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptConcurrency,
+		c.storageKeys(key),
+		int64(c.limit),
+		c.clock.Now().UnixMicro(),
+		durationToMicros(c.ttl),
+		durationToMilliseconds(c.ttl),
+		int64(n),
+	)
+	if err == nil && len(values) != 5 {
+		err = fmt.Errorf("unexpected redis script result length: %d", len(values))
+	}
+	if res, retErr, done := failOpenHandler(start, err, c.failOpen, c.metrics, c.limit); done {
+		return core.Lease{}, res, retErr
+	}
+
+	res, err := buildRedisScriptResult(c.limit, values[:4])
+	if res2, retErr, done := failOpenHandler(start, err, c.failOpen, c.metrics, c.limit); done {
+		return core.Lease{}, res2, retErr
+	}
+
+	c.metrics.ObserveLatency(time.Since(start))
+	var lease core.Lease
+	if res.Allowed {
+		c.metrics.IncAllow()
+		lease = core.Lease{Key: key, ID: strconv.FormatInt(values[4], 10)}
+	} else {
+		c.metrics.IncDeny()
+	}
+
+	return lease, res, nil
+}
+
+// Release gives the slot held by lease back. Releasing an expired or unknown lease is a no-op.
+func (c *ConcurrencyLimiter) Release(ctx context.Context, lease core.Lease) error {
+	if lease.ID == "" {
+		return nil
+	}
+
+	if runner, ok := c.store.(redisScriptRunner); ok {
+		id, err := strconv.ParseInt(lease.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid lease id %q: %w", lease.ID, err)
+		}
+		_, err = runner.EvalScript(ctx, redisScriptConcurrencyRelease, c.storageKeys(lease.Key)[:1], id)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	held := c.leases[lease.Key]
+	for i, entry := range held {
+		if entry.id == lease.ID {
+			c.setLeases(lease.Key, append(held[:i:i], held[i+1:]...))
+			break
+		}
+	}
+	return nil
+}
+
+// Peek reports how many leases key holds without acquiring one.
+// Allowed tells whether a single lease could currently be acquired.
+func (c *ConcurrencyLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if runner, ok := c.store.(redisScriptRunner); ok {
+		values, err := runner.EvalScript(
+			ctx,
+			redisScriptConcurrencyPeek,
+			c.storageKeys(key)[:1],
+			int64(c.limit),
+			c.clock.Now().UnixMicro(),
+		)
+		if err != nil {
+			return core.Result{Limit: c.limit}, err
+		}
+		return buildRedisScriptResult(c.limit, values)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now().UnixNano()
+	held := c.live(key, now)
+	reset, wait := c.timing(held, now, 1)
+
+	res := core.Result{
+		Allowed:   len(held) < c.limit,
+		Limit:     c.limit,
+		Remaining: c.limit - len(held),
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = wait
+	}
+	return res, nil
+}
+
+// Refund releases the n leases of key that would expire soonest.
+// It is meant for callers that acquired anonymous leases through Allow or AllowN.
+func (c *ConcurrencyLimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	if runner, ok := c.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(
+			ctx,
+			redisScriptConcurrencyRefund,
+			c.storageKeys(key)[:1],
+			c.clock.Now().UnixMicro(),
+			int64(n),
+		)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	held := c.live(key, c.clock.Now().UnixNano())
+	if n > len(held) {
+		n = len(held)
+	}
+	c.setLeases(key, held[n:])
+	return nil
+}
+
+// Reset drops every lease held for key.
+func (c *ConcurrencyLimiter) Reset(ctx context.Context, key string) error {
+	if _, ok := c.store.(redisScriptRunner); ok {
+		return c.store.Delete(ctx, c.storageKeys(key)...)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.leases, key)
+	return nil
+}
+
+// Close releases resources held by the limiter.
+func (c *ConcurrencyLimiter) Close() error {
+	return c.store.Close()
+}
+
+// live prunes expired leases for key and returns the ones still held. Callers must hold c.mu.
+func (c *ConcurrencyLimiter) live(key string, now int64) []leaseEntry {
+	held := c.leases[key]
+	expired := 0
+	for expired < len(held) && held[expired].expiresAt <= now {
+		expired++
+	}
+	if expired > 0 {
+		held = held[expired:]
+		c.setLeases(key, held)
+	}
+	return held
+}
+
+// sweep prunes the leases of up to sweepBatch other keys, so keys whose leases all expired
+// without being released are eventually forgotten. Callers must hold c.mu.
+func (c *ConcurrencyLimiter) sweep(now int64) {
+	checked := 0
+	for key := range c.leases {
+		if checked == sweepBatch {
+			return
+		}
+		checked++
+		c.live(key, now)
+	}
+}
+
+// setLeases stores the leases held for key, forgetting the key once none are left.
+func (c *ConcurrencyLimiter) setLeases(key string, held []leaseEntry) {
+	if len(held) == 0 {
+		delete(c.leases, key)
+		return
+	}
+	c.leases[key] = held
+}
+
+// timing returns how long until every lease has expired and how long until cost more leases fit.
+func (c *ConcurrencyLimiter) timing(held []leaseEntry, now int64, cost int) (time.Duration, time.Duration) {
+	reset := time.Duration(0)
+	if len(held) > 0 {
+		reset = clampDuration(time.Duration(held[len(held)-1].expiresAt - now))
+	}
+	wait := time.Duration(0)
+	if needed := len(held) + cost - c.limit; needed > 0 {
+		wait = clampDuration(time.Duration(held[needed-1].expiresAt - now))
+	}
+	return reset, wait
+}
+
+func (c *ConcurrencyLimiter) storageKeys(key string) []string {
+	return []string{
+		fmt.Sprintf("%s:{%s}:leases", c.prefix, key),
+		fmt.Sprintf("%s:{%s}:seq", c.prefix, key),
+	}
+}
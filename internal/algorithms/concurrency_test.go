@@ -0,0 +1,131 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+func newTestConcurrencyLimiter(limit int, ttl time.Duration) core.LeaseLimiter {
+	return NewConcurrencyLimiter(core.Config{
+		Limit: limit, Window: ttl, Metrics: &core.NoopMetrics{},
+	}, inmem.NewInMemoryStore()).(core.LeaseLimiter)
+}
+
+// TestConcurrency_AcquireAndRelease verifies that slots are bounded and come back on Release.
+func TestConcurrency_AcquireAndRelease(t *testing.T) {
+	limiter := newTestConcurrencyLimiter(2, time.Minute)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	first, res, err := limiter.Acquire(ctx, "tenant")
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected first lease, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 1 || res.Reset <= 0 {
+		t.Fatalf("unexpected metadata after first lease: %+v", res)
+	}
+	if _, res, _ = limiter.Acquire(ctx, "tenant"); !res.Allowed {
+		t.Fatal("expected second lease")
+	}
+
+	_, denied, err := limiter.Acquire(ctx, "tenant")
+	if err != nil || denied.Allowed {
+		t.Fatalf("expected third lease to be denied, got %v, err %v", denied.Allowed, err)
+	}
+	if denied.Remaining != 0 || denied.RetryAfter <= 0 || denied.RetryAfter > time.Minute {
+		t.Fatalf("unexpected metadata on denied lease: %+v", denied)
+	}
+
+	if err := limiter.Release(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := limiter.Release(ctx, first); err != nil {
+		t.Fatalf("releasing twice should be a no-op, got %v", err)
+	}
+	if _, res, _ = limiter.Acquire(ctx, "tenant"); !res.Allowed {
+		t.Fatal("expected released slot to be reusable")
+	}
+	if _, res, _ = limiter.Acquire(ctx, "tenant"); res.Allowed {
+		t.Fatal("a double release must not free a second slot")
+	}
+}
+
+// TestConcurrency_LeasesExpire ensures abandoned leases stop counting after their TTL.
+func TestConcurrency_LeasesExpire(t *testing.T) {
+	limiter := newTestConcurrencyLimiter(1, 50*time.Millisecond)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	if res, _ := limiter.Allow(ctx, "tenant"); !res.Allowed {
+		t.Fatal("expected first lease")
+	}
+	if res, _ := limiter.Allow(ctx, "tenant"); res.Allowed {
+		t.Fatal("expected second lease to be denied")
+	}
+
+	time.Sleep(70 * time.Millisecond)
+	if res, _ := limiter.Allow(ctx, "tenant"); !res.Allowed {
+		t.Fatal("expected expired lease to free its slot")
+	}
+}
+
+// TestConcurrency_PeekRefundAndReset covers the non-admission operations on anonymous leases.
+func TestConcurrency_PeekRefundAndReset(t *testing.T) {
+	limiter := newTestConcurrencyLimiter(3, time.Minute)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	if res, err := limiter.AllowN(ctx, "tenant", 3); err != nil || !res.Allowed {
+		t.Fatalf("expected weighted acquire, got %v, err %v", res.Allowed, err)
+	}
+
+	peek, err := limiter.Peek(ctx, "tenant")
+	if err != nil || peek.Allowed || peek.Remaining != 0 || peek.RetryAfter <= 0 {
+		t.Fatalf("expected exhausted peek result, got %+v, err %v", peek, err)
+	}
+
+	if err := limiter.Refund(ctx, "tenant", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peek, _ = limiter.Peek(ctx, "tenant"); peek.Remaining != 2 {
+		t.Fatalf("expected two refunded slots, got %+v", peek)
+	}
+
+	if err := limiter.Reset(ctx, "tenant"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peek, _ = limiter.Peek(ctx, "tenant"); peek.Remaining != 3 || peek.Reset != 0 {
+		t.Fatalf("expected no leases after reset, got %+v", peek)
+	}
+}
+
+// TestConcurrency_ForgetsIdleKeys checks that keys whose leases expired unreleased are dropped
+// by later requests for other keys.
+func TestConcurrency_ForgetsIdleKeys(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewConcurrencyLimiter(core.Config{
+		Limit: 1, Window: time.Second, Metrics: &core.NoopMetrics{}, Clock: clock,
+	}, inmem.NewInMemoryStoreWithClock(clock)).(*ConcurrencyLimiter)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		limiter.Allow(ctx, fmt.Sprintf("idle-%d", i))
+	}
+	clock.Advance(2 * time.Second)
+	for i := 0; i < 100; i++ {
+		limiter.Allow(ctx, "active")
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if len(limiter.leases) != 1 {
+		t.Fatalf("expected only the active key to be tracked, got %d keys", len(limiter.leases))
+	}
+}
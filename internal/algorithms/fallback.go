@@ -0,0 +1,251 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// fallbackLeasePrefix marks the IDs of leases taken from the fallback limiter, so that Release
+// can send them back there.
+const fallbackLeasePrefix = "fallback:"
+
+// FallbackLimiter decides requests on a primary limiter backed by a shared store, and on a local
+// limiter while a circuit breaker around the primary is open. Results from the local limiter
+// have Fallback set.
+type FallbackLimiter struct {
+	primary core.Limiter
+	local   core.Limiter
+	breaker *circuitBreaker
+}
+
+// fallbackLeaseLimiter keeps Acquire and Release available when both limiters hand out leases.
+type fallbackLeaseLimiter struct {
+	*FallbackLimiter
+	primaryLeaser core.LeaseLimiter
+	localLeaser   core.LeaseLimiter
+}
+
+// NewFallbackLimiter wraps primary and local with the breaker of cfg.Fallback. primary must be
+// built to fail closed, so that backend errors reach the breaker. When both limiters are
+// core.LeaseLimiters, so is the returned limiter.
+func NewFallbackLimiter(primary, local core.Limiter, cfg core.Config) core.Limiter {
+	threshold := cfg.Fallback.ErrorThreshold
+	if threshold == 0 {
+		threshold = core.DefaultFallbackErrorThreshold
+	}
+	cooldown := cfg.Fallback.Cooldown
+	if cooldown == 0 {
+		cooldown = core.DefaultFallbackCooldown
+	}
+	f := &FallbackLimiter{
+		primary: primary,
+		local:   local,
+		breaker: &circuitBreaker{threshold: threshold, cooldown: cooldown, clock: clockOf(cfg)},
+	}
+	primaryLeaser, ok := primary.(core.LeaseLimiter)
+	localLeaser, localOK := local.(core.LeaseLimiter)
+	if ok && localOK {
+		return &fallbackLeaseLimiter{FallbackLimiter: f, primaryLeaser: primaryLeaser, localLeaser: localLeaser}
+	}
+	return f
+}
+
+// Allow checks a single request for key.
+func (f *FallbackLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+// AllowN asks the primary limiter while the breaker is closed, and the local limiter while it is
+// open or when the primary fails.
+func (f *FallbackLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	if ok, probe := f.breaker.try(); ok {
+		res, err := f.primary.AllowN(ctx, key, n)
+		if !f.breaker.record(ctx, err, probe) {
+			return res, err
+		}
+	}
+	return markFallback(f.local.AllowN(ctx, key, n))
+}
+
+// AllowMany passes the requests to the primary limiter in one batch while the breaker is closed.
+// Requests the primary failed on, and every request while the breaker is open, are decided by
+// the local limiter in one batch.
+func (f *FallbackLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	if len(reqs) == 0 {
+		return nil
+	}
+	ok, probe := f.breaker.try()
+	if !ok {
+		return markFallbacks(AllowMany(ctx, f.local, reqs))
+	}
+
+	results := AllowMany(ctx, f.primary, reqs)
+	// The batch shares one backend, so it counts as one call for the breaker: a failure if any
+	// request hit a backend error, a success if any got through, and neither otherwise.
+	outcome := results[0].Err
+	rest := make([]core.BatchRequest, 0, len(reqs))
+	restIdx := make([]int, 0, len(reqs))
+	for i, res := range results {
+		switch {
+		case backendFailure(ctx, res.Err):
+			outcome = res.Err
+			rest = append(rest, reqs[i])
+			restIdx = append(restIdx, i)
+		case res.Err == nil && len(rest) == 0:
+			outcome = nil
+		}
+	}
+	if !f.breaker.record(ctx, outcome, probe) {
+		return results
+	}
+	for i, res := range markFallbacks(AllowMany(ctx, f.local, rest)) {
+		results[restIdx[i]] = res
+	}
+	return results
+}
+
+// Peek reports the state of the limiter that would decide the next request for key.
+func (f *FallbackLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if ok, probe := f.breaker.try(); ok {
+		res, err := f.primary.Peek(ctx, key)
+		if !f.breaker.record(ctx, err, probe) {
+			return res, err
+		}
+	}
+	return markFallback(f.local.Peek(ctx, key))
+}
+
+// Refund gives n units back to key in the limiter currently deciding requests.
+func (f *FallbackLimiter) Refund(ctx context.Context, key string, n int) error {
+	if f.breaker.isOpen() {
+		return f.local.Refund(ctx, key, n)
+	}
+	err := f.primary.Refund(ctx, key, n)
+	f.breaker.record(ctx, err, false)
+	return err
+}
+
+// Reset forgets the state of key in both limiters.
+func (f *FallbackLimiter) Reset(ctx context.Context, key string) error {
+	localErr := f.local.Reset(ctx, key)
+	if f.breaker.isOpen() {
+		return localErr
+	}
+	err := f.primary.Reset(ctx, key)
+	f.breaker.record(ctx, err, false)
+	return errors.Join(err, localErr)
+}
+
+// Close closes both limiters.
+func (f *FallbackLimiter) Close() error {
+	return errors.Join(f.primary.Close(), f.local.Close())
+}
+
+// Acquire takes a slot from the primary limiter while the breaker is closed, and from the local
+// limiter otherwise.
+func (f *fallbackLeaseLimiter) Acquire(ctx context.Context, key string) (core.Lease, core.Result, error) {
+	if ok, probe := f.breaker.try(); ok {
+		lease, res, err := f.primaryLeaser.Acquire(ctx, key)
+		if !f.breaker.record(ctx, err, probe) {
+			return lease, res, err
+		}
+	}
+	lease, res, err := f.localLeaser.Acquire(ctx, key)
+	if lease.ID != "" {
+		lease.ID = fallbackLeasePrefix + lease.ID
+	}
+	res, err = markFallback(res, err)
+	return lease, res, err
+}
+
+// Release gives a slot back to the limiter it was taken from.
+func (f *fallbackLeaseLimiter) Release(ctx context.Context, lease core.Lease) error {
+	if id, ok := strings.CutPrefix(lease.ID, fallbackLeasePrefix); ok {
+		lease.ID = id
+		return f.localLeaser.Release(ctx, lease)
+	}
+	return f.primaryLeaser.Release(ctx, lease)
+}
+
+func markFallback(res core.Result, err error) (core.Result, error) {
+	res.Fallback = true
+	return res, err
+}
+
+func markFallbacks(results []core.BatchResult) []core.BatchResult {
+	for i := range results {
+		results[i].Fallback = true
+	}
+	return results
+}
+
+// circuitBreaker tracks consecutive failures of a backend. It opens at threshold failures and,
+// once cooldown has passed, lets a single probe through; the probe's outcome closes it or keeps
+// it open for another cooldown.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	clock     core.Clock
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// try reports whether a call may go to the backend, either because the breaker is closed or
+// because the call is the probe, and whether it is the probe.
+func (b *circuitBreaker) try() (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if b.probing || b.clock.Now().Before(b.openUntil) {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// isOpen reports whether calls currently bypass the backend.
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
+
+// record notes the outcome of a backend call and reports whether it was a backend failure.
+// Errors caused by the caller, such as an invalid cost or a canceled context, do not count.
+// Only the probe, as reported by try, lets the next probe through.
+func (b *circuitBreaker) record(ctx context.Context, err error, probe bool) bool {
+	failed := backendFailure(ctx, err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case failed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = b.clock.Now().Add(b.cooldown)
+		}
+	case err == nil:
+		b.failures = 0
+	}
+	return failed
+}
+
+// backendFailure reports whether err is a failure of the backend rather than of the caller.
+func backendFailure(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil &&
+		!errors.Is(err, core.ErrInvalidCost) && !errors.Is(err, core.ErrCostExceedsLimit)
+}
@@ -0,0 +1,229 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+var errStoreDown = errors.New("store down")
+
+// flakyStore fails every call while down is set.
+type flakyStore struct {
+	storage.Storage
+	down atomic.Bool
+}
+
+func (s *flakyStore) IncrBy(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error) {
+	if s.down.Load() {
+		return 0, errStoreDown
+	}
+	return s.Storage.IncrBy(ctx, key, delta, ttl)
+}
+
+func (s *flakyStore) Get(ctx context.Context, key string) (float64, error) {
+	if s.down.Load() {
+		return 0, errStoreDown
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func newTestFallback(t *testing.T, clock *clocktest.ManualClock) (core.Limiter, *flakyStore, *countingLimiter) {
+	t.Helper()
+	store := &flakyStore{Storage: inmem.NewInMemoryStoreWithClock(clock)}
+	cfg := core.Config{
+		Limit: 10, Window: time.Hour, Metrics: &core.NoopMetrics{}, Clock: clock,
+		Fallback: core.FallbackPolicy{Instances: 5, ErrorThreshold: 2, Cooldown: time.Second},
+	}
+	primary := &countingLimiter{Limiter: NewFixedWindowLimiter(cfg, store)}
+	localCfg := cfg
+	localCfg.Limit = 2
+	local := NewFixedWindowLimiter(localCfg, inmem.NewInMemoryStoreWithClock(clock))
+	limiter := NewFallbackLimiter(primary, local, cfg)
+	t.Cleanup(func() { limiter.Close() })
+	return limiter, store, primary
+}
+
+// TestFallback_TripsAndServesLocally checks that backend errors are answered by the local
+// limiter and that the breaker stops calling the backend once it trips.
+func TestFallback_TripsAndServesLocally(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, store, primary := newTestFallback(t, clock)
+	ctx := context.Background()
+
+	if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed || res.Fallback {
+		t.Fatalf("expected a primary admission, got %+v, err %v", res, err)
+	}
+
+	store.down.Store(true)
+	for i := 0; i < 2; i++ {
+		res, err := limiter.Allow(ctx, "k")
+		if err != nil || !res.Allowed || !res.Fallback || res.Limit != 2 {
+			t.Fatalf("req %d: expected a local admission, got %+v, err %v", i+1, res, err)
+		}
+	}
+	calls := primary.calls
+	res, err := limiter.Allow(ctx, "k")
+	if err != nil || res.Allowed || !res.Fallback {
+		t.Fatalf("expected the local limit to deny, got %+v, err %v", res, err)
+	}
+	if primary.calls != calls {
+		t.Fatal("expected the open breaker to skip the primary limiter")
+	}
+}
+
+// TestFallback_ProbesForRecovery checks that after the cooldown one request probes the backend,
+// keeping the breaker open on failure and closing it on success.
+func TestFallback_ProbesForRecovery(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, store, primary := newTestFallback(t, clock)
+	ctx := context.Background()
+
+	store.down.Store(true)
+	limiter.Allow(ctx, "k")
+	limiter.Allow(ctx, "k")
+
+	clock.Advance(2 * time.Second)
+	calls := primary.calls
+	if res, _ := limiter.Allow(ctx, "k"); !res.Fallback || primary.calls != calls+1 {
+		t.Fatalf("expected a failed probe answered locally, got %+v after %d calls", res, primary.calls-calls)
+	}
+	if limiter.Allow(ctx, "k"); primary.calls != calls+1 {
+		t.Fatal("expected the failed probe to keep the breaker open")
+	}
+
+	store.down.Store(false)
+	clock.Advance(2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed || res.Fallback {
+			t.Fatalf("req %d: expected a primary admission after recovery, got %+v, err %v", i+1, res, err)
+		}
+	}
+}
+
+// TestCircuitBreaker_OnlyProbeEndsProbing checks that a call started before the breaker opened
+// does not let a second probe through when it finishes during the probe.
+func TestCircuitBreaker_OnlyProbeEndsProbing(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &circuitBreaker{threshold: 1, cooldown: time.Second, clock: clock}
+	ctx := context.Background()
+
+	// The straggler starts while the breaker is closed; another call then trips it.
+	_, straggler := b.try()
+	_, tripping := b.try()
+	b.record(ctx, errStoreDown, tripping)
+
+	clock.Advance(2 * time.Second)
+	if ok, probe := b.try(); !ok || !probe {
+		t.Fatalf("expected a probe after the cooldown, got ok=%v probe=%v", ok, probe)
+	}
+	b.record(ctx, core.ErrInvalidCost, straggler)
+	if ok, _ := b.try(); ok {
+		t.Fatal("expected a single probe while the first one is running")
+	}
+}
+
+// TestFallback_CallerErrorsDoNotTrip checks that invalid costs are returned as they are.
+func TestFallback_CallerErrorsDoNotTrip(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, _, _ := newTestFallback(t, clock)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.AllowN(ctx, "k", 0); !errors.Is(err, core.ErrInvalidCost) {
+			t.Fatalf("expected ErrInvalidCost, got %v", err)
+		}
+	}
+	if res, err := limiter.Allow(ctx, "k"); err != nil || res.Fallback {
+		t.Fatalf("expected the breaker to stay closed, got %+v, err %v", res, err)
+	}
+}
+
+// downLeaser is a lease limiter whose backend is unreachable.
+type downLeaser struct {
+	core.LeaseLimiter
+	releases int
+}
+
+func (d *downLeaser) Acquire(context.Context, string) (core.Lease, core.Result, error) {
+	return core.Lease{}, core.Result{}, errStoreDown
+}
+
+func (d *downLeaser) Release(context.Context, core.Lease) error {
+	d.releases++
+	return errStoreDown
+}
+
+func (d *downLeaser) Close() error { return nil }
+
+// TestFallback_AllowMany checks that a batch counts as one backend call for the breaker and that
+// its requests are decided locally once the backend fails.
+func TestFallback_AllowMany(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, store, primary := newTestFallback(t, clock)
+	batcher, ok := limiter.(core.BatchLimiter)
+	if !ok {
+		t.Fatal("expected the fallback limiter to be a core.BatchLimiter")
+	}
+	ctx := context.Background()
+	reqs := []core.BatchRequest{{Key: "k", Cost: 1}, {Key: "k", Cost: 1}, {Key: "k", Cost: 1}}
+
+	for _, res := range batcher.AllowMany(ctx, reqs[:2]) {
+		if res.Err != nil || !res.Allowed || res.Fallback {
+			t.Fatalf("expected a primary admission, got %+v", res)
+		}
+	}
+
+	store.down.Store(true)
+	results := batcher.AllowMany(ctx, reqs)
+	if !results[0].Allowed || !results[1].Allowed || results[2].Allowed {
+		t.Fatalf("expected the local limit of 2 to apply, got %+v", results)
+	}
+	for _, res := range results {
+		if res.Err != nil || !res.Fallback {
+			t.Fatalf("expected a local decision, got %+v", res)
+		}
+	}
+
+	batcher.AllowMany(ctx, reqs[:1])
+	calls := primary.calls
+	if res := batcher.AllowMany(ctx, reqs[:1])[0]; res.Allowed || !res.Fallback {
+		t.Fatalf("expected the local limit to deny, got %+v", res)
+	}
+	if primary.calls != calls {
+		t.Fatal("expected the breaker to open after two failed batches")
+	}
+}
+
+// TestFallback_LeasesReleaseWhereAcquired checks that leases taken from the local limiter
+// during an outage are released there.
+func TestFallback_LeasesReleaseWhereAcquired(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cfg := core.Config{
+		Limit: 1, Window: time.Minute, Metrics: &core.NoopMetrics{}, Clock: clock,
+		Fallback: core.FallbackPolicy{Instances: 1},
+	}
+	primary := &downLeaser{}
+	limiter := NewFallbackLimiter(primary, NewConcurrencyLimiter(cfg, inmem.NewInMemoryStoreWithClock(clock)), cfg).(core.LeaseLimiter)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	lease, res, err := limiter.Acquire(ctx, "k")
+	if err != nil || !res.Allowed || !res.Fallback || !strings.HasPrefix(lease.ID, fallbackLeasePrefix) {
+		t.Fatalf("expected a local lease, got %+v, %+v, err %v", lease, res, err)
+	}
+	if err := limiter.Release(ctx, lease); err != nil || primary.releases != 0 {
+		t.Fatalf("expected a local release, got err %v and %d primary releases", err, primary.releases)
+	}
+	if _, res, _ := limiter.Acquire(ctx, "k"); !res.Allowed {
+		t.Fatal("expected the released slot to be available again")
+	}
+}
@@ -4,6 +4,8 @@ package algorithms
 import (
 	"context"
 	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
@@ -18,40 +20,118 @@ type FixedWindowLimiter struct {
 	store    storage.Storage
 	prefix   string
 	metrics  core.MetricsCollector
+	clock    core.Clock
 	failOpen bool
+	windowAt func(now time.Time) counterWindow
+	mu       sync.Mutex // Serializes the check and increment on stores without scripts
+}
+
+// counterWindow identifies the counter that applies at a given moment.
+type counterWindow struct {
+	id  int64         // Suffix of the storage key
+	end time.Time     // When the counter stops applying
+	ttl time.Duration // Lifetime of the storage key
 }
 
 // NewFixedWindowLimiter creates a new FixedWindowLimiter.
 func NewFixedWindowLimiter(cfg core.Config, store storage.Storage) core.Limiter {
-	return &FixedWindowLimiter{
+	f := &FixedWindowLimiter{
 		limit:    cfg.Limit,
 		window:   cfg.Window,
 		store:    store,
 		prefix:   "gorl:fw",
 		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
 		failOpen: cfg.FailOpen,
 	}
+	f.windowAt = f.epochWindow
+	return f
+}
+
+// epochWindow buckets time into consecutive windows aligned to the Unix epoch.
+func (f *FixedWindowLimiter) epochWindow(now time.Time) counterWindow {
+	bucket := now.UnixNano() / int64(f.window)
+	return counterWindow{
+		id:  bucket,
+		end: time.Unix(0, (bucket+1)*int64(f.window)),
+		ttl: f.window,
+	}
+}
+
+func (f *FixedWindowLimiter) storageKey(key string, w counterWindow) string {
+	return fmt.Sprintf("%s:%s:%d", f.prefix, key, w.id)
+}
+
+func (f *FixedWindowLimiter) setLimit(limit int) {
+	f.limit = limit
 }
 
 // Allow checks if a request with the given key is allowed under the fixed window policy.
 func (f *FixedWindowLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+// AllowN checks if a request costing n units is allowed under the fixed window policy.
+func (f *FixedWindowLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := f.store.(redisScriptRunner)
+	return f.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (f *FixedWindowLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, f.store, reqs, f.allow)
+}
+
+func (f *FixedWindowLimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	if err := validateCost(n, f.limit); err != nil {
+		return core.Result{Limit: f.limit}, err
+	}
+
 	start := time.Now()
-	bucket := start.UnixNano() / int64(f.window)
-	storageKey := fmt.Sprintf("%s:%s:%d", f.prefix, key, bucket)
+	now := f.clock.Now()
+	w := f.windowAt(now)
+	storageKey := f.storageKey(key, w)
 
-	count, err := f.store.Incr(ctx, storageKey, f.window)
+	if runner != nil {
+		return f.allowRedis(ctx, start, now, runner, storageKey, w, n)
+	}
+
+	return f.allowGeneric(ctx, start, now, storageKey, w, n)
+}
+
+func (f *FixedWindowLimiter) allowGeneric(ctx context.Context, start, now time.Time, storageKey string, w counterWindow, n int) (core.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Denied requests never touch the counter, so they cannot crowd out concurrent requests.
+	count, err := f.store.Get(ctx, storageKey)
 	if res, retErr, done := failOpenHandler(start, err, f.failOpen, f.metrics, f.limit); done {
 		return res, retErr
 	}
 
-	nextBucketStart := time.Unix(0, (bucket+1)*int64(f.window))
-	reset := clampDuration(time.Until(nextBucketStart))
+	allowed := count+float64(n) <= float64(f.limit)
+	if allowed {
+		count, err = f.store.IncrBy(ctx, storageKey, float64(n), w.ttl)
+		if res, retErr, done := failOpenHandler(start, err, f.failOpen, f.metrics, f.limit); done {
+			return res, retErr
+		}
+		if count > float64(f.limit) {
+			// Another limiter on the same store got there first; hand the cost back without
+			// driving the counter below zero if the window has ended meanwhile.
+			allowed = false
+			count, err = f.giveBack(ctx, storageKey, w, n)
+			if res, retErr, done := failOpenHandler(start, err, f.failOpen, f.metrics, f.limit); done {
+				return res, retErr
+			}
+		}
+	}
+
+	reset := clampDuration(w.end.Sub(now))
 	remaining := f.limit - int(count)
 	if remaining < 0 {
 		remaining = 0
 	}
 
-	allowed := count <= float64(f.limit)
 	f.metrics.ObserveLatency(time.Since(start))
 
 	res := core.Result{
@@ -70,6 +150,103 @@ func (f *FixedWindowLimiter) Allow(ctx context.Context, key string) (core.Result
 	return res, nil
 }
 
+func (f *FixedWindowLimiter) allowRedis(ctx context.Context, start, now time.Time, runner redisScriptRunner, storageKey string, w counterWindow, n int) (core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptFixedWindow,
+		[]string{storageKey},
+		int64(f.limit),
+		now.UnixMicro(),
+		w.end.UnixMicro(),
+		durationToMilliseconds(w.ttl),
+		int64(n),
+	)
+	if res, retErr, done := failOpenHandler(start, err, f.failOpen, f.metrics, f.limit); done {
+		return res, retErr
+	}
+
+	res, err := buildRedisScriptResult(f.limit, values)
+	if res2, retErr, done := failOpenHandler(start, err, f.failOpen, f.metrics, f.limit); done {
+		return res2, retErr
+	}
+
+	f.metrics.ObserveLatency(time.Since(start))
+	if res.Allowed {
+		f.metrics.IncAllow()
+	} else {
+		f.metrics.IncDeny()
+	}
+
+	return res, nil
+}
+
+// Peek reports the current window state for key without counting a request.
+// Allowed tells whether a single-unit request would currently succeed.
+// The state is a single counter, so a plain Get is already read-only on every backend.
+func (f *FixedWindowLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	now := f.clock.Now()
+	w := f.windowAt(now)
+
+	count, err := f.store.Get(ctx, f.storageKey(key, w))
+	if err != nil {
+		return core.Result{Limit: f.limit}, err
+	}
+
+	reset := clampDuration(w.end.Sub(now))
+	remaining := f.limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	res := core.Result{
+		Allowed:   remaining > 0,
+		Limit:     f.limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = reset
+	}
+	return res, nil
+}
+
+// Refund gives back n units consumed from the current window for key.
+// The counter never drops below zero; units consumed in earlier windows have already expired.
+func (f *FixedWindowLimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	w := f.windowAt(f.clock.Now())
+	storageKey := f.storageKey(key, w)
+
+	if runner, ok := f.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(ctx, redisScriptFixedWindowRefund, []string{storageKey}, int64(n))
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.giveBack(ctx, storageKey, w, n)
+	return err
+}
+
+// giveBack takes up to n units off the counter at storageKey and returns what is left. A counter
+// that is already gone stays gone.
+func (f *FixedWindowLimiter) giveBack(ctx context.Context, storageKey string, w counterWindow, n int) (float64, error) {
+	count, err := f.store.Get(ctx, storageKey)
+	if err != nil || count <= 0 {
+		return 0, err
+	}
+	return f.store.IncrBy(ctx, storageKey, -math.Min(float64(n), count), w.ttl)
+}
+
+// Reset clears the current window's counter for key.
+// Counters from earlier windows are never read again and simply expire.
+func (f *FixedWindowLimiter) Reset(ctx context.Context, key string) error {
+	return f.store.Delete(ctx, f.storageKey(key, f.windowAt(f.clock.Now())))
+}
+
 // Close releases resources held by the limiter.
 func (f *FixedWindowLimiter) Close() error {
 	return f.store.Close()
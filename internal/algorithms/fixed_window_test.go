@@ -8,7 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
 	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
 )
 
@@ -77,6 +79,33 @@ func TestFixedWindow_WindowReset(t *testing.T) {
 	}
 }
 
+// TestFixedWindow_ManualClock ensures the window follows the configured clock rather than wall time.
+func TestFixedWindow_ManualClock(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	limiter := NewFixedWindowLimiter(core.Config{
+		Limit: 1, Window: time.Hour, Metrics: &core.NoopMetrics{}, Clock: clock,
+	}, store)
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "key")
+	clock.Advance(59 * time.Minute)
+	res, _ := limiter.Allow(ctx, "key")
+	if res.Allowed {
+		t.Fatal("should be denied within window")
+	}
+	if res.RetryAfter != time.Minute {
+		t.Fatalf("expected retry after the remaining minute, got %v", res.RetryAfter)
+	}
+
+	clock.Advance(time.Minute)
+	res, _ = limiter.Allow(ctx, "key")
+	if !res.Allowed {
+		t.Fatal("should be allowed once the clock reaches the next window")
+	}
+}
+
 // TestFixedWindow_FailOpen verifies that the limiter allows requests when the storage backend fails
 // and FailOpen is set to true.
 func TestFixedWindow_FailOpen(t *testing.T) {
@@ -202,6 +231,150 @@ func TestFixedWindow_ResultMetadata(t *testing.T) {
 	}
 }
 
+// TestFixedWindow_AllowN verifies that weighted requests consume their full cost and that a
+// denied request leaves the remaining capacity untouched.
+func TestFixedWindow_AllowN(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewFixedWindowLimiter(core.Config{
+		Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.AllowN(ctx, "bulk", 3)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 3 to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 2 {
+		t.Fatalf("expected remaining=2, got %d", res.Remaining)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected cost 3 to be denied with 2 units left")
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 2)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 2 to fit the remaining capacity, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected remaining=0, got %d", res.Remaining)
+	}
+}
+
+// racingStore lets another instance spend the window between a limiter's read and its increment,
+// and optionally expires the counter right after.
+type racingStore struct {
+	storage.Storage
+	other  float64
+	expire bool
+}
+
+func (s *racingStore) IncrBy(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error) {
+	if s.other > 0 {
+		s.Storage.IncrBy(ctx, key, s.other, ttl)
+		s.other = 0
+	}
+	count, err := s.Storage.IncrBy(ctx, key, delta, ttl)
+	if s.expire {
+		s.expire = false
+		s.Storage.Delete(ctx, key)
+	}
+	return count, err
+}
+
+// TestFixedWindow_DeniedRequestsLeaveCounter verifies that a denied request never changes the
+// counter: one rejected up front is not counted, and one losing a race to another instance hands
+// its cost back without driving an expired counter below zero.
+func TestFixedWindow_DeniedRequestsLeaveCounter(t *testing.T) {
+	store := &racingStore{Storage: inmem.NewInMemoryStore()}
+	defer store.Close()
+	limiter := NewFixedWindowLimiter(core.Config{
+		Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	limiter.AllowN(ctx, "k", 4)
+	if res, _ := limiter.AllowN(ctx, "k", 2); res.Allowed {
+		t.Fatal("expected cost 2 denied with 1 unit left")
+	}
+	if res, _ := limiter.Peek(ctx, "k"); res.Remaining != 1 {
+		t.Fatalf("expected the denied request not counted, got %d remaining", res.Remaining)
+	}
+
+	store.other = 1
+	if res, _ := limiter.Allow(ctx, "k"); res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected the request losing the race denied, got %+v", res)
+	}
+	if res, _ := limiter.Peek(ctx, "k"); res.Remaining != 0 {
+		t.Fatalf("expected the other instance's unit kept, got %d remaining", res.Remaining)
+	}
+
+	limiter.Reset(ctx, "k")
+	limiter.AllowN(ctx, "k", 3)
+	store.other, store.expire = 1, true
+	if res, _ := limiter.AllowN(ctx, "k", 2); res.Allowed {
+		t.Fatal("expected the request losing the race denied")
+	}
+	if res, _ := limiter.Peek(ctx, "k"); res.Remaining != 5 {
+		t.Fatalf("expected the expired counter to stay empty, got %d remaining", res.Remaining)
+	}
+}
+
+// TestFixedWindow_PeekDoesNotConsume verifies that Peek reports the current state without
+// spending capacity or recording metrics.
+func TestFixedWindow_PeekDoesNotConsume(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	m := &mockMetrics{}
+	limiter := NewFixedWindowLimiter(core.Config{
+		Limit: 3, Window: time.Minute, Metrics: m,
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 3 {
+		t.Fatalf("expected fresh key to report full capacity, got %+v", res)
+	}
+
+	limiter.Allow(ctx, "dash")
+	limiter.Allow(ctx, "dash")
+	for i := 0; i < 2; i++ {
+		res, err = limiter.Peek(ctx, "dash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.Allowed || res.Remaining != 1 {
+			t.Fatalf("peek %d: expected remaining=1, got %+v", i+1, res)
+		}
+	}
+
+	limiter.Allow(ctx, "dash")
+	res, err = limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected exhausted state, got %+v", res)
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+	if m.allows != 3 || m.denies != 0 {
+		t.Fatalf("peek should not record decisions, got %d allows and %d denies", m.allows, m.denies)
+	}
+}
+
 // BenchmarkFixedWindow_SingleKey benchmarks the performance of the Fixed Window limiter with a single key.
 func BenchmarkFixedWindow_SingleKey(b *testing.B) {
 	b.ReportAllocs()
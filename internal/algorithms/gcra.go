@@ -0,0 +1,269 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// GCRALimiter implements the generic cell rate algorithm.
+// State is a single key per user holding the theoretical arrival time (TAT): the moment
+// the key would be fully recovered if no further requests arrived.
+type GCRALimiter struct {
+	limit            int
+	window           time.Duration
+	store            storage.Storage
+	prefix           string
+	mu               sync.Mutex
+	metrics          core.MetricsCollector
+	clock            core.Clock
+	emissionInterval int64
+	failOpen         bool
+}
+
+// NewGCRALimiter constructs a new GCRALimiter.
+// Requests are spaced window/limit apart, with bursts of up to limit requests.
+func NewGCRALimiter(cfg core.Config, store storage.Storage) core.Limiter {
+	g := &GCRALimiter{
+		window:   cfg.Window,
+		store:    store,
+		prefix:   "gorl:gcra",
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+		failOpen: cfg.FailOpen,
+	}
+	g.setLimit(cfg.Limit)
+	return g
+}
+
+// setLimit spaces requests window/limit apart.
+func (g *GCRALimiter) setLimit(limit int) {
+	interval := g.window.Nanoseconds() / int64(limit)
+	if interval <= 0 {
+		interval = 1
+	}
+	g.limit = limit
+	g.emissionInterval = interval
+}
+
+// Allow checks whether a request conforms to the configured rate and records it if so.
+func (g *GCRALimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return g.AllowN(ctx, key, 1)
+}
+
+// AllowN checks whether n units conform to the configured rate and records them if so.
+func (g *GCRALimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := g.store.(redisScriptRunner)
+	return g.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (g *GCRALimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, g.store, reqs, g.allow)
+}
+
+func (g *GCRALimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	if err := validateCost(n, g.limit); err != nil {
+		return core.Result{Limit: g.limit}, err
+	}
+
+	start := time.Now()
+	if runner != nil {
+		return g.allowRedis(ctx, start, runner, key, n)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.allowGeneric(ctx, start, key, n)
+}
+
+func (g *GCRALimiter) allowGeneric(ctx context.Context, start time.Time, key string, n int) (core.Result, error) {
+	now := g.clock.Now().UnixNano()
+	storageKey := g.storageKey(key)
+
+	tatVal, err := g.store.Get(ctx, storageKey)
+	if res, retErr, done := failOpenHandler(start, err, g.failOpen, g.metrics, g.limit); done {
+		return res, retErr
+	}
+	tat := int64(tatVal)
+
+	newTAT, wait := g.schedule(tat, now, n)
+	allowed := wait == 0
+	if allowed {
+		tat = newTAT
+		// The key expires exactly when it has fully recovered.
+		err = g.store.Set(ctx, storageKey, float64(tat), time.Duration(tat-now))
+		if res, retErr, done := failOpenHandler(start, err, g.failOpen, g.metrics, g.limit); done {
+			return res, retErr
+		}
+	}
+
+	g.metrics.ObserveLatency(time.Since(start))
+
+	remaining, reset := g.state(tat, now)
+	res := core.Result{
+		Allowed:   allowed,
+		Limit:     g.limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+
+	if allowed {
+		g.metrics.IncAllow()
+	} else {
+		g.metrics.IncDeny()
+		res.RetryAfter = wait
+	}
+
+	return res, nil
+}
+
+func (g *GCRALimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptGCRA,
+		[]string{g.storageKey(key)},
+		durationToMicros(time.Duration(g.emissionInterval)),
+		int64(g.limit),
+		g.clock.Now().UnixMicro(),
+		int64(n),
+	)
+	if res, retErr, done := failOpenHandler(start, err, g.failOpen, g.metrics, g.limit); done {
+		return res, retErr
+	}
+
+	res, err := buildRedisScriptResult(g.limit, values)
+	if res2, retErr, done := failOpenHandler(start, err, g.failOpen, g.metrics, g.limit); done {
+		return res2, retErr
+	}
+
+	g.metrics.ObserveLatency(time.Since(start))
+	if res.Allowed {
+		g.metrics.IncAllow()
+	} else {
+		g.metrics.IncDeny()
+	}
+
+	return res, nil
+}
+
+// Peek reports the state for key without recording a request.
+// Allowed tells whether a single-unit request would currently conform.
+func (g *GCRALimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if runner, ok := g.store.(redisScriptRunner); ok {
+		values, err := runner.EvalScript(
+			ctx,
+			redisScriptGCRAPeek,
+			[]string{g.storageKey(key)},
+			durationToMicros(time.Duration(g.emissionInterval)),
+			int64(g.limit),
+			g.clock.Now().UnixMicro(),
+		)
+		if err != nil {
+			return core.Result{Limit: g.limit}, err
+		}
+		return buildRedisScriptResult(g.limit, values)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now().UnixNano()
+	tatVal, err := g.store.Get(ctx, g.storageKey(key))
+	if err != nil {
+		return core.Result{Limit: g.limit}, err
+	}
+	tat := int64(tatVal)
+
+	_, wait := g.schedule(tat, now, 1)
+	remaining, reset := g.state(tat, now)
+	res := core.Result{
+		Allowed:   wait == 0,
+		Limit:     g.limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = wait
+	}
+	return res, nil
+}
+
+// Refund moves the TAT for key back by n emission intervals, never earlier than now.
+func (g *GCRALimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	if runner, ok := g.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(
+			ctx,
+			redisScriptGCRARefund,
+			[]string{g.storageKey(key)},
+			durationToMicros(time.Duration(g.emissionInterval)),
+			g.clock.Now().UnixMicro(),
+			int64(n),
+		)
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now().UnixNano()
+	storageKey := g.storageKey(key)
+	tatVal, err := g.store.Get(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+
+	tat := int64(tatVal) - g.emissionInterval*int64(n)
+	if tat <= now {
+		return g.store.Delete(ctx, storageKey)
+	}
+	return g.store.Set(ctx, storageKey, float64(tat), time.Duration(tat-now))
+}
+
+// Reset deletes the TAT stored for key.
+func (g *GCRALimiter) Reset(ctx context.Context, key string) error {
+	return g.store.Delete(ctx, g.storageKey(key))
+}
+
+// Close releases resources held by the limiter.
+func (g *GCRALimiter) Close() error {
+	return g.store.Close()
+}
+
+// schedule returns the TAT after admitting cost units and how long until they would conform.
+// A TAT in the past (or a missing one) means the key is fully recovered.
+func (g *GCRALimiter) schedule(tat, now int64, cost int) (int64, time.Duration) {
+	if tat < now {
+		tat = now
+	}
+	newTAT := tat + g.emissionInterval*int64(cost)
+	allowAt := newTAT - g.period()
+	return newTAT, clampDuration(time.Duration(allowAt - now))
+}
+
+// state derives the remaining capacity and time until full recovery from a TAT.
+func (g *GCRALimiter) state(tat, now int64) (int, time.Duration) {
+	if tat < now {
+		tat = now
+	}
+	backlog := tat - now
+	return int((g.period() - backlog) / g.emissionInterval), time.Duration(backlog)
+}
+
+// period is the burst capacity expressed as time: limit emission intervals.
+func (g *GCRALimiter) period() int64 {
+	return g.emissionInterval * int64(g.limit)
+}
+
+func (g *GCRALimiter) storageKey(key string) string {
+	return fmt.Sprintf("%s:%s", g.prefix, key)
+}
@@ -0,0 +1,127 @@
+package algorithms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// TestGCRA_Basic verifies that a key may burst up to the limit and is then denied.
+func TestGCRA_Basic(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewGCRALimiter(core.Config{
+		Limit: 3, Window: time.Second, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		res, err := limiter.Allow(ctx, "user-1")
+		if err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed, got %v, err %v", i+1, res.Allowed, err)
+		}
+		if res.Remaining != 2-i {
+			t.Fatalf("req %d: expected remaining=%d, got %d", i+1, 2-i, res.Remaining)
+		}
+	}
+	res, err := limiter.Allow(ctx, "user-1")
+	if res.Allowed || err != nil {
+		t.Fatalf("expected denied, got %v, err %v", res.Allowed, err)
+	}
+}
+
+// TestGCRA_ResultMetadata checks Reset and the exact RetryAfter of a denied request.
+func TestGCRA_ResultMetadata(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	// Emission interval = 1s / 10 = 100ms.
+	limiter := NewGCRALimiter(core.Config{
+		Limit: 10, Window: time.Second, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	first, _ := limiter.Allow(ctx, "k")
+	if first.Reset <= 0 || first.Reset > 100*time.Millisecond {
+		t.Fatalf("expected reset of one emission interval, got %v", first.Reset)
+	}
+	limiter.AllowN(ctx, "k", 9)
+
+	denied, err := limiter.Allow(ctx, "k")
+	if err != nil || denied.Allowed {
+		t.Fatalf("expected denied, got %v, err %v", denied.Allowed, err)
+	}
+	if denied.Remaining != 0 {
+		t.Fatalf("expected remaining=0, got %d", denied.Remaining)
+	}
+	if denied.RetryAfter <= 90*time.Millisecond || denied.RetryAfter > 100*time.Millisecond {
+		t.Fatalf("expected retry_after close to one emission interval, got %v", denied.RetryAfter)
+	}
+	if denied.Reset <= 900*time.Millisecond || denied.Reset > time.Second {
+		t.Fatalf("expected reset close to the full window, got %v", denied.Reset)
+	}
+
+	time.Sleep(denied.RetryAfter)
+	if res, _ := limiter.Allow(ctx, "k"); !res.Allowed {
+		t.Fatal("expected request to conform after waiting RetryAfter")
+	}
+}
+
+// TestGCRA_SingleKeyPerUser ensures the limiter keeps one storage key per user.
+func TestGCRA_SingleKeyPerUser(t *testing.T) {
+	store := &setFailAfterNStore{data: make(map[string]float64), failAfter: 1 << 30}
+	limiter := NewGCRALimiter(core.Config{
+		Limit: 5, Window: time.Second, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "a")
+	limiter.Allow(ctx, "a")
+	limiter.Allow(ctx, "b")
+
+	if len(store.data) != 2 {
+		t.Fatalf("expected one key per user, got %v", store.data)
+	}
+}
+
+// TestGCRA_DeniedAllowNLeavesStateUntouched ensures a denied weighted request consumes nothing.
+func TestGCRA_DeniedAllowNLeavesStateUntouched(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewGCRALimiter(core.Config{
+		Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	limiter.AllowN(ctx, "k", 3)
+	if res, _ := limiter.AllowN(ctx, "k", 3); res.Allowed {
+		t.Fatal("expected weighted request to be denied")
+	}
+	res, _ := limiter.AllowN(ctx, "k", 2)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected remaining capacity to be intact, got %+v", res)
+	}
+}
+
+// TestGCRA_FailOpen verifies that storage errors are handled according to FailOpen.
+func TestGCRA_FailOpen(t *testing.T) {
+	ctx := context.Background()
+
+	open := NewGCRALimiter(core.Config{
+		Limit: 5, Window: time.Second, Metrics: &core.NoopMetrics{}, FailOpen: true,
+	}, &failingStore{})
+	res, err := open.Allow(ctx, "k")
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected fail-open allow, got %v, err %v", res.Allowed, err)
+	}
+
+	closed := NewGCRALimiter(core.Config{
+		Limit: 5, Window: time.Second, Metrics: &core.NoopMetrics{}, FailOpen: false,
+	}, &failingStore{})
+	res, err = closed.Allow(ctx, "k")
+	if err == nil || res.Allowed {
+		t.Fatalf("expected fail-closed error, got %v, err %v", res.Allowed, err)
+	}
+}
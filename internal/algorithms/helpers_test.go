@@ -25,12 +25,18 @@ type failingStore struct{}
 func (s *failingStore) Incr(_ context.Context, _ string, _ time.Duration) (float64, error) {
 	return 0, fmt.Errorf("store unavailable")
 }
+func (s *failingStore) IncrBy(_ context.Context, _ string, _ float64, _ time.Duration) (float64, error) {
+	return 0, fmt.Errorf("store unavailable")
+}
 func (s *failingStore) Get(_ context.Context, _ string) (float64, error) {
 	return 0, fmt.Errorf("store unavailable")
 }
 func (s *failingStore) Set(_ context.Context, _ string, _ float64, _ time.Duration) error {
 	return fmt.Errorf("store unavailable")
 }
+func (s *failingStore) Delete(_ context.Context, _ ...string) error {
+	return fmt.Errorf("store unavailable")
+}
 func (s *failingStore) Close() error { return nil }
 
 // Ensure failingStore implements storage.Storage.
@@ -49,12 +55,22 @@ func (s *failOnSetStore) Incr(_ context.Context, key string, _ time.Duration) (f
 	s.data[key]++
 	return s.data[key], nil
 }
+func (s *failOnSetStore) IncrBy(_ context.Context, key string, delta float64, _ time.Duration) (float64, error) {
+	s.data[key] += delta
+	return s.data[key], nil
+}
 func (s *failOnSetStore) Get(_ context.Context, key string) (float64, error) {
 	return s.data[key], nil
 }
 func (s *failOnSetStore) Set(_ context.Context, _ string, _ float64, _ time.Duration) error {
 	return fmt.Errorf("set failed")
 }
+func (s *failOnSetStore) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
 func (s *failOnSetStore) Close() error { return nil }
 
 var _ storage.Storage = (*failOnSetStore)(nil)
@@ -70,6 +86,10 @@ func (s *setFailAfterNStore) Incr(_ context.Context, key string, _ time.Duration
 	s.data[key]++
 	return s.data[key], nil
 }
+func (s *setFailAfterNStore) IncrBy(_ context.Context, key string, delta float64, _ time.Duration) (float64, error) {
+	s.data[key] += delta
+	return s.data[key], nil
+}
 func (s *setFailAfterNStore) Get(_ context.Context, key string) (float64, error) {
 	return s.data[key], nil
 }
@@ -81,11 +101,17 @@ func (s *setFailAfterNStore) Set(_ context.Context, key string, val float64, _ t
 	s.data[key] = val
 	return nil
 }
+func (s *setFailAfterNStore) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
 func (s *setFailAfterNStore) Close() error { return nil }
 
 var _ storage.Storage = (*setFailAfterNStore)(nil)
 
-// incrFailStore fails on Incr but succeeds on Get/Set.
+// incrFailStore fails on Incr and IncrBy but succeeds on Get/Set.
 type incrFailStore struct {
 	data map[string]float64
 }
@@ -93,6 +119,9 @@ type incrFailStore struct {
 func (s *incrFailStore) Incr(_ context.Context, _ string, _ time.Duration) (float64, error) {
 	return 0, fmt.Errorf("incr failed")
 }
+func (s *incrFailStore) IncrBy(_ context.Context, _ string, _ float64, _ time.Duration) (float64, error) {
+	return 0, fmt.Errorf("incr failed")
+}
 func (s *incrFailStore) Get(_ context.Context, key string) (float64, error) {
 	return s.data[key], nil
 }
@@ -100,6 +129,12 @@ func (s *incrFailStore) Set(_ context.Context, key string, val float64, _ time.D
 	s.data[key] = val
 	return nil
 }
+func (s *incrFailStore) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
 func (s *incrFailStore) Close() error { return nil }
 
 var _ storage.Storage = (*incrFailStore)(nil)
@@ -115,6 +150,10 @@ func (s *getFailAfterNStore) Incr(_ context.Context, key string, _ time.Duration
 	s.data[key]++
 	return s.data[key], nil
 }
+func (s *getFailAfterNStore) IncrBy(_ context.Context, key string, delta float64, _ time.Duration) (float64, error) {
+	s.data[key] += delta
+	return s.data[key], nil
+}
 func (s *getFailAfterNStore) Get(_ context.Context, key string) (float64, error) {
 	s.getCalls++
 	if s.getCalls > s.failAfter {
@@ -126,6 +165,12 @@ func (s *getFailAfterNStore) Set(_ context.Context, key string, val float64, _ t
 	s.data[key] = val
 	return nil
 }
+func (s *getFailAfterNStore) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
 func (s *getFailAfterNStore) Close() error { return nil }
 
 var _ storage.Storage = (*getFailAfterNStore)(nil)
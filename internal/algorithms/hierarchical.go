@@ -0,0 +1,128 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// HierarchicalLimiter checks a request against nested levels, such as global, tenant and user.
+// It is a CompositeLimiter whose members are keyed per level from one descriptor, so a request
+// is charged at every level or none, atomically on Redis.
+type HierarchicalLimiter struct {
+	levels    []core.HierarchyLevel
+	composite *CompositeLimiter
+}
+
+// NewHierarchicalLimiter builds a hierarchical limiter from one member limiter per level.
+// Members must share store and follow the same rules as in NewCompositeLimiter.
+func NewHierarchicalLimiter(cfg core.Config, store storage.Storage, levels []core.HierarchyLevel, members []core.Limiter) (core.HierarchicalLimiter, error) {
+	if len(levels) != len(members) {
+		return nil, fmt.Errorf("%w: %d levels but %d limiters", core.ErrConfigInvalid, len(levels), len(members))
+	}
+	composite, err := NewCompositeLimiter(cfg, store, members)
+	if err != nil {
+		return nil, err
+	}
+	return &HierarchicalLimiter{levels: levels, composite: composite.(*CompositeLimiter)}, nil
+}
+
+// Allow checks a single request described by d against every level.
+func (h *HierarchicalLimiter) Allow(ctx context.Context, d core.Descriptor) (core.HierarchicalResult, error) {
+	return h.AllowN(ctx, d, 1)
+}
+
+// AllowN charges n units at every level if all of them allow it, and at none otherwise.
+// The Level of a denial names the level that rejected the request.
+func (h *HierarchicalLimiter) AllowN(ctx context.Context, d core.Descriptor, n int) (core.HierarchicalResult, error) {
+	keys, err := h.levelKeys(d)
+	if err != nil {
+		return core.HierarchicalResult{Result: core.Result{Limit: h.composite.minLimit}}, err
+	}
+	res, index, err := h.composite.allowKeys(ctx, keys, n)
+	return h.result(res, index), err
+}
+
+// Peek reports the most restrictive level for d without consuming capacity.
+func (h *HierarchicalLimiter) Peek(ctx context.Context, d core.Descriptor) (core.HierarchicalResult, error) {
+	keys, err := h.levelKeys(d)
+	if err != nil {
+		return core.HierarchicalResult{Result: core.Result{Limit: h.composite.minLimit}}, err
+	}
+	res, index, err := h.composite.peekKeys(ctx, keys)
+	return h.result(res, index), err
+}
+
+// Refund gives n units back at every level for d.
+func (h *HierarchicalLimiter) Refund(ctx context.Context, d core.Descriptor, n int) error {
+	keys, err := h.levelKeys(d)
+	if err != nil {
+		return err
+	}
+	return h.composite.refundKeys(ctx, keys, n)
+}
+
+// Reset forgets the state stored at the named level for d. Other levels are left untouched,
+// so resetting a user does not reset its tenant or the global counter.
+func (h *HierarchicalLimiter) Reset(ctx context.Context, level string, d core.Descriptor) error {
+	for i, l := range h.levels {
+		if l.Name != level {
+			continue
+		}
+		key, err := h.levelKey(i, d)
+		if err != nil {
+			return err
+		}
+		return h.composite.members[i].Reset(ctx, key)
+	}
+	return fmt.Errorf("unknown level %q", level)
+}
+
+// Close releases resources held by the limiter.
+func (h *HierarchicalLimiter) Close() error {
+	return h.composite.Close()
+}
+
+func (h *HierarchicalLimiter) result(res core.Result, index int) core.HierarchicalResult {
+	out := core.HierarchicalResult{Result: res}
+	if index >= 0 {
+		out.Level = h.levels[index].Name
+	}
+	return out
+}
+
+func (h *HierarchicalLimiter) levelKeys(d core.Descriptor) ([]string, error) {
+	keys := make([]string, len(h.levels))
+	for i := range h.levels {
+		key, err := h.levelKey(i, d)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// levelKey builds the storage key of level i from the level name and the descriptor values of
+// that level and every outer one, so a user is counted separately in each tenant.
+// Each part is length-prefixed to keep keys unambiguous whatever the values contain, and the
+// whole key is wrapped in a hash tag, as composite members expect.
+func (h *HierarchicalLimiter) levelKey(i int, d core.Descriptor) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d:%s", len(h.levels[i].Name), h.levels[i].Name)
+	for _, level := range h.levels[:i+1] {
+		if level.Field == "" {
+			continue
+		}
+		value, ok := d[level.Field]
+		if !ok || value == "" {
+			return "", fmt.Errorf("%w: missing %q", core.ErrIncompleteDescriptor, level.Field)
+		}
+		fmt.Fprintf(&b, ":%d:%s", len(value), value)
+	}
+	return hashTag(b.String()), nil
+}
@@ -0,0 +1,135 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+func newTestHierarchy(t *testing.T, global, tenant, user int) core.HierarchicalLimiter {
+	t.Helper()
+	store := inmem.NewInMemoryStore()
+	levels := []core.HierarchyLevel{
+		{Name: "global", Policy: core.ResourcePolicy{Limit: global, Window: time.Minute}},
+		{Name: "tenant", Field: "tenant", Policy: core.ResourcePolicy{Limit: tenant, Window: time.Minute}},
+		{Name: "user", Field: "user", Policy: core.ResourcePolicy{Limit: user, Window: time.Minute}},
+	}
+	members := make([]core.Limiter, len(levels))
+	for i, level := range levels {
+		members[i] = NewFixedWindowLimiter(core.Config{
+			Limit: level.Policy.Limit, Window: level.Policy.Window, Metrics: &core.NoopMetrics{},
+		}, store)
+	}
+	limiter, err := NewHierarchicalLimiter(core.Config{Metrics: &core.NoopMetrics{}}, store, levels, members)
+	if err != nil {
+		t.Fatalf("NewHierarchicalLimiter: %v", err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+	return limiter
+}
+
+// TestHierarchical_ReportsRejectingLevel checks that each level caps its subtree and is named on denial.
+func TestHierarchical_ReportsRejectingLevel(t *testing.T) {
+	limiter := newTestHierarchy(t, 5, 3, 2)
+	ctx := context.Background()
+	alice := core.Descriptor{"tenant": "acme", "user": "alice"}
+	bob := core.Descriptor{"tenant": "acme", "user": "bob"}
+	carol := core.Descriptor{"tenant": "globex", "user": "carol"}
+
+	limiter.AllowN(ctx, alice, 2)
+	res, err := limiter.Allow(ctx, alice)
+	if err != nil || res.Allowed || res.Level != "user" {
+		t.Fatalf("expected user-level denial, got allowed=%v level=%q err=%v", res.Allowed, res.Level, err)
+	}
+
+	limiter.Allow(ctx, bob)
+	res, err = limiter.Allow(ctx, bob)
+	if err != nil || res.Allowed || res.Level != "tenant" {
+		t.Fatalf("expected tenant-level denial, got allowed=%v level=%q err=%v", res.Allowed, res.Level, err)
+	}
+
+	limiter.AllowN(ctx, carol, 2)
+	res, err = limiter.Allow(ctx, core.Descriptor{"tenant": "globex", "user": "dave"})
+	if err != nil || res.Allowed || res.Level != "global" {
+		t.Fatalf("expected global denial, got allowed=%v level=%q err=%v", res.Allowed, res.Level, err)
+	}
+	if res.Limit != 5 {
+		t.Fatalf("expected the global limit to be reported, got %d", res.Limit)
+	}
+}
+
+// TestHierarchical_DenialChargesNoLevel ensures a rejection at one level leaves the others untouched.
+func TestHierarchical_DenialChargesNoLevel(t *testing.T) {
+	limiter := newTestHierarchy(t, 10, 10, 1)
+	ctx := context.Background()
+	alice := core.Descriptor{"tenant": "acme", "user": "alice"}
+
+	limiter.Allow(ctx, alice)
+	for i := 0; i < 5; i++ {
+		limiter.Allow(ctx, alice)
+	}
+
+	res, err := limiter.Peek(ctx, core.Descriptor{"tenant": "acme", "user": "bob"})
+	if err != nil {
+		t.Fatalf("peek: %v", err)
+	}
+	if res.Remaining != 1 || res.Level != "user" {
+		t.Fatalf("expected fresh user with the tenant charged once, got remaining=%d level=%q", res.Remaining, res.Level)
+	}
+}
+
+// TestHierarchical_UsersAreScopedByTenant ensures the same user ID in two tenants is counted separately.
+func TestHierarchical_UsersAreScopedByTenant(t *testing.T) {
+	limiter := newTestHierarchy(t, 10, 10, 1)
+	ctx := context.Background()
+
+	for _, tenant := range []string{"acme", "globex"} {
+		res, err := limiter.Allow(ctx, core.Descriptor{"tenant": tenant, "user": "u1"})
+		if err != nil || !res.Allowed {
+			t.Fatalf("tenant %s: expected allowed, got %v, err %v", tenant, res.Allowed, err)
+		}
+	}
+}
+
+// TestHierarchical_IncompleteDescriptor ensures a missing field is an error rather than a shared key.
+func TestHierarchical_IncompleteDescriptor(t *testing.T) {
+	limiter := newTestHierarchy(t, 10, 10, 10)
+
+	_, err := limiter.Allow(context.Background(), core.Descriptor{"user": "alice"})
+	if !errors.Is(err, core.ErrIncompleteDescriptor) {
+		t.Fatalf("expected ErrIncompleteDescriptor, got %v", err)
+	}
+}
+
+// TestHierarchical_RefundAndReset verifies Refund reaches every level and Reset only the named one.
+func TestHierarchical_RefundAndReset(t *testing.T) {
+	limiter := newTestHierarchy(t, 10, 3, 2)
+	ctx := context.Background()
+	alice := core.Descriptor{"tenant": "acme", "user": "alice"}
+
+	limiter.AllowN(ctx, alice, 2)
+	if err := limiter.Refund(ctx, alice, 2); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	res, _ := limiter.Peek(ctx, alice)
+	if res.Remaining != 2 {
+		t.Fatalf("expected refund at every level, got remaining=%d at %q", res.Remaining, res.Level)
+	}
+
+	limiter.AllowN(ctx, alice, 2)
+	if err := limiter.Reset(ctx, "user", alice); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	res, _ = limiter.Peek(ctx, alice)
+	if res.Remaining != 1 || res.Level != "tenant" {
+		t.Fatalf("expected only the user level to be reset, got remaining=%d level=%q", res.Remaining, res.Level)
+	}
+
+	if err := limiter.Reset(ctx, "region", alice); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+}
@@ -21,7 +21,9 @@ type LeakyBucketLimiter struct {
 	prefix   string
 	mu       sync.Mutex
 	metrics  core.MetricsCollector
+	clock    core.Clock
 	failOpen bool
+	tagged   bool // keys come from a CompositeLimiter and already carry its hash tag
 }
 
 // NewLeakyBucketLimiter constructs a new LeakyBucketLimiter.
@@ -32,26 +34,50 @@ func NewLeakyBucketLimiter(cfg core.Config, store storage.Storage) core.Limiter
 		store:    store,
 		prefix:   "gorl:lb",
 		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
 		failOpen: cfg.FailOpen,
 	}
 }
 
+func (l *LeakyBucketLimiter) setLimit(limit int) {
+	l.limit = limit
+}
+
 // Allow checks and updates water level, allowing requests at a steady rate.
 func (l *LeakyBucketLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN checks whether n units of water fit in the bucket and pours them in if so.
+func (l *LeakyBucketLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := l.store.(redisScriptRunner)
+	return l.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (l *LeakyBucketLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, l.store, reqs, l.allow)
+}
+
+func (l *LeakyBucketLimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	if err := validateCost(n, l.limit); err != nil {
+		return core.Result{Limit: l.limit}, err
+	}
+
 	start := time.Now()
-	if runner, ok := l.store.(redisScriptRunner); ok {
-		return l.allowRedis(ctx, start, runner, key)
+	if runner != nil {
+		return l.allowRedis(ctx, start, runner, key, n)
 	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.allowGeneric(ctx, start, key)
+	return l.allowGeneric(ctx, start, key, n)
 }
 
-func (l *LeakyBucketLimiter) allowGeneric(ctx context.Context, start time.Time, key string) (core.Result, error) {
-	now := time.Now().UnixNano()
-	waterKey := fmt.Sprintf("%s:{%s}:water", l.prefix, key)
-	leakKey := fmt.Sprintf("%s:{%s}:leak", l.prefix, key)
+func (l *LeakyBucketLimiter) allowGeneric(ctx context.Context, start time.Time, key string, n int) (core.Result, error) {
+	now := l.clock.Now().UnixNano()
+	keys := l.storageKeys(key)
+	waterKey, leakKey := keys[0], keys[1]
 
 	// Load current state
 	waterVal, err := l.store.Get(ctx, waterKey)
@@ -59,44 +85,19 @@ func (l *LeakyBucketLimiter) allowGeneric(ctx context.Context, start time.Time,
 		return res, retErr
 	}
 
-	waterLevel := int(waterVal)
 	lastLeakVal, err := l.store.Get(ctx, leakKey)
 	if res, retErr, done := failOpenHandler(start, err, l.failOpen, l.metrics, l.limit); done {
 		return res, retErr
 	}
-	lastLeak := int64(lastLeakVal)
-
-	// Initialize if first run
-	if lastLeak == 0 {
-		waterLevel = 0
-		lastLeak = now
-	} else {
-		// Compute leaked tokens since last leak
-		elapsed := now - lastLeak
-		tokensPerNano := float64(l.limit) / float64(l.window.Nanoseconds())
-		leaked := int64(math.Floor(float64(elapsed) * tokensPerNano))
-		if leaked > 0 {
-			waterLevel -= int(leaked)
-			if waterLevel < 0 {
-				waterLevel = 0
-			}
-			lastLeak += int64(math.Floor(float64(leaked) / tokensPerNano))
-		}
-	}
+	waterLevel, lastLeak := l.leak(int(waterVal), int64(lastLeakVal), now)
 
 	// Determine allowance and update water level
-	allowed := waterLevel < l.limit
+	allowed := waterLevel+n <= l.limit
 	if allowed {
-		waterLevel++
+		waterLevel += n
 	}
 
-	nanoPerToken := float64(l.window.Nanoseconds()) / float64(l.limit)
-	elapsedSinceLeak := float64(now - lastLeak)
-	nextLeak := clampDuration(time.Duration(nanoPerToken-elapsedSinceLeak) * time.Nanosecond)
-	reset := time.Duration(0)
-	if waterLevel > 0 {
-		reset = clampDuration(time.Duration(float64(waterLevel)*nanoPerToken-elapsedSinceLeak) * time.Nanosecond)
-	}
+	reset, nextLeak := l.timing(waterLevel, lastLeak, now, n)
 
 	// Persist updated state
 	err = l.store.Set(ctx, waterKey, float64(waterLevel), l.window)
@@ -127,20 +128,16 @@ func (l *LeakyBucketLimiter) allowGeneric(ctx context.Context, start time.Time,
 	return res, nil
 }
 
-func (l *LeakyBucketLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string) (core.Result, error) {
-	keys := []string{
-		fmt.Sprintf("%s:{%s}:water", l.prefix, key),
-		fmt.Sprintf("%s:{%s}:leak", l.prefix, key),
-	}
-
+func (l *LeakyBucketLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string, n int) (core.Result, error) {
 	values, err := runner.EvalScript(
 		ctx,
 		redisScriptLeakyBucket,
-		keys,
+		l.storageKeys(key),
 		int64(l.limit),
-		time.Now().UnixMicro(),
+		l.clock.Now().UnixMicro(),
 		durationToMicros(l.window),
 		durationToMilliseconds(l.window),
+		int64(n),
 	)
 	if res, retErr, done := failOpenHandler(start, err, l.failOpen, l.metrics, l.limit); done {
 		return res, retErr
@@ -161,6 +158,168 @@ func (l *LeakyBucketLimiter) allowRedis(ctx context.Context, start time.Time, ru
 	return res, nil
 }
 
+// Peek reports the bucket state for key without pouring any water in.
+// Allowed tells whether a single-unit request would currently fit.
+func (l *LeakyBucketLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if runner, ok := l.store.(redisScriptRunner); ok {
+		return l.peekRedis(ctx, runner, key)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.peekGeneric(ctx, key)
+}
+
+func (l *LeakyBucketLimiter) peekGeneric(ctx context.Context, key string) (core.Result, error) {
+	now := l.clock.Now().UnixNano()
+	keys := l.storageKeys(key)
+
+	waterVal, err := l.store.Get(ctx, keys[0])
+	if err != nil {
+		return core.Result{Limit: l.limit}, err
+	}
+	lastLeakVal, err := l.store.Get(ctx, keys[1])
+	if err != nil {
+		return core.Result{Limit: l.limit}, err
+	}
+
+	waterLevel, lastLeak := l.leak(int(waterVal), int64(lastLeakVal), now)
+	reset, nextLeak := l.timing(waterLevel, lastLeak, now, 1)
+
+	res := core.Result{
+		Allowed:   waterLevel < l.limit,
+		Limit:     l.limit,
+		Remaining: l.limit - waterLevel,
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = nextLeak
+	}
+	return res, nil
+}
+
+func (l *LeakyBucketLimiter) peekRedis(ctx context.Context, runner redisScriptRunner, key string) (core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptLeakyBucketPeek,
+		l.storageKeys(key),
+		int64(l.limit),
+		l.clock.Now().UnixMicro(),
+		durationToMicros(l.window),
+	)
+	if err != nil {
+		return core.Result{Limit: l.limit}, err
+	}
+	return buildRedisScriptResult(l.limit, values)
+}
+
+// leak drains the bucket up to now without touching storage.
+// A zero lastLeak means the key has no state yet, so the bucket starts empty.
+func (l *LeakyBucketLimiter) leak(waterLevel int, lastLeak, now int64) (int, int64) {
+	if lastLeak == 0 {
+		return 0, now
+	}
+
+	elapsed := now - lastLeak
+	tokensPerNano := float64(l.limit) / float64(l.window.Nanoseconds())
+	leaked := int64(math.Floor(float64(elapsed) * tokensPerNano))
+	if leaked > 0 {
+		waterLevel -= int(leaked)
+		if waterLevel < 0 {
+			waterLevel = 0
+		}
+		lastLeak += int64(math.Floor(float64(leaked) / tokensPerNano))
+	}
+	return waterLevel, lastLeak
+}
+
+// timing returns how long until the bucket is empty and how long until cost units fit again.
+func (l *LeakyBucketLimiter) timing(waterLevel int, lastLeak, now int64, cost int) (time.Duration, time.Duration) {
+	nanoPerToken := float64(l.window.Nanoseconds()) / float64(l.limit)
+	elapsedSinceLeak := float64(now - lastLeak)
+	overflow := float64(waterLevel + cost - l.limit)
+	wait := clampDuration(time.Duration(overflow*nanoPerToken-elapsedSinceLeak) * time.Nanosecond)
+	reset := time.Duration(0)
+	if waterLevel > 0 {
+		reset = clampDuration(time.Duration(float64(waterLevel)*nanoPerToken-elapsedSinceLeak) * time.Nanosecond)
+	}
+	return reset, wait
+}
+
+func (l *LeakyBucketLimiter) useTaggedKeys() {
+	l.tagged = true
+}
+
+func (l *LeakyBucketLimiter) storageKeys(key string) []string {
+	tag := key
+	if !l.tagged {
+		tag = hashTag(key)
+	}
+	return []string{
+		fmt.Sprintf("%s:%s:water", l.prefix, tag),
+		fmt.Sprintf("%s:%s:leak", l.prefix, tag),
+	}
+}
+
+// Refund drains n units of water from the bucket for key, down to empty.
+func (l *LeakyBucketLimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	if runner, ok := l.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(
+			ctx,
+			redisScriptLeakyBucketRefund,
+			l.storageKeys(key),
+			int64(l.limit),
+			l.clock.Now().UnixMicro(),
+			durationToMicros(l.window),
+			durationToMilliseconds(l.window),
+			int64(n),
+		)
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.refundGeneric(ctx, key, n)
+}
+
+func (l *LeakyBucketLimiter) refundGeneric(ctx context.Context, key string, n int) error {
+	now := l.clock.Now().UnixNano()
+	keys := l.storageKeys(key)
+
+	waterVal, err := l.store.Get(ctx, keys[0])
+	if err != nil {
+		return err
+	}
+	lastLeakVal, err := l.store.Get(ctx, keys[1])
+	if err != nil {
+		return err
+	}
+	if lastLeakVal == 0 {
+		// No state means the bucket is already empty.
+		return nil
+	}
+
+	waterLevel, lastLeak := l.leak(int(waterVal), int64(lastLeakVal), now)
+	waterLevel -= n
+	if waterLevel < 0 {
+		waterLevel = 0
+	}
+
+	if err := l.store.Set(ctx, keys[0], float64(waterLevel), l.window); err != nil {
+		return err
+	}
+	return l.store.Set(ctx, keys[1], float64(lastLeak), l.window)
+}
+
+// Reset deletes the water level and leak timestamp stored for key.
+func (l *LeakyBucketLimiter) Reset(ctx context.Context, key string) error {
+	return l.store.Delete(ctx, l.storageKeys(key)...)
+}
+
 // Close releases resources held by the limiter.
 func (l *LeakyBucketLimiter) Close() error {
 	return l.store.Close()
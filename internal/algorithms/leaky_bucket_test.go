@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
 )
@@ -179,20 +180,21 @@ func TestLeakyBucket_GetLeakError(t *testing.T) {
 // TestLeakyBucket_WaterLevelFloor ensures that the water level is correctly reset to 0
 // if the calculated leak amount exceeds the current level.
 func TestLeakyBucket_WaterLevelFloor(t *testing.T) {
-	store := inmem.NewInMemoryStore()
+	clock := clocktest.NewManual(time.Now())
+	store := inmem.NewInMemoryStoreWithClock(clock)
 	defer store.Close()
-	// Window (5s) >> sleep so keys don't expire.
+	// Window (5s) >> advance so keys don't expire.
 	// limit=3, leakInterval = 5s/3 ≈ 1.67s
 	limiter := NewLeakyBucketLimiter(core.Config{
-		Limit: 3, Window: 5 * time.Second, Metrics: &core.NoopMetrics{},
+		Limit: 3, Window: 5 * time.Second, Metrics: &core.NoopMetrics{}, Clock: clock,
 	}, store)
 	ctx := context.Background()
 
 	// Use 1 token
 	limiter.Allow(ctx, "k")
 
-	// Wait for water to leak to 0.
-	time.Sleep(2 * time.Second)
+	// Let the water leak to 0.
+	clock.Advance(2 * time.Second)
 
 	// Should be able to use all 3 tokens
 	for i := 0; i < 3; i++ {
@@ -300,6 +302,91 @@ func TestLeakyBucket_ResultMetadata(t *testing.T) {
 	}
 }
 
+// TestLeakyBucket_AllowN verifies that weighted requests consume their full cost and that a
+// denied request leaves the remaining capacity untouched.
+func TestLeakyBucket_AllowN(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewLeakyBucketLimiter(core.Config{
+		Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.AllowN(ctx, "bulk", 3)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 3 to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 2 {
+		t.Fatalf("expected remaining=2, got %d", res.Remaining)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected cost 3 to be denied with 2 units left")
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 2)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 2 to fit the remaining capacity, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected remaining=0, got %d", res.Remaining)
+	}
+}
+
+// TestLeakyBucket_PeekDoesNotConsume verifies that Peek reports the current state without
+// spending capacity or recording metrics.
+func TestLeakyBucket_PeekDoesNotConsume(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	m := &mockMetrics{}
+	limiter := NewLeakyBucketLimiter(core.Config{
+		Limit: 3, Window: time.Minute, Metrics: m,
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 3 {
+		t.Fatalf("expected fresh key to report full capacity, got %+v", res)
+	}
+
+	limiter.Allow(ctx, "dash")
+	limiter.Allow(ctx, "dash")
+	for i := 0; i < 2; i++ {
+		res, err = limiter.Peek(ctx, "dash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.Allowed || res.Remaining != 1 {
+			t.Fatalf("peek %d: expected remaining=1, got %+v", i+1, res)
+		}
+	}
+
+	limiter.Allow(ctx, "dash")
+	res, err = limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected exhausted state, got %+v", res)
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+	if m.allows != 3 || m.denies != 0 {
+		t.Fatalf("peek should not record decisions, got %d allows and %d denies", m.allows, m.denies)
+	}
+}
+
 func BenchmarkLeakyBucket_SingleKey(b *testing.B) {
 	b.ReportAllocs()
 	store := inmem.NewInMemoryStore()
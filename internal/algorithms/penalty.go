@@ -0,0 +1,294 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// PenaltyBox wraps a limiter and bans keys that are denied too often. Denials are counted per key;
+// reaching the threshold within the period bans the key, and each repeat offence remembered in
+// storage multiplies the ban length by the escalation factor. Banned keys are denied without
+// consulting the wrapped limiter. Ban state lives in the store, so it is shared through Redis.
+type PenaltyBox struct {
+	inner    core.Limiter
+	store    storage.Storage
+	policy   core.PenaltyPolicy
+	memory   time.Duration
+	limit    int
+	failOpen bool
+	metrics  core.MetricsCollector
+	clock    core.Clock
+	mu       sync.Mutex
+}
+
+// penaltyLeaseLimiter keeps Acquire and Release available when the wrapped limiter hands out leases.
+type penaltyLeaseLimiter struct {
+	*PenaltyBox
+	leaser core.LeaseLimiter
+}
+
+// NewPenaltyBox wraps inner with the penalty policy of cfg. store must be the store inner uses;
+// it stays owned by inner, which closes it. When inner is a core.LeaseLimiter, so is the returned
+// limiter.
+func NewPenaltyBox(inner core.Limiter, cfg core.Config, store storage.Storage) core.Limiter {
+	limit := cfg.Limit
+	if cfg.Strategy == core.TokenBucket && cfg.Burst > 0 {
+		limit = cfg.Burst
+	}
+	memory := cfg.Penalty.OffenceMemory
+	if memory == 0 {
+		memory = core.DefaultOffenceMemory
+	}
+	p := &PenaltyBox{
+		inner:    inner,
+		store:    store,
+		policy:   cfg.Penalty,
+		memory:   memory,
+		limit:    limit,
+		failOpen: cfg.FailOpen,
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+	}
+	if leaser, ok := inner.(core.LeaseLimiter); ok {
+		return &penaltyLeaseLimiter{PenaltyBox: p, leaser: leaser}
+	}
+	return p
+}
+
+// Allow checks a single request for key.
+func (p *PenaltyBox) Allow(ctx context.Context, key string) (core.Result, error) {
+	return p.AllowN(ctx, key, 1)
+}
+
+// AllowN denies banned keys outright and otherwise asks the wrapped limiter. A denial from the
+// wrapped limiter counts as a strike, and the strike that reaches the threshold starts a ban.
+func (p *PenaltyBox) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	return p.admit(ctx, key, func() (core.Result, error) {
+		return p.inner.AllowN(ctx, key, n)
+	})
+}
+
+// AllowMany denies banned keys outright and passes the other requests to the wrapped limiter in
+// one batch, counting each denial as a strike.
+func (p *PenaltyBox) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	results := make([]core.BatchResult, len(reqs))
+	rest := make([]core.BatchRequest, 0, len(reqs))
+	restIdx := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		if res, err, done := p.screen(ctx, req.Key); done {
+			results[i] = core.BatchResult{Result: res, Err: err}
+			continue
+		}
+		rest = append(rest, req)
+		restIdx = append(restIdx, i)
+	}
+	for i, batchRes := range AllowMany(ctx, p.inner, rest) {
+		res, err := p.punish(ctx, rest[i].Key, batchRes.Result, batchRes.Err)
+		results[restIdx[i]] = core.BatchResult{Result: res, Err: err}
+	}
+	return results
+}
+
+// admit denies banned keys and otherwise decides the request with check, counting its denial as
+// a strike.
+func (p *PenaltyBox) admit(ctx context.Context, key string, check func() (core.Result, error)) (core.Result, error) {
+	if res, err, done := p.screen(ctx, key); done {
+		return res, err
+	}
+	res, err := check()
+	return p.punish(ctx, key, res, err)
+}
+
+// screen decides the request for key without the wrapped limiter when key is banned, or when the
+// ban cannot be read.
+func (p *PenaltyBox) screen(ctx context.Context, key string) (core.Result, error, bool) {
+	start := time.Now()
+	res, banned, err := p.banned(ctx, key)
+	if err != nil {
+		return failOpenHandler(start, err, p.failOpen, p.metrics, p.limit)
+	}
+	if !banned {
+		return core.Result{}, nil, false
+	}
+	p.metrics.ObserveLatency(time.Since(start))
+	p.metrics.IncDeny()
+	return res, nil, true
+}
+
+// punish counts a denial of the wrapped limiter as a strike for key, extending the wait of res
+// by the ban the strike starts.
+func (p *PenaltyBox) punish(ctx context.Context, key string, res core.Result, err error) (core.Result, error) {
+	if err != nil || res.Allowed {
+		return res, err
+	}
+
+	until, err := p.strike(ctx, key)
+	if err != nil {
+		// The request is denied either way; only report the lost strike when failing closed.
+		if p.failOpen {
+			return res, nil
+		}
+		return res, err
+	}
+	if until > 0 {
+		ban := p.remaining(until)
+		res.RetryAfter = max(res.RetryAfter, ban)
+		res.Reset = max(res.Reset, ban)
+	}
+	return res, nil
+}
+
+// Acquire denies banned keys with the zero Lease, and otherwise takes a slot from the wrapped
+// limiter; a denied Acquire counts as a strike.
+func (p *penaltyLeaseLimiter) Acquire(ctx context.Context, key string) (core.Lease, core.Result, error) {
+	var lease core.Lease
+	res, err := p.admit(ctx, key, func() (core.Result, error) {
+		var res core.Result
+		var err error
+		lease, res, err = p.leaser.Acquire(ctx, key)
+		return res, err
+	})
+	return lease, res, err
+}
+
+// Release gives a slot back to the wrapped limiter.
+func (p *penaltyLeaseLimiter) Release(ctx context.Context, lease core.Lease) error {
+	return p.leaser.Release(ctx, lease)
+}
+
+// Peek reports the ban of key if there is one, and the wrapped limiter's state otherwise.
+func (p *PenaltyBox) Peek(ctx context.Context, key string) (core.Result, error) {
+	res, banned, err := p.banned(ctx, key)
+	if err != nil {
+		return core.Result{Limit: p.limit}, err
+	}
+	if banned {
+		return res, nil
+	}
+	return p.inner.Peek(ctx, key)
+}
+
+// Refund gives n units back to key in the wrapped limiter. Bans and strikes are unaffected.
+func (p *PenaltyBox) Refund(ctx context.Context, key string, n int) error {
+	return p.inner.Refund(ctx, key, n)
+}
+
+// Reset forgets the state of key in the wrapped limiter and lifts its ban, strikes and offences.
+func (p *PenaltyBox) Reset(ctx context.Context, key string) error {
+	if err := p.inner.Reset(ctx, key); err != nil {
+		return err
+	}
+	return p.store.Delete(ctx, p.keys(key)...)
+}
+
+// Close closes the wrapped limiter.
+func (p *PenaltyBox) Close() error {
+	return p.inner.Close()
+}
+
+// banned reports whether key is banned and, if so, the denial to return for it.
+func (p *PenaltyBox) banned(ctx context.Context, key string) (core.Result, bool, error) {
+	until, err := p.store.Get(ctx, p.keys(key)[2])
+	if err != nil {
+		return core.Result{}, false, err
+	}
+	wait := p.remaining(int64(until))
+	if wait <= 0 {
+		return core.Result{}, false, nil
+	}
+	return core.Result{
+		Allowed:    false,
+		Limit:      p.limit,
+		Remaining:  0,
+		Reset:      wait,
+		RetryAfter: wait,
+	}, true, nil
+}
+
+// strike records a denial for key and returns the end of the ban it started in Unix
+// microseconds, or 0 when the key is not banned yet.
+func (p *PenaltyBox) strike(ctx context.Context, key string) (int64, error) {
+	keys := p.keys(key)
+	now := p.clock.Now()
+
+	if runner, ok := p.store.(redisScriptRunner); ok {
+		values, err := runner.EvalScript(ctx, redisScriptPenaltyStrike, keys,
+			now.UnixMicro(),
+			int64(p.policy.Threshold),
+			durationToMilliseconds(p.policy.Period),
+			durationToMicros(p.policy.BanDuration),
+			int64(math.Round(p.escalation()*1e6)),
+			durationToMicros(p.policy.MaxBanDuration),
+			durationToMilliseconds(p.memory),
+		)
+		if err != nil {
+			return 0, err
+		}
+		if len(values) != 1 {
+			return 0, fmt.Errorf("unexpected redis script result length: %d", len(values))
+		}
+		return values[0], nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	strikes, err := p.store.IncrBy(ctx, keys[0], 1, p.policy.Period)
+	if err != nil || int(strikes) < p.policy.Threshold {
+		return 0, err
+	}
+	if err := p.store.Delete(ctx, keys[0]); err != nil {
+		return 0, err
+	}
+	offences, err := p.store.Get(ctx, keys[1])
+	if err != nil {
+		return 0, err
+	}
+	offences++
+	if err := p.store.Set(ctx, keys[1], offences, p.memory); err != nil {
+		return 0, err
+	}
+
+	ban := p.banDuration(int(offences))
+	until := now.Add(ban).UnixMicro()
+	if err := p.store.Set(ctx, keys[2], float64(until), ban); err != nil {
+		return 0, err
+	}
+	return until, nil
+}
+
+// banDuration returns the length of the ban for the given offence, counting from 1.
+func (p *PenaltyBox) banDuration(offence int) time.Duration {
+	ban := float64(p.policy.BanDuration) * math.Pow(p.escalation(), float64(offence-1))
+	if p.policy.MaxBanDuration > 0 && ban > float64(p.policy.MaxBanDuration) {
+		return p.policy.MaxBanDuration
+	}
+	return time.Duration(ban)
+}
+
+func (p *PenaltyBox) escalation() float64 {
+	if p.policy.Escalation == 0 {
+		return 1
+	}
+	return p.policy.Escalation
+}
+
+// remaining returns how long a ban ending at until (Unix microseconds) still lasts.
+func (p *PenaltyBox) remaining(until int64) time.Duration {
+	return clampDuration(time.UnixMicro(until).Sub(p.clock.Now()))
+}
+
+// keys returns the strike counter, offence counter and ban keys of key, in one hash slot.
+func (p *PenaltyBox) keys(key string) []string {
+	return []string{
+		fmt.Sprintf("gorl:penalty:{%s}:strikes", key),
+		fmt.Sprintf("gorl:penalty:{%s}:offences", key),
+		fmt.Sprintf("gorl:penalty:{%s}:ban", key),
+	}
+}
@@ -0,0 +1,166 @@
+package algorithms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// countingLimiter counts the calls that reach the wrapped limiter.
+type countingLimiter struct {
+	core.Limiter
+	calls int
+}
+
+func (c *countingLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	c.calls++
+	return c.Limiter.AllowN(ctx, key, n)
+}
+
+func newTestPenaltyBox(t *testing.T, clock *clocktest.ManualClock, policy core.PenaltyPolicy) (core.Limiter, *countingLimiter) {
+	t.Helper()
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	cfg := core.Config{Limit: 1, Window: time.Hour, Penalty: policy, Metrics: &core.NoopMetrics{}, Clock: clock}
+	inner := &countingLimiter{Limiter: NewFixedWindowLimiter(cfg, store)}
+	limiter := NewPenaltyBox(inner, cfg, store)
+	t.Cleanup(func() { limiter.Close() })
+	return limiter, inner
+}
+
+// TestPenaltyBox_BansAfterThreshold checks that the denial reaching the threshold starts a ban
+// and that banned requests never reach the wrapped limiter.
+func TestPenaltyBox_BansAfterThreshold(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, inner := newTestPenaltyBox(t, clock, core.PenaltyPolicy{
+		Threshold: 3, Period: time.Minute, BanDuration: 10 * time.Minute,
+	})
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "k")
+	for i := 0; i < 2; i++ {
+		res, _ := limiter.Allow(ctx, "k")
+		if res.Allowed || res.RetryAfter > time.Hour || res.RetryAfter < 59*time.Minute {
+			t.Fatalf("strike %d: expected a plain window denial, got %+v", i+1, res)
+		}
+	}
+	res, err := limiter.Allow(ctx, "k")
+	if err != nil || res.Allowed {
+		t.Fatalf("expected denial, got %v, err %v", res.Allowed, err)
+	}
+
+	clock.Advance(time.Minute)
+	calls := inner.calls
+	res, err = limiter.Allow(ctx, "k")
+	if err != nil || res.Allowed {
+		t.Fatalf("expected banned key to be denied, got %v, err %v", res.Allowed, err)
+	}
+	if res.RetryAfter != 9*time.Minute {
+		t.Fatalf("expected the ban's remaining 9m as RetryAfter, got %v", res.RetryAfter)
+	}
+	if inner.calls != calls {
+		t.Fatal("expected a banned request not to reach the wrapped limiter")
+	}
+
+	clock.Advance(9 * time.Minute)
+	limiter.Allow(ctx, "k")
+	if inner.calls != calls+1 {
+		t.Fatal("expected the wrapped limiter to be consulted once the ban is over")
+	}
+}
+
+// TestPenaltyBox_StrikesExpire ensures denials spread beyond the period do not add up to a ban.
+func TestPenaltyBox_StrikesExpire(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, inner := newTestPenaltyBox(t, clock, core.PenaltyPolicy{
+		Threshold: 2, Period: time.Minute, BanDuration: time.Hour,
+	})
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "k")
+	limiter.Allow(ctx, "k")
+	clock.Advance(2 * time.Minute)
+	limiter.Allow(ctx, "k")
+	clock.Advance(time.Second)
+
+	calls := inner.calls
+	limiter.Allow(ctx, "k")
+	if inner.calls != calls+1 {
+		t.Fatal("expected no ban from strikes in different periods")
+	}
+}
+
+// TestPenaltyBox_Escalation checks that repeat offences lengthen the ban up to the maximum.
+func TestPenaltyBox_Escalation(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, _ := newTestPenaltyBox(t, clock, core.PenaltyPolicy{
+		Threshold: 1, Period: time.Minute, BanDuration: 2 * time.Hour, Escalation: 3, MaxBanDuration: 12 * time.Hour,
+	})
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "k")
+	for i, want := range []time.Duration{2 * time.Hour, 6 * time.Hour, 12 * time.Hour} {
+		res, _ := limiter.Allow(ctx, "k")
+		if res.Allowed || res.RetryAfter != want {
+			t.Fatalf("offence %d: expected a ban of %v, got allowed=%v retry=%v", i+1, want, res.Allowed, res.RetryAfter)
+		}
+		// Wait out the ban and use up the next window.
+		clock.Advance(want)
+		limiter.Allow(ctx, "k")
+	}
+}
+
+// TestPenaltyBox_ResetLiftsBan ensures Reset clears both the strategy state and the ban.
+func TestPenaltyBox_ResetLiftsBan(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, _ := newTestPenaltyBox(t, clock, core.PenaltyPolicy{
+		Threshold: 1, Period: time.Minute, BanDuration: time.Hour,
+	})
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "k")
+	limiter.Allow(ctx, "k")
+	if res, _ := limiter.Peek(ctx, "k"); res.RetryAfter != time.Hour {
+		t.Fatalf("expected Peek to report the ban, got %v", res.RetryAfter)
+	}
+
+	if err := limiter.Reset(ctx, "k"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed {
+		t.Fatalf("expected allowed after reset, got %v, err %v", res.Allowed, err)
+	}
+}
+
+// TestPenaltyBox_AllowMany checks that a batch skips banned keys and strikes the others' denials.
+func TestPenaltyBox_AllowMany(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, inner := newTestPenaltyBox(t, clock, core.PenaltyPolicy{
+		Threshold: 2, Period: time.Minute, BanDuration: 2 * time.Hour,
+	})
+	batcher, ok := limiter.(core.BatchLimiter)
+	if !ok {
+		t.Fatal("expected the penalty box to be a core.BatchLimiter")
+	}
+	ctx := context.Background()
+
+	results := batcher.AllowMany(ctx, []core.BatchRequest{{Key: "k", Cost: 1}, {Key: "k", Cost: 1}, {Key: "k", Cost: 1}, {Key: "other", Cost: 1}})
+	if !results[0].Allowed || results[1].Allowed || results[2].Allowed || !results[3].Allowed {
+		t.Fatalf("expected the first request per key allowed, got %+v", results)
+	}
+	if results[2].RetryAfter != 2*time.Hour {
+		t.Fatalf("expected the second denial to start the ban, got %+v", results[2])
+	}
+
+	calls := inner.calls
+	results = batcher.AllowMany(ctx, []core.BatchRequest{{Key: "k", Cost: 1}, {Key: "fresh", Cost: 1}})
+	if results[0].Allowed || results[0].RetryAfter != 2*time.Hour || !results[1].Allowed {
+		t.Fatalf("expected the banned key denied and the fresh key allowed, got %+v", results)
+	}
+	if inner.calls != calls+1 {
+		t.Fatalf("expected only the fresh key to reach the wrapped limiter, got %d calls", inner.calls-calls)
+	}
+}
@@ -0,0 +1,283 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// expireChecks is how many times per lease TTL the limiter looks for expired leases, so unspent
+// permits return to the shared budget at most TTL/expireChecks after their lease ends.
+const expireChecks = 4
+
+// PermitLeaseLimiter wraps a limiter on a shared store and admits requests from permits leased
+// from it in batches: one AllowN of Batch units on the wrapped limiter pays for the next Batch
+// local admissions of a key. Unspent permits are refunded when their lease expires and when the
+// limiter is closed. Once a lease is denied, requests for the key go to the wrapped limiter one
+// by one until the lease TTL has passed, so close to the limit nothing is held back locally.
+type PermitLeaseLimiter struct {
+	inner   core.Limiter
+	limit   int
+	batch   int
+	ttl     time.Duration
+	metrics core.MetricsCollector
+	clock   core.Clock
+
+	mu     sync.Mutex
+	leases map[string]*permitLease
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// permitLease holds the permits leased for one key.
+type permitLease struct {
+	mu          sync.Mutex
+	permits     int         // Leased permits not spent yet
+	res         core.Result // Result of the lease, the base of the state reported locally
+	leasedAt    time.Time
+	expires     time.Time
+	directUntil time.Time // Requests skip leasing until then, after a denied lease
+	removed     bool      // Dropped from the lease table; look the key up again
+}
+
+// NewPermitLeaseLimiter wraps inner with the leasing policy of cfg. inner should be built
+// without metrics: the returned limiter reports every decision to cfg.Metrics itself, since
+// one call on inner pays for many requests. Close the limiter to hand unspent permits back.
+func NewPermitLeaseLimiter(inner core.Limiter, cfg core.Config) core.Limiter {
+	limit := cfg.Limit
+	if cfg.Strategy == core.TokenBucket && cfg.Burst > 0 {
+		limit = cfg.Burst
+	}
+	ttl := cfg.PermitLease.TTL
+	if ttl == 0 {
+		ttl = core.DefaultPermitLeaseTTL
+		if cfg.Window > 0 {
+			ttl = min(ttl, cfg.Window)
+		}
+	}
+	l := &PermitLeaseLimiter{
+		inner:   inner,
+		limit:   limit,
+		batch:   cfg.PermitLease.Batch,
+		ttl:     ttl,
+		metrics: cfg.Metrics,
+		clock:   clockOf(cfg),
+		leases:  make(map[string]*permitLease),
+		stop:    make(chan struct{}),
+	}
+	go l.expireLoop()
+	return l
+}
+
+// Allow checks a single request for key.
+func (l *PermitLeaseLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN spends n leased permits of key, leasing a new batch when too few are left. Requests
+// costing more than a batch go to the wrapped limiter directly.
+func (l *PermitLeaseLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	start := time.Now()
+	if n > l.batch {
+		return l.direct(ctx, start, key, n)
+	}
+	if err := validateCost(n, l.limit); err != nil {
+		return core.Result{Limit: l.limit}, err
+	}
+
+	for {
+		lease := l.lease(key)
+		lease.mu.Lock()
+		if lease.removed {
+			lease.mu.Unlock()
+			continue
+		}
+		res, err := l.allowLocked(ctx, start, lease, key, n)
+		lease.mu.Unlock()
+		return res, err
+	}
+}
+
+func (l *PermitLeaseLimiter) allowLocked(ctx context.Context, start time.Time, lease *permitLease, key string, n int) (core.Result, error) {
+	now := l.clock.Now()
+	if now.Before(lease.expires) && lease.permits >= n {
+		lease.permits -= n
+		recordDecision(l.metrics, start, true)
+		return lease.result(now), nil
+	}
+	if now.Before(lease.directUntil) {
+		return l.direct(ctx, start, key, n)
+	}
+
+	// A lost refund only leaves the shared state stricter than it needs to be.
+	_ = l.refund(ctx, key, lease)
+	res, err := l.inner.AllowN(ctx, key, l.batch)
+	if err != nil {
+		return res, err
+	}
+	if !res.Allowed {
+		lease.directUntil = now.Add(l.ttl)
+		return l.direct(ctx, start, key, n)
+	}
+	lease.permits = l.batch - n
+	lease.res = res
+	lease.leasedAt = now
+	lease.expires = now.Add(l.ttl)
+	recordDecision(l.metrics, start, true)
+	return lease.result(now), nil
+}
+
+// direct decides a request on the wrapped limiter without leasing.
+func (l *PermitLeaseLimiter) direct(ctx context.Context, start time.Time, key string, n int) (core.Result, error) {
+	res, err := l.inner.AllowN(ctx, key, n)
+	if err != nil {
+		return res, err
+	}
+	recordDecision(l.metrics, start, res.Allowed)
+	return res, nil
+}
+
+// result reports the state of a lease: the shared state after the lease was taken plus the
+// permits still held locally.
+func (p *permitLease) result(now time.Time) core.Result {
+	res := p.res
+	res.Allowed = true
+	res.Remaining += p.permits
+	res.Reset = clampDuration(res.Reset - now.Sub(p.leasedAt))
+	res.RetryAfter = 0
+	return res
+}
+
+// Peek reports the wrapped limiter's state, counting the permits leased for key as remaining.
+func (l *PermitLeaseLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	res, err := l.inner.Peek(ctx, key)
+	if err != nil {
+		return res, err
+	}
+	l.mu.Lock()
+	lease, ok := l.leases[key]
+	l.mu.Unlock()
+	if !ok {
+		return res, nil
+	}
+
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+	if !lease.removed && l.clock.Now().Before(lease.expires) && lease.permits > 0 {
+		res.Allowed = true
+		res.Remaining = min(res.Remaining+lease.permits, res.Limit)
+		res.RetryAfter = 0
+	}
+	return res, nil
+}
+
+// Refund gives n units back to key in the wrapped limiter.
+func (l *PermitLeaseLimiter) Refund(ctx context.Context, key string, n int) error {
+	return l.inner.Refund(ctx, key, n)
+}
+
+// Reset drops the permits leased for key and forgets its state in the wrapped limiter.
+func (l *PermitLeaseLimiter) Reset(ctx context.Context, key string) error {
+	l.mu.Lock()
+	lease, ok := l.leases[key]
+	delete(l.leases, key)
+	l.mu.Unlock()
+	if ok {
+		lease.mu.Lock()
+		lease.removed = true
+		lease.permits = 0
+		lease.mu.Unlock()
+	}
+	return l.inner.Reset(ctx, key)
+}
+
+// Close refunds every unspent permit and closes the wrapped limiter.
+func (l *PermitLeaseLimiter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.stop)
+
+		l.mu.Lock()
+		leases := l.leases
+		l.leases = make(map[string]*permitLease)
+		l.mu.Unlock()
+
+		var errs []error
+		for key, lease := range leases {
+			lease.mu.Lock()
+			lease.removed = true
+			if err := l.refund(context.Background(), key, lease); err != nil {
+				errs = append(errs, err)
+			}
+			lease.mu.Unlock()
+		}
+		errs = append(errs, l.inner.Close())
+		l.closeErr = errors.Join(errs...)
+	})
+	return l.closeErr
+}
+
+// lease returns the lease entry of key, creating an empty one if needed.
+func (l *PermitLeaseLimiter) lease(key string) *permitLease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lease, ok := l.leases[key]
+	if !ok {
+		lease = &permitLease{}
+		l.leases[key] = lease
+	}
+	return lease
+}
+
+// refund hands the unspent permits of lease back to the wrapped limiter. lease.mu must be held.
+func (l *PermitLeaseLimiter) refund(ctx context.Context, key string, lease *permitLease) error {
+	permits := lease.permits
+	lease.permits = 0
+	if permits == 0 {
+		return nil
+	}
+	return l.inner.Refund(ctx, key, permits)
+}
+
+func (l *PermitLeaseLimiter) expireLoop() {
+	ticker := time.NewTicker(max(l.ttl/expireChecks, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.expire(context.Background(), l.clock.Now())
+		}
+	}
+}
+
+// expire refunds and drops the leases that ended before now. Leases busy with a request are
+// left for the next round.
+func (l *PermitLeaseLimiter) expire(ctx context.Context, now time.Time) {
+	stale := make(map[string]*permitLease)
+	l.mu.Lock()
+	for key, lease := range l.leases {
+		if !lease.mu.TryLock() {
+			continue
+		}
+		if now.Before(lease.expires) || now.Before(lease.directUntil) {
+			lease.mu.Unlock()
+			continue
+		}
+		lease.removed = true
+		delete(l.leases, key)
+		stale[key] = lease
+	}
+	l.mu.Unlock()
+
+	for key, lease := range stale {
+		_ = l.refund(ctx, key, lease)
+		lease.mu.Unlock()
+	}
+}
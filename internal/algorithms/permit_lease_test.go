@@ -0,0 +1,219 @@
+package algorithms
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// newLeaseInstance simulates one instance leasing permits from a store shared with others.
+func newLeaseInstance(t *testing.T, cfg core.Config, store storage.Storage) *PermitLeaseLimiter {
+	t.Helper()
+	strategyCfg := cfg
+	strategyCfg.Metrics = &core.NoopMetrics{}
+	limiter := NewPermitLeaseLimiter(NewFixedWindowLimiter(strategyCfg, sharedTestStore{store}), cfg).(*PermitLeaseLimiter)
+	t.Cleanup(func() { limiter.Close() })
+	return limiter
+}
+
+// sharedTestStore keeps instances from closing the store they share.
+type sharedTestStore struct {
+	storage.Storage
+}
+
+func (sharedTestStore) Close() error { return nil }
+
+// TestPermitLease_GlobalLimitAcrossInstances checks that instances spending leased permits
+// concurrently never admit more than the shared limit.
+func TestPermitLease_GlobalLimitAcrossInstances(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	cfg := core.Config{
+		Limit: 100, Window: time.Minute, Metrics: &core.NoopMetrics{},
+		PermitLease: core.PermitLeasePolicy{Batch: 10, TTL: time.Minute},
+	}
+	instances := make([]*PermitLeaseLimiter, 4)
+	for i := range instances {
+		instances[i] = newLeaseInstance(t, cfg, store)
+	}
+
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 400; i++ {
+		wg.Add(1)
+		go func(l core.Limiter) {
+			defer wg.Done()
+			res, err := l.Allow(context.Background(), "hot")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.Allowed {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}(instances[i%len(instances)])
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&allowed); got > 100 || got < 100-int32(len(instances))*10 {
+		t.Fatalf("expected between 60 and 100 admissions, got %d", got)
+	}
+}
+
+// TestPermitLease_FewerRoundTrips checks that one lease pays for a batch of requests.
+func TestPermitLease_FewerRoundTrips(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	cfg := core.Config{
+		Limit: 100, Window: time.Minute, Metrics: &mockMetrics{},
+		PermitLease: core.PermitLeasePolicy{Batch: 10, TTL: time.Minute},
+	}
+	inner := &countingLimiter{Limiter: NewFixedWindowLimiter(core.Config{Limit: 100, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)}
+	limiter := NewPermitLeaseLimiter(inner, cfg)
+	defer limiter.Close()
+
+	for i := 0; i < 25; i++ {
+		res, err := limiter.Allow(context.Background(), "k")
+		if err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed, got %+v, err %v", i+1, res, err)
+		}
+		if want := 100 - i - 1; res.Remaining != want {
+			t.Fatalf("req %d: expected %d remaining, got %d", i+1, want, res.Remaining)
+		}
+	}
+	if inner.calls != 3 {
+		t.Fatalf("expected 3 leases for 25 requests, got %d calls", inner.calls)
+	}
+	if m := cfg.Metrics.(*mockMetrics); m.allows != 25 {
+		t.Fatalf("expected 25 allows recorded, got %d", m.allows)
+	}
+}
+
+// TestPermitLease_RefundsOnExpiryAndClose checks that unspent permits return to the shared
+// budget when their lease expires and when the limiter is closed.
+func TestPermitLease_RefundsOnExpiryAndClose(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	cfg := core.Config{
+		Limit: 20, Window: time.Hour, Metrics: &core.NoopMetrics{}, Clock: clock,
+		PermitLease: core.PermitLeasePolicy{Batch: 10, TTL: time.Second},
+	}
+	shared := NewFixedWindowLimiter(cfg, sharedTestStore{store})
+	ctx := context.Background()
+
+	a := newLeaseInstance(t, cfg, store)
+	if res, _ := a.Allow(ctx, "k"); !res.Allowed {
+		t.Fatal("expected first request allowed")
+	}
+	if res, _ := shared.Peek(ctx, "k"); res.Remaining != 10 {
+		t.Fatalf("expected the lease to hold 10 permits, got %d remaining", res.Remaining)
+	}
+
+	clock.Advance(2 * time.Second)
+	a.expire(ctx, clock.Now())
+	if res, _ := shared.Peek(ctx, "k"); res.Remaining != 19 {
+		t.Fatalf("expected 9 permits refunded on expiry, got %d remaining", res.Remaining)
+	}
+
+	b := newLeaseInstance(t, cfg, store)
+	b.AllowN(ctx, "k", 3)
+	if err := b.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if res, _ := shared.Peek(ctx, "k"); res.Remaining != 16 {
+		t.Fatalf("expected 7 permits refunded on close, got %d remaining", res.Remaining)
+	}
+}
+
+// TestPermitLease_DirectNearLimit checks that a denied lease falls back to per-request calls,
+// so the last permits of a key can still be spent by any instance.
+func TestPermitLease_DirectNearLimit(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	cfg := core.Config{
+		Limit: 15, Window: time.Minute, Metrics: &core.NoopMetrics{},
+		PermitLease: core.PermitLeasePolicy{Batch: 10, TTL: time.Minute},
+	}
+	a := newLeaseInstance(t, cfg, store)
+	b := newLeaseInstance(t, cfg, store)
+	ctx := context.Background()
+
+	a.Allow(ctx, "k")
+	for i := 0; i < 5; i++ {
+		if res, err := b.Allow(ctx, "k"); err != nil || !res.Allowed {
+			t.Fatalf("req %d on b: expected allowed, got %+v, err %v", i+1, res, err)
+		}
+	}
+	if res, _ := b.Allow(ctx, "k"); res.Allowed || res.RetryAfter <= 0 {
+		t.Fatalf("expected b denied with a RetryAfter, got %+v", res)
+	}
+	for i := 0; i < 9; i++ {
+		if res, _ := a.Allow(ctx, "k"); !res.Allowed {
+			t.Fatalf("req %d on a: expected a leased permit", i+1)
+		}
+	}
+	if res, _ := a.Allow(ctx, "k"); res.Allowed {
+		t.Fatal("expected a denied once the budget is spent")
+	}
+}
+
+// TestPermitLease_OvershootBound checks that permits leased in one window and spent in the next
+// admit at most Batch requests per instance on top of the limit.
+func TestPermitLease_OvershootBound(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 59, 0, time.UTC))
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	cfg := core.Config{
+		Limit: 20, Window: time.Minute, Metrics: &core.NoopMetrics{}, Clock: clock,
+		PermitLease: core.PermitLeasePolicy{Batch: 5, TTL: 10 * time.Second},
+	}
+	instances := []*PermitLeaseLimiter{newLeaseInstance(t, cfg, store), newLeaseInstance(t, cfg, store)}
+	ctx := context.Background()
+
+	for _, l := range instances {
+		l.Allow(ctx, "k")
+	}
+	clock.Advance(2 * time.Second)
+
+	allowed := 0
+	for i := 0; i < 40; i++ {
+		if res, _ := instances[i%2].Allow(ctx, "k"); res.Allowed {
+			allowed++
+		}
+	}
+	if allowed > 20+2*5 {
+		t.Fatalf("expected at most %d admissions in the new window, got %d", 20+2*5, allowed)
+	}
+	if allowed < 20 {
+		t.Fatalf("expected the new window's limit to be available, got %d admissions", allowed)
+	}
+}
+
+// TestPermitLease_ExpiresWithinTTL checks that unspent permits return to the shared budget soon
+// after their lease ends, not a whole TTL later.
+func TestPermitLease_ExpiresWithinTTL(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	cfg := core.Config{
+		Limit: 20, Window: time.Hour, Metrics: &core.NoopMetrics{},
+		PermitLease: core.PermitLeasePolicy{Batch: 10, TTL: 400 * time.Millisecond},
+	}
+	shared := NewFixedWindowLimiter(cfg, sharedTestStore{store})
+	ctx := context.Background()
+
+	// Lease halfway between two checks at the TTL's pace, which would keep the permits out
+	// until the second one.
+	l := newLeaseInstance(t, cfg, store)
+	time.Sleep(200 * time.Millisecond)
+	l.Allow(ctx, "k")
+	time.Sleep(540 * time.Millisecond)
+	if res, _ := shared.Peek(ctx, "k"); res.Remaining != 19 {
+		t.Fatalf("expected 9 permits refunded soon after expiry, got %d remaining", res.Remaining)
+	}
+}
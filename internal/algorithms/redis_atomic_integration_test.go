@@ -2,6 +2,7 @@ package algorithms_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/AliRizaAynaci/gorl/v2/internal/algorithms"
 	"github.com/AliRizaAynaci/gorl/v2/storage"
@@ -41,6 +43,9 @@ func TestRedisAtomicAlgorithms_MultiInstanceBurst(t *testing.T) {
 		{"SlidingWindow", algorithms.NewSlidingWindowLimiter},
 		{"TokenBucket", algorithms.NewTokenBucketLimiter},
 		{"LeakyBucket", algorithms.NewLeakyBucketLimiter},
+		{"SlidingLog", algorithms.NewSlidingLogLimiter},
+		{"GCRA", algorithms.NewGCRALimiter},
+		{"Concurrency", algorithms.NewConcurrencyLimiter},
 	}
 
 	for _, strategy := range strategies {
@@ -111,6 +116,9 @@ func TestRedisAtomicAlgorithms_ResultMetadata(t *testing.T) {
 		{"SlidingWindow", algorithms.NewSlidingWindowLimiter},
 		{"TokenBucket", algorithms.NewTokenBucketLimiter},
 		{"LeakyBucket", algorithms.NewLeakyBucketLimiter},
+		{"SlidingLog", algorithms.NewSlidingLogLimiter},
+		{"GCRA", algorithms.NewGCRALimiter},
+		{"Concurrency", algorithms.NewConcurrencyLimiter},
 	}
 
 	for _, strategy := range strategies {
@@ -166,3 +174,435 @@ func TestRedisAtomicAlgorithms_ResultMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisAtomicAlgorithms_PeekRefundAndReset(t *testing.T) {
+	strategies := []struct {
+		name        string
+		constructor func(core.Config, storage.Storage) core.Limiter
+	}{
+		{"FixedWindow", algorithms.NewFixedWindowLimiter},
+		{"SlidingWindow", algorithms.NewSlidingWindowLimiter},
+		{"TokenBucket", algorithms.NewTokenBucketLimiter},
+		{"LeakyBucket", algorithms.NewLeakyBucketLimiter},
+		{"SlidingLog", algorithms.NewSlidingLogLimiter},
+		{"GCRA", algorithms.NewGCRALimiter},
+		{"Concurrency", algorithms.NewConcurrencyLimiter},
+	}
+
+	for _, strategy := range strategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			store := newRedisStoreForTest(t)
+			limiter := strategy.constructor(core.Config{
+				Limit:   5,
+				Window:  time.Minute,
+				Metrics: &core.NoopMetrics{},
+			}, store)
+			defer limiter.Close()
+
+			ctx := context.Background()
+			key := fmt.Sprintf("%s-reset-%d", strategy.name, time.Now().UnixNano())
+
+			res, err := limiter.AllowN(ctx, key, 5)
+			if err != nil || !res.Allowed {
+				t.Fatalf("expected weighted request to be allowed, got %v, err %v", res.Allowed, err)
+			}
+
+			peek, err := limiter.Peek(ctx, key)
+			if err != nil {
+				t.Fatalf("unexpected peek error: %v", err)
+			}
+			if peek.Allowed || peek.Remaining != 0 || peek.RetryAfter <= 0 {
+				t.Fatalf("expected exhausted peek result, got %+v", peek)
+			}
+
+			if err := limiter.Refund(ctx, key, 2); err != nil {
+				t.Fatalf("unexpected refund error: %v", err)
+			}
+			peek, err = limiter.Peek(ctx, key)
+			if err != nil {
+				t.Fatalf("unexpected peek error: %v", err)
+			}
+			if !peek.Allowed || peek.Remaining != 2 {
+				t.Fatalf("expected two refunded units, got %+v", peek)
+			}
+
+			if err := limiter.Reset(ctx, key); err != nil {
+				t.Fatalf("unexpected reset error: %v", err)
+			}
+
+			peek, err = limiter.Peek(ctx, key)
+			if err != nil {
+				t.Fatalf("unexpected peek error: %v", err)
+			}
+			if !peek.Allowed || peek.Remaining != 5 {
+				t.Fatalf("expected full capacity after reset, got %+v", peek)
+			}
+		})
+	}
+}
+
+func TestRedisAtomicAlgorithms_ConcurrencyLeasesAcrossInstances(t *testing.T) {
+	cfg := core.Config{Limit: 2, Window: time.Minute, Metrics: &core.NoopMetrics{}}
+	limiterA := algorithms.NewConcurrencyLimiter(cfg, newRedisStoreForTest(t)).(core.LeaseLimiter)
+	defer limiterA.Close()
+	limiterB := algorithms.NewConcurrencyLimiter(cfg, newRedisStoreForTest(t)).(core.LeaseLimiter)
+	defer limiterB.Close()
+
+	ctx := context.Background()
+	key := fmt.Sprintf("concurrency-leases-%d", time.Now().UnixNano())
+
+	leaseA, res, err := limiterA.Acquire(ctx, key)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected first lease, got %v, err %v", res.Allowed, err)
+	}
+	if _, res, err = limiterB.Acquire(ctx, key); err != nil || !res.Allowed {
+		t.Fatalf("expected second lease, got %v, err %v", res.Allowed, err)
+	}
+	if _, res, err = limiterB.Acquire(ctx, key); err != nil || res.Allowed {
+		t.Fatalf("expected third lease to be denied, got %v, err %v", res.Allowed, err)
+	}
+
+	if err := limiterB.Release(ctx, leaseA); err != nil {
+		t.Fatalf("unexpected release error: %v", err)
+	}
+	if _, res, err = limiterB.Acquire(ctx, key); err != nil || !res.Allowed {
+		t.Fatalf("expected released slot to be reusable, got %v, err %v", res.Allowed, err)
+	}
+}
+
+func TestRedisAtomicAlgorithms_CompositeAllOrNothing(t *testing.T) {
+	strategies := []struct {
+		name        string
+		constructor func(core.Config, storage.Storage) core.Limiter
+	}{
+		{"FixedWindow", algorithms.NewFixedWindowLimiter},
+		{"SlidingWindow", algorithms.NewSlidingWindowLimiter},
+		{"TokenBucket", algorithms.NewTokenBucketLimiter},
+		{"LeakyBucket", algorithms.NewLeakyBucketLimiter},
+		{"GCRA", algorithms.NewGCRALimiter},
+	}
+
+	for _, strategy := range strategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			store := newRedisStoreForTest(t)
+			wide := strategy.constructor(core.Config{Limit: 10, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+			narrow := strategy.constructor(core.Config{Limit: 3, Window: time.Hour, Metrics: &core.NoopMetrics{}}, store)
+			limiter, err := algorithms.NewCompositeLimiter(core.Config{Metrics: &core.NoopMetrics{}}, store, []core.Limiter{wide, narrow})
+			if err != nil {
+				t.Fatalf("NewCompositeLimiter: %v", err)
+			}
+			defer limiter.Close()
+
+			ctx := context.Background()
+			key := fmt.Sprintf("composite-%s-%d", strategy.name, time.Now().UnixNano())
+			defer limiter.Reset(ctx, key)
+
+			for i := 0; i < 3; i++ {
+				if res, err := limiter.Allow(ctx, key); err != nil || !res.Allowed {
+					t.Fatalf("req %d: expected allowed, got %v, err %v", i+1, res.Allowed, err)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				res, err := limiter.Allow(ctx, key)
+				if err != nil || res.Allowed {
+					t.Fatalf("expected denied, got %v, err %v", res.Allowed, err)
+				}
+				if res.Limit != 3 || res.RetryAfter <= 0 {
+					t.Fatalf("expected the narrow policy to be reported, got limit=%d retry_after=%v", res.Limit, res.RetryAfter)
+				}
+			}
+
+			res, err := wide.Peek(ctx, "{"+key+"}#0")
+			if err != nil {
+				t.Fatalf("peek: %v", err)
+			}
+			if res.Remaining != 7 {
+				t.Fatalf("expected denials to leave the wide policy uncharged, remaining=%d", res.Remaining)
+			}
+		})
+	}
+}
+
+// TestRedisAtomicAlgorithms_CompositeMatchesStrategyScripts checks that the evaluators of the
+// composite script, which repeat the per-strategy scripts, decide and report exactly like them.
+func TestRedisAtomicAlgorithms_CompositeMatchesStrategyScripts(t *testing.T) {
+	strategies := []struct {
+		name        string
+		constructor func(core.Config, storage.Storage) core.Limiter
+	}{
+		{"FixedWindow", algorithms.NewFixedWindowLimiter},
+		{"SlidingWindow", algorithms.NewSlidingWindowLimiter},
+		{"TokenBucket", algorithms.NewTokenBucketLimiter},
+		{"LeakyBucket", algorithms.NewLeakyBucketLimiter},
+		{"GCRA", algorithms.NewGCRALimiter},
+	}
+	costs := []int{1, 2, 1, 3, 1, 1, 2, 1, 1, 4, 1, 1}
+
+	for _, strategy := range strategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			store := newRedisStoreForTest(t)
+			clock := clocktest.NewManual(time.Now())
+			cfg := core.Config{Limit: 5, Window: 10 * time.Second, Metrics: &core.NoopMetrics{}, Clock: clock}
+			solo := strategy.constructor(cfg, store)
+			member := strategy.constructor(cfg, store)
+			composite, err := algorithms.NewCompositeLimiter(cfg, store, []core.Limiter{member})
+			if err != nil {
+				t.Fatalf("NewCompositeLimiter: %v", err)
+			}
+			defer composite.Close()
+
+			ctx := context.Background()
+			key := fmt.Sprintf("composite-match-%s-%d", strategy.name, time.Now().UnixNano())
+			defer solo.Reset(ctx, key)
+			defer composite.Reset(ctx, key)
+
+			for i, cost := range costs {
+				want, err := solo.AllowN(ctx, key, cost)
+				if err != nil {
+					t.Fatalf("req %d: strategy script: %v", i+1, err)
+				}
+				got, err := composite.AllowN(ctx, key, cost)
+				if err != nil {
+					t.Fatalf("req %d: composite script: %v", i+1, err)
+				}
+				if got != want {
+					t.Fatalf("req %d: composite result %+v differs from strategy result %+v", i+1, got, want)
+				}
+				clock.Advance(1300 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestRedisAtomicAlgorithms_HierarchicalAcrossInstances(t *testing.T) {
+	levels := []core.HierarchyLevel{
+		{Name: "tenant", Field: "tenant", Policy: core.ResourcePolicy{Limit: 3, Window: time.Minute}},
+		{Name: "user", Field: "user", Policy: core.ResourcePolicy{Limit: 2, Window: time.Minute}},
+	}
+	newInstance := func() core.HierarchicalLimiter {
+		store := newRedisStoreForTest(t)
+		members := make([]core.Limiter, len(levels))
+		for i, level := range levels {
+			members[i] = algorithms.NewSlidingWindowLimiter(core.Config{
+				Limit: level.Policy.Limit, Window: level.Policy.Window, Metrics: &core.NoopMetrics{},
+			}, store)
+		}
+		limiter, err := algorithms.NewHierarchicalLimiter(core.Config{Metrics: &core.NoopMetrics{}}, store, levels, members)
+		if err != nil {
+			t.Fatalf("NewHierarchicalLimiter: %v", err)
+		}
+		return limiter
+	}
+	limiterA := newInstance()
+	defer limiterA.Close()
+	limiterB := newInstance()
+	defer limiterB.Close()
+
+	ctx := context.Background()
+	tenant := fmt.Sprintf("tenant-%d", time.Now().UnixNano())
+	alice := core.Descriptor{"tenant": tenant, "user": "alice"}
+	bob := core.Descriptor{"tenant": tenant, "user": "bob"}
+	defer limiterA.Reset(ctx, "tenant", alice)
+	defer limiterA.Reset(ctx, "user", alice)
+	defer limiterA.Reset(ctx, "user", bob)
+
+	if res, err := limiterA.AllowN(ctx, alice, 2); err != nil || !res.Allowed {
+		t.Fatalf("expected alice to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	res, err := limiterB.AllowN(ctx, bob, 2)
+	if err != nil || res.Allowed || res.Level != "tenant" {
+		t.Fatalf("expected tenant-level denial, got allowed=%v level=%q err=%v", res.Allowed, res.Level, err)
+	}
+	if res, err := limiterB.Allow(ctx, bob); err != nil || !res.Allowed {
+		t.Fatalf("expected the denied request to leave bob's level uncharged, got %v, err %v", res.Allowed, err)
+	}
+}
+
+func TestRedisAtomicAlgorithms_TokenBucketBurstAndRate(t *testing.T) {
+	cfg := core.Config{Burst: 5, Rate: 1, Metrics: &core.NoopMetrics{}}
+	limiterA := algorithms.NewTokenBucketLimiter(cfg, newRedisStoreForTest(t))
+	defer limiterA.Close()
+	limiterB := algorithms.NewTokenBucketLimiter(cfg, newRedisStoreForTest(t))
+	defer limiterB.Close()
+
+	ctx := context.Background()
+	key := fmt.Sprintf("token-bucket-burst-%d", time.Now().UnixNano())
+	defer limiterA.Reset(ctx, key)
+
+	if res, err := limiterA.AllowN(ctx, key, 3); err != nil || !res.Allowed || res.Limit != 5 {
+		t.Fatalf("expected allowed with limit=5, got %+v, err %v", res, err)
+	}
+	if res, err := limiterB.AllowN(ctx, key, 2); err != nil || !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected the rest of the burst on the second instance, got %+v, err %v", res, err)
+	}
+	res, err := limiterB.Allow(ctx, key)
+	if err != nil || res.Allowed {
+		t.Fatalf("expected denial once the burst is spent, got %v, err %v", res.Allowed, err)
+	}
+	if res.RetryAfter <= 900*time.Millisecond || res.RetryAfter > time.Second {
+		t.Fatalf("expected retry_after close to one second at 1/s, got %v", res.RetryAfter)
+	}
+}
+
+func TestRedisAtomicAlgorithms_AdaptiveLimitAcrossInstances(t *testing.T) {
+	cfg := core.AdaptiveConfig{
+		MinLimit: 1, MaxLimit: 40, Name: fmt.Sprintf("adaptive-%d", time.Now().UnixNano()),
+		Policy: core.ResourcePolicy{Limit: 10, Window: time.Minute},
+	}
+	newInstance := func() core.AdaptiveLimiter {
+		store := newRedisStoreForTest(t)
+		return algorithms.NewAdaptiveLimiter(cfg, store, func(limit int) core.Limiter {
+			return algorithms.NewFixedWindowLimiter(core.Config{Limit: limit, Window: time.Minute, Metrics: &core.NoopMetrics{}}, store)
+		})
+	}
+	limiterA := newInstance()
+	defer limiterA.Close()
+	limiterB := newInstance()
+	defer limiterB.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		limiter := limiterA
+		if i%2 == 1 {
+			limiter = limiterB
+		}
+		go func(l core.AdaptiveLimiter) {
+			defer wg.Done()
+			if err := l.Report(ctx, core.Outcome{}); err != nil {
+				t.Errorf("report: %v", err)
+			}
+		}(limiter)
+	}
+	wg.Wait()
+
+	if err := limiterA.Report(ctx, core.Outcome{Err: fmt.Errorf("overloaded")}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if got := limiterA.CurrentLimit(); got != 15 {
+		t.Fatalf("expected every report from both instances to count (10+20 halved), got %d", got)
+	}
+}
+
+func TestRedisAtomicAlgorithms_PenaltyBanSharedAcrossInstances(t *testing.T) {
+	cfg := core.Config{
+		Limit: 1, Window: time.Minute, Metrics: &core.NoopMetrics{},
+		Penalty: core.PenaltyPolicy{Threshold: 2, Period: time.Minute, BanDuration: 10 * time.Minute, Escalation: 2},
+	}
+	newInstance := func() core.Limiter {
+		store := newRedisStoreForTest(t)
+		return algorithms.NewPenaltyBox(algorithms.NewFixedWindowLimiter(cfg, store), cfg, store)
+	}
+	limiterA := newInstance()
+	defer limiterA.Close()
+	limiterB := newInstance()
+	defer limiterB.Close()
+
+	ctx := context.Background()
+	key := fmt.Sprintf("penalty-%d", time.Now().UnixNano())
+	defer limiterA.Reset(ctx, key)
+
+	limiterA.Allow(ctx, key)
+	limiterA.Allow(ctx, key)
+	res, err := limiterB.Allow(ctx, key)
+	if err != nil || res.Allowed || res.RetryAfter <= 9*time.Minute {
+		t.Fatalf("expected the second strike on another instance to start a 10m ban, got %+v, err %v", res, err)
+	}
+	if res, err := limiterA.Peek(ctx, key); err != nil || res.RetryAfter <= 9*time.Minute {
+		t.Fatalf("expected the ban to be visible on the first instance, got %+v, err %v", res, err)
+	}
+}
+
+func TestRedisAtomicAlgorithms_AllowMany(t *testing.T) {
+	strategies := []struct {
+		name        string
+		constructor func(core.Config, storage.Storage) core.Limiter
+	}{
+		{"FixedWindow", algorithms.NewFixedWindowLimiter},
+		{"SlidingWindow", algorithms.NewSlidingWindowLimiter},
+		{"TokenBucket", algorithms.NewTokenBucketLimiter},
+		{"LeakyBucket", algorithms.NewLeakyBucketLimiter},
+		{"SlidingLog", algorithms.NewSlidingLogLimiter},
+		{"GCRA", algorithms.NewGCRALimiter},
+		{"Concurrency", algorithms.NewConcurrencyLimiter},
+	}
+
+	for _, strategy := range strategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			store := newRedisStoreForTest(t)
+			limiter := strategy.constructor(core.Config{
+				Limit:   5,
+				Window:  time.Minute,
+				Metrics: &core.NoopMetrics{},
+			}, store)
+			defer limiter.Close()
+
+			hot := fmt.Sprintf("%s-batch-hot-%d", strategy.name, time.Now().UnixNano())
+			cold := fmt.Sprintf("%s-batch-cold-%d", strategy.name, time.Now().UnixNano())
+			var reqs []core.BatchRequest
+			for i := 0; i < 8; i++ {
+				reqs = append(reqs, core.BatchRequest{Key: hot, Cost: 1})
+			}
+			reqs = append(reqs, core.BatchRequest{Key: cold, Cost: 5}, core.BatchRequest{Key: cold, Cost: 0})
+
+			results := algorithms.AllowMany(context.Background(), limiter, reqs)
+			allowed := 0
+			for _, res := range results[:8] {
+				if res.Err != nil {
+					t.Fatalf("unexpected error: %v", res.Err)
+				}
+				if res.Allowed {
+					allowed++
+				}
+			}
+			if allowed != 5 {
+				t.Fatalf("expected exactly 5 allowed requests for the hot key, got %d", allowed)
+			}
+			if !results[8].Allowed || results[8].Err != nil {
+				t.Fatalf("expected the cold key allowed, got %+v", results[8])
+			}
+			if !errors.Is(results[9].Err, core.ErrInvalidCost) {
+				t.Fatalf("expected ErrInvalidCost, got %v", results[9].Err)
+			}
+		})
+	}
+}
+
+func TestRedisAtomicAlgorithms_PermitLeaseAcrossInstances(t *testing.T) {
+	cfg := core.Config{
+		Limit:       50,
+		Window:      time.Minute,
+		Metrics:     &core.NoopMetrics{},
+		PermitLease: core.PermitLeasePolicy{Batch: 5},
+	}
+	key := fmt.Sprintf("permit-lease-%d", time.Now().UnixNano())
+
+	var instances []core.Limiter
+	for i := 0; i < 4; i++ {
+		inner := algorithms.NewTokenBucketLimiter(cfg, newRedisStoreForTest(t))
+		instances = append(instances, algorithms.NewPermitLeaseLimiter(inner, cfg))
+	}
+
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 400; i++ {
+		wg.Add(1)
+		go func(l core.Limiter) {
+			defer wg.Done()
+			if res, err := l.Allow(context.Background(), key); err == nil && res.Allowed {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}(instances[i%len(instances)])
+	}
+	wg.Wait()
+	for _, l := range instances {
+		if err := l.Close(); err != nil {
+			t.Fatalf("unexpected close error: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&allowed); got > 50 {
+		t.Fatalf("expected at most 50 allowed requests across instances, got %d", got)
+	}
+}
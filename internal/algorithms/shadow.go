@@ -0,0 +1,142 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// ShadowLimiter wraps a limiter in shadow mode: the wrapped limiter decides and updates its state
+// as usual, but every decision is turned into an admission. Denials it would have made are
+// marked with Result.ShadowDenied and passed to the hook.
+type ShadowLimiter struct {
+	inner core.Limiter
+	hook  core.ShadowHook
+}
+
+// shadowLeaseLimiter keeps Acquire and Release available when the wrapped limiter hands out leases.
+type shadowLeaseLimiter struct {
+	*ShadowLimiter
+	leaser core.LeaseLimiter
+}
+
+// NewShadowLimiter wraps inner in shadow mode. hook may be nil. inner should be built with
+// ShadowMetrics, so that its would-be denials are not counted as denials. When inner is a
+// core.LeaseLimiter, so is the returned limiter.
+func NewShadowLimiter(inner core.Limiter, hook core.ShadowHook) core.Limiter {
+	s := &ShadowLimiter{inner: inner, hook: hook}
+	if leaser, ok := inner.(core.LeaseLimiter); ok {
+		return &shadowLeaseLimiter{ShadowLimiter: s, leaser: leaser}
+	}
+	return s
+}
+
+// Shadow returns the shadow mode equivalent of a decision: always allowed, with Shadow set and
+// ShadowDenied set when res was a denial.
+func Shadow(res core.Result) core.Result {
+	res.Shadow = true
+	if !res.Allowed {
+		res.Allowed = true
+		res.ShadowDenied = true
+	}
+	return res
+}
+
+// Allow checks a single request for key.
+func (s *ShadowLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return s.AllowN(ctx, key, 1)
+}
+
+// AllowN asks the wrapped limiter and admits the request whatever it decides. Errors are
+// returned unchanged, so FailOpen still applies to backend failures.
+func (s *ShadowLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	res, err := s.inner.AllowN(ctx, key, n)
+	if err != nil {
+		return res, err
+	}
+	return s.report(ctx, key, Shadow(res)), nil
+}
+
+// AllowMany passes the requests to the wrapped limiter in one batch and admits each of them
+// whatever it decides.
+func (s *ShadowLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	results := AllowMany(ctx, s.inner, reqs)
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Result = s.report(ctx, reqs[i].Key, Shadow(results[i].Result))
+		}
+	}
+	return results
+}
+
+// Peek reports the wrapped limiter's state in shadow mode, without calling the hook.
+func (s *ShadowLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	res, err := s.inner.Peek(ctx, key)
+	if err != nil {
+		return res, err
+	}
+	return Shadow(res), nil
+}
+
+// Refund gives n units back to key in the wrapped limiter.
+func (s *ShadowLimiter) Refund(ctx context.Context, key string, n int) error {
+	return s.inner.Refund(ctx, key, n)
+}
+
+// Reset forgets the state of key in the wrapped limiter.
+func (s *ShadowLimiter) Reset(ctx context.Context, key string) error {
+	return s.inner.Reset(ctx, key)
+}
+
+// Close closes the wrapped limiter.
+func (s *ShadowLimiter) Close() error {
+	return s.inner.Close()
+}
+
+func (s *ShadowLimiter) report(ctx context.Context, key string, res core.Result) core.Result {
+	if res.ShadowDenied && s.hook != nil {
+		s.hook(ctx, "", key, res)
+	}
+	return res
+}
+
+// Acquire takes a slot from the wrapped limiter. When none is free the request is admitted
+// anyway with the zero Lease, so nothing has to be released.
+func (s *shadowLeaseLimiter) Acquire(ctx context.Context, key string) (core.Lease, core.Result, error) {
+	lease, res, err := s.leaser.Acquire(ctx, key)
+	if err != nil {
+		return lease, res, err
+	}
+	return lease, s.report(ctx, key, Shadow(res)), nil
+}
+
+// Release gives a slot back to the wrapped limiter.
+func (s *shadowLeaseLimiter) Release(ctx context.Context, lease core.Lease) error {
+	return s.leaser.Release(ctx, lease)
+}
+
+// shadowMetrics counts denials as allowed requests, since shadow mode admits them, and reports
+// them to collectors implementing core.ShadowObserver.
+type shadowMetrics struct {
+	core.MetricsCollector
+}
+
+// ShadowMetrics adapts m for a limiter running in shadow mode.
+func ShadowMetrics(m core.MetricsCollector) core.MetricsCollector {
+	return shadowMetrics{MetricsCollector: m}
+}
+
+func (m shadowMetrics) IncDeny() {
+	m.MetricsCollector.IncAllow()
+	if observer, ok := m.MetricsCollector.(core.ShadowObserver); ok {
+		observer.IncShadowDeny()
+	}
+}
+
+// ObserveLimit passes the limit on to collectors implementing core.LimitObserver.
+func (m shadowMetrics) ObserveLimit(limit int) {
+	if observer, ok := m.MetricsCollector.(core.LimitObserver); ok {
+		observer.ObserveLimit(limit)
+	}
+}
@@ -0,0 +1,60 @@
+package algorithms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+// shadowMockMetrics records would-be denials next to the regular counters.
+type shadowMockMetrics struct {
+	mockMetrics
+	shadowDenies int
+}
+
+func (m *shadowMockMetrics) IncShadowDeny() { m.shadowDenies++ }
+
+// TestShadowLimiter_AllowsAndReports checks that shadow mode admits every request, keeps counting
+// against the limit and reports would-be denials to the hook and the metrics collector.
+func TestShadowLimiter_AllowsAndReports(t *testing.T) {
+	metrics := &shadowMockMetrics{}
+	store := inmem.NewInMemoryStore()
+	inner := NewFixedWindowLimiter(core.Config{Limit: 2, Window: time.Minute, Metrics: ShadowMetrics(metrics)}, store)
+
+	var hooked []string
+	limiter := NewShadowLimiter(inner, func(_ context.Context, resource, key string, res core.Result) {
+		if resource != "" || !res.ShadowDenied {
+			t.Errorf("unexpected hook call for %q: %+v", resource, res)
+		}
+		hooked = append(hooked, key)
+	})
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		res, err := limiter.Allow(ctx, "k")
+		if err != nil || !res.Allowed || !res.Shadow {
+			t.Fatalf("req %d: expected a shadow admission, got %+v, err %v", i+1, res, err)
+		}
+		if res.ShadowDenied != (i >= 2) {
+			t.Fatalf("req %d: expected ShadowDenied=%v, got %+v", i+1, i >= 2, res)
+		}
+	}
+	if len(hooked) != 2 {
+		t.Fatalf("expected 2 hook calls, got %d", len(hooked))
+	}
+	if metrics.allows != 4 || metrics.denies != 0 || metrics.shadowDenies != 2 {
+		t.Fatalf("expected 4 allows and 2 shadow denials, got %+v", *metrics)
+	}
+
+	res, err := limiter.Peek(ctx, "k")
+	if err != nil || !res.ShadowDenied || res.Remaining != 0 {
+		t.Fatalf("expected Peek to show the would-be denial, got %+v, err %v", res, err)
+	}
+	if len(hooked) != 2 {
+		t.Fatal("expected Peek not to call the hook")
+	}
+}
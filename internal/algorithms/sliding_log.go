@@ -0,0 +1,301 @@
+// Package algorithms implements various rate limiting algorithms.
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+)
+
+// SlidingLogLimiter counts every request in the trailing window exactly by keeping its timestamp.
+// On Redis, timestamps live in a sorted set trimmed by a Lua script. Other backends cannot store
+// a log through storage.Storage, so each key then gets a ring buffer of at most limit entries.
+type SlidingLogLimiter struct {
+	limit    int
+	window   time.Duration
+	store    storage.Storage
+	prefix   string
+	mu       sync.Mutex
+	logs     map[string]*requestLog
+	metrics  core.MetricsCollector
+	clock    core.Clock
+	failOpen bool
+}
+
+// requestLog is a ring buffer of request timestamps in arrival order.
+type requestLog struct {
+	entries []int64
+	head    int
+	size    int
+}
+
+// at returns the i-th oldest timestamp in the log.
+func (r *requestLog) at(i int) int64 {
+	return r.entries[(r.head+i)%len(r.entries)]
+}
+
+// NewSlidingLogLimiter constructs a new SlidingLogLimiter.
+func NewSlidingLogLimiter(cfg core.Config, store storage.Storage) core.Limiter {
+	return &SlidingLogLimiter{
+		limit:    cfg.Limit,
+		window:   cfg.Window,
+		store:    store,
+		prefix:   "gorl:sl",
+		logs:     make(map[string]*requestLog),
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+		failOpen: cfg.FailOpen,
+	}
+}
+
+// setLimit resizes the ring buffers to limit, keeping the newest entries that still fit.
+func (s *SlidingLogLimiter) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	for _, log := range s.logs {
+		keep := min(log.size, limit)
+		entries := make([]int64, limit)
+		for i := 0; i < keep; i++ {
+			entries[i] = log.at(log.size - keep + i)
+		}
+		log.entries, log.head, log.size = entries, 0, keep
+	}
+}
+
+// Allow records the request if fewer than limit requests happened in the trailing window.
+func (s *SlidingLogLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return s.AllowN(ctx, key, 1)
+}
+
+// AllowN records n requests if all of them fit in the trailing window.
+func (s *SlidingLogLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := s.store.(redisScriptRunner)
+	return s.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (s *SlidingLogLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, s.store, reqs, s.allow)
+}
+
+func (s *SlidingLogLimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	if err := validateCost(n, s.limit); err != nil {
+		return core.Result{Limit: s.limit}, err
+	}
+
+	start := time.Now()
+	if runner != nil {
+		return s.allowRedis(ctx, start, runner, key, n)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.allowGeneric(start, key, n)
+}
+
+func (s *SlidingLogLimiter) allowGeneric(start time.Time, key string, n int) (core.Result, error) {
+	now := s.clock.Now().UnixNano()
+	s.sweep(now)
+	log := s.trim(key, now)
+
+	allowed := log.size+n <= s.limit
+	if allowed {
+		for i := 0; i < n; i++ {
+			log.entries[(log.head+log.size)%len(log.entries)] = now
+			log.size++
+		}
+		s.logs[key] = log
+	}
+
+	reset, wait := s.timing(log, now, n)
+	res := core.Result{
+		Allowed:   allowed,
+		Limit:     s.limit,
+		Remaining: s.limit - log.size,
+		Reset:     reset,
+	}
+
+	s.metrics.ObserveLatency(time.Since(start))
+	if allowed {
+		s.metrics.IncAllow()
+	} else {
+		s.metrics.IncDeny()
+		res.RetryAfter = wait
+	}
+
+	return res, nil
+}
+
+func (s *SlidingLogLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptSlidingLog,
+		s.storageKeys(key),
+		int64(s.limit),
+		s.clock.Now().UnixMicro(),
+		durationToMicros(s.window),
+		durationToMilliseconds(s.window),
+		int64(n),
+	)
+	if res, retErr, done := failOpenHandler(start, err, s.failOpen, s.metrics, s.limit); done {
+		return res, retErr
+	}
+
+	res, err := buildRedisScriptResult(s.limit, values)
+	if res2, retErr, done := failOpenHandler(start, err, s.failOpen, s.metrics, s.limit); done {
+		return res2, retErr
+	}
+
+	s.metrics.ObserveLatency(time.Since(start))
+	if res.Allowed {
+		s.metrics.IncAllow()
+	} else {
+		s.metrics.IncDeny()
+	}
+
+	return res, nil
+}
+
+// Peek reports how many requests key made in the trailing window without recording one.
+// Allowed tells whether a single request would currently be permitted.
+func (s *SlidingLogLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if runner, ok := s.store.(redisScriptRunner); ok {
+		values, err := runner.EvalScript(
+			ctx,
+			redisScriptSlidingLogPeek,
+			s.storageKeys(key)[:1],
+			int64(s.limit),
+			s.clock.Now().UnixMicro(),
+			durationToMicros(s.window),
+		)
+		if err != nil {
+			return core.Result{Limit: s.limit}, err
+		}
+		return buildRedisScriptResult(s.limit, values)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now().UnixNano()
+	log := s.trim(key, now)
+	reset, wait := s.timing(log, now, 1)
+
+	res := core.Result{
+		Allowed:   log.size < s.limit,
+		Limit:     s.limit,
+		Remaining: s.limit - log.size,
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = wait
+	}
+	return res, nil
+}
+
+// Refund removes the n most recent entries from the log for key.
+func (s *SlidingLogLimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	if runner, ok := s.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(
+			ctx,
+			redisScriptSlidingLogRefund,
+			s.storageKeys(key)[:1],
+			s.clock.Now().UnixMicro(),
+			durationToMicros(s.window),
+			int64(n),
+		)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log := s.trim(key, s.clock.Now().UnixNano())
+	if n >= log.size {
+		delete(s.logs, key)
+		return nil
+	}
+	log.size -= n
+	return nil
+}
+
+// Reset deletes the request log stored for key.
+func (s *SlidingLogLimiter) Reset(ctx context.Context, key string) error {
+	if _, ok := s.store.(redisScriptRunner); ok {
+		return s.store.Delete(ctx, s.storageKeys(key)...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, key)
+	return nil
+}
+
+// Close releases resources held by the limiter.
+func (s *SlidingLogLimiter) Close() error {
+	return s.store.Close()
+}
+
+// trim drops entries that left the window and returns the log for key. Callers must hold s.mu.
+// Logs that become empty are forgotten; the returned log is only stored again by the caller.
+func (s *SlidingLogLimiter) trim(key string, now int64) *requestLog {
+	log, ok := s.logs[key]
+	if !ok {
+		return &requestLog{entries: make([]int64, s.limit)}
+	}
+
+	cutoff := now - s.window.Nanoseconds()
+	for log.size > 0 && log.at(0) <= cutoff {
+		log.head = (log.head + 1) % len(log.entries)
+		log.size--
+	}
+	if log.size == 0 {
+		delete(s.logs, key)
+	}
+	return log
+}
+
+// sweep forgets up to sweepBatch logs whose newest entry has left the window, so keys that stop
+// sending requests do not stay in memory. Callers must hold s.mu.
+func (s *SlidingLogLimiter) sweep(now int64) {
+	cutoff := now - s.window.Nanoseconds()
+	checked := 0
+	for key, log := range s.logs {
+		if checked == sweepBatch {
+			return
+		}
+		checked++
+		if log.size == 0 || log.at(log.size-1) <= cutoff {
+			delete(s.logs, key)
+		}
+	}
+}
+
+// timing returns how long until every entry has left the window and how long until cost more fit,
+// both measured from the entries that are still in the window.
+func (s *SlidingLogLimiter) timing(log *requestLog, now int64, cost int) (time.Duration, time.Duration) {
+	window := s.window.Nanoseconds()
+	reset := time.Duration(0)
+	if log.size > 0 {
+		reset = clampDuration(time.Duration(log.at(log.size-1) + window - now))
+	}
+	wait := time.Duration(0)
+	if needed := log.size + cost - s.limit; needed > 0 {
+		wait = clampDuration(time.Duration(log.at(needed-1) + window - now))
+	}
+	return reset, wait
+}
+
+func (s *SlidingLogLimiter) storageKeys(key string) []string {
+	return []string{
+		fmt.Sprintf("%s:{%s}:log", s.prefix, key),
+		fmt.Sprintf("%s:{%s}:seq", s.prefix, key),
+	}
+}
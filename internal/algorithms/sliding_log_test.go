@@ -0,0 +1,128 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
+	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
+)
+
+func newTestSlidingLogLimiter(limit int, window time.Duration) *SlidingLogLimiter {
+	return NewSlidingLogLimiter(core.Config{
+		Limit: limit, Window: window, Metrics: &core.NoopMetrics{},
+	}, inmem.NewInMemoryStore()).(*SlidingLogLimiter)
+}
+
+// TestSlidingLog_ExactTrailingCount verifies that only requests inside the trailing window count,
+// with no interpolation across window boundaries.
+func TestSlidingLog_ExactTrailingCount(t *testing.T) {
+	limiter := newTestSlidingLogLimiter(3, 200*time.Millisecond)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "login")
+	time.Sleep(120 * time.Millisecond)
+	limiter.Allow(ctx, "login")
+	limiter.Allow(ctx, "login")
+
+	if res, _ := limiter.Allow(ctx, "login"); res.Allowed {
+		t.Fatal("expected fourth attempt inside the window to be denied")
+	}
+
+	// The first attempt leaves the window ~80ms later; the other two are still inside it.
+	time.Sleep(100 * time.Millisecond)
+	res, _ := limiter.Allow(ctx, "login")
+	if !res.Allowed {
+		t.Fatal("expected attempt to be allowed once the oldest entry left the window")
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected remaining=0 with three attempts in the window, got %d", res.Remaining)
+	}
+}
+
+// TestSlidingLog_RetryAfterFromOldestEntry checks that metadata is derived from the logged timestamps.
+func TestSlidingLog_RetryAfterFromOldestEntry(t *testing.T) {
+	limiter := newTestSlidingLogLimiter(2, time.Second)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "login")
+	time.Sleep(100 * time.Millisecond)
+	limiter.Allow(ctx, "login")
+
+	denied, err := limiter.Allow(ctx, "login")
+	if err != nil || denied.Allowed {
+		t.Fatalf("expected denied, got %v, err %v", denied.Allowed, err)
+	}
+	// The oldest entry is ~100ms old, so it leaves the window in ~900ms.
+	if denied.RetryAfter <= 800*time.Millisecond || denied.RetryAfter > 900*time.Millisecond {
+		t.Fatalf("expected retry_after from the oldest entry, got %v", denied.RetryAfter)
+	}
+	// The newest entry leaves the window a full second after it was logged.
+	if denied.Reset <= 900*time.Millisecond || denied.Reset > time.Second {
+		t.Fatalf("expected reset from the newest entry, got %v", denied.Reset)
+	}
+
+	if _, err := limiter.AllowN(ctx, "login", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	peek, _ := limiter.Peek(ctx, "login")
+	if peek.Remaining != 0 {
+		t.Fatalf("expected denied AllowN to leave the log untouched, got remaining=%d", peek.Remaining)
+	}
+}
+
+// TestSlidingLog_RingBufferIsBounded ensures the in-memory log never grows beyond the limit
+// and is dropped once every entry has left the window.
+func TestSlidingLog_RingBufferIsBounded(t *testing.T) {
+	limiter := newTestSlidingLogLimiter(3, 50*time.Millisecond)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		limiter.Allow(ctx, "login")
+	}
+	if got := len(limiter.logs["login"].entries); got != 3 {
+		t.Fatalf("expected ring buffer capacity 3, got %d", got)
+	}
+
+	time.Sleep(70 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if res, _ := limiter.Allow(ctx, "login"); !res.Allowed {
+			t.Fatalf("req %d: expected wrapped ring buffer to admit a fresh window", i+1)
+		}
+	}
+
+	time.Sleep(70 * time.Millisecond)
+	limiter.Peek(ctx, "login")
+	if _, ok := limiter.logs["login"]; ok {
+		t.Fatal("expected expired log to be forgotten")
+	}
+}
+
+// TestSlidingLog_ForgetsIdleKeys checks that logs of keys that stopped sending requests are
+// dropped by later requests for other keys.
+func TestSlidingLog_ForgetsIdleKeys(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewSlidingLogLimiter(core.Config{
+		Limit: 5, Window: time.Second, Metrics: &core.NoopMetrics{}, Clock: clock,
+	}, inmem.NewInMemoryStoreWithClock(clock)).(*SlidingLogLimiter)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		limiter.Allow(ctx, fmt.Sprintf("idle-%d", i))
+	}
+	clock.Advance(2 * time.Second)
+	for i := 0; i < 100; i++ {
+		limiter.Allow(ctx, "active")
+	}
+
+	if len(limiter.logs) != 1 {
+		t.Fatalf("expected only the active key to be tracked, got %d keys", len(limiter.logs))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
@@ -19,8 +20,11 @@ type SlidingWindowLimiter struct {
 	stateTTL time.Duration
 	store    storage.Storage
 	prefix   string
+	mu       sync.Mutex
 	metrics  core.MetricsCollector
+	clock    core.Clock
 	failOpen bool
+	tagged   bool // keys come from a CompositeLimiter and already carry its hash tag
 }
 
 // NewSlidingWindowLimiter constructs a new SlidingWindowLimiter.
@@ -32,26 +36,51 @@ func NewSlidingWindowLimiter(cfg core.Config, store storage.Storage) core.Limite
 		store:    store,
 		prefix:   "gorl:sw",
 		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
 		failOpen: cfg.FailOpen,
 	}
 }
 
+func (s *SlidingWindowLimiter) setLimit(limit int) {
+	s.limit = limit
+}
+
 // Allow checks whether a request is allowed under a sliding window.
 func (s *SlidingWindowLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return s.AllowN(ctx, key, 1)
+}
+
+// AllowN checks whether a request costing n units is allowed under a sliding window.
+func (s *SlidingWindowLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := s.store.(redisScriptRunner)
+	return s.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (s *SlidingWindowLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, s.store, reqs, s.allow)
+}
+
+func (s *SlidingWindowLimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	if err := validateCost(n, s.limit); err != nil {
+		return core.Result{Limit: s.limit}, err
+	}
+
 	start := time.Now()
-	if runner, ok := s.store.(redisScriptRunner); ok {
-		return s.allowRedis(ctx, start, runner, key)
+	if runner != nil {
+		return s.allowRedis(ctx, start, runner, key, n)
 	}
 
-	return s.allowGeneric(ctx, start, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.allowGeneric(ctx, start, key, n)
 }
 
-func (s *SlidingWindowLimiter) allowGeneric(ctx context.Context, start time.Time, key string) (core.Result, error) {
-	now := time.Now().UnixNano()
+func (s *SlidingWindowLimiter) allowGeneric(ctx context.Context, start time.Time, key string, n int) (core.Result, error) {
+	now := s.clock.Now().UnixNano()
 
-	tsKey := fmt.Sprintf("%s:{%s}:ts", s.prefix, key)
-	currKey := fmt.Sprintf("%s:{%s}:curr", s.prefix, key)
-	prevKey := fmt.Sprintf("%s:{%s}:prev", s.prefix, key)
+	keys := s.storageKeys(key)
+	tsKey, currKey, prevKey := keys[0], keys[1], keys[2]
 
 	// Load last window start
 	tsVal, err := s.store.Get(ctx, tsKey)
@@ -128,18 +157,21 @@ func (s *SlidingWindowLimiter) allowGeneric(ctx context.Context, start time.Time
 	}
 
 	// Approximate total in sliding window before handling the current request.
+	// A request costing n fits when all but its last unit keep the window strictly under the limit,
+	// which reduces to the plain slidingCount < limit check for single-unit requests.
 	slidingCount := prevCount*(1-ratio) + currCount
-	allowed := slidingCount < float64(s.limit)
+	cost := float64(n)
+	allowed := slidingCount+cost-1 < float64(s.limit)
 	currCountAfter := currCount
 	slidingCountAfter := slidingCount
 
 	if allowed {
-		_, err := s.store.Incr(ctx, currKey, s.stateTTL)
+		_, err := s.store.IncrBy(ctx, currKey, cost, s.stateTTL)
 		if res, retErr, done := failOpenHandler(start, err, s.failOpen, s.metrics, s.limit); done {
 			return res, retErr
 		}
-		currCountAfter++
-		slidingCountAfter++
+		currCountAfter += cost
+		slidingCountAfter += cost
 	}
 
 	s.metrics.ObserveLatency(time.Since(start))
@@ -149,64 +181,32 @@ func (s *SlidingWindowLimiter) allowGeneric(ctx context.Context, start time.Time
 		remaining = 0
 	}
 
-	windowUntilBoundary := clampDuration(time.Duration(int64(s.window)-since) * time.Nanosecond)
-	reset := time.Duration(0)
-	switch {
-	case currCountAfter > 0:
-		reset = clampDuration(time.Duration(2*int64(s.window)-since) * time.Nanosecond)
-	case prevCount > 0:
-		reset = windowUntilBoundary
-	}
-
 	res := core.Result{
 		Allowed:   allowed,
 		Limit:     s.limit,
 		Remaining: remaining,
-		Reset:     reset,
+		Reset:     s.reset(since, prevCount, currCountAfter),
 	}
 
 	if allowed {
 		s.metrics.IncAllow()
 	} else {
 		s.metrics.IncDeny()
-		switch {
-		case currCount >= float64(s.limit):
-			res.RetryAfter = windowUntilBoundary
-		case prevCount > 0:
-			requiredRatio := 1 - (float64(s.limit)-currCount)/prevCount
-			delayRatio := requiredRatio - ratio
-			if delayRatio < 0 {
-				delayRatio = 0
-			}
-			res.RetryAfter = clampDuration(time.Duration(math.Ceil(delayRatio*float64(s.window.Nanoseconds()))) * time.Nanosecond)
-			if res.RetryAfter == 0 {
-				res.RetryAfter = time.Nanosecond
-			}
-			if res.RetryAfter > windowUntilBoundary {
-				res.RetryAfter = windowUntilBoundary
-			}
-		default:
-			res.RetryAfter = windowUntilBoundary
-		}
+		res.RetryAfter = s.retryAfter(since, prevCount, currCount, cost)
 	}
 	return res, nil
 }
 
-func (s *SlidingWindowLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string) (core.Result, error) {
-	keys := []string{
-		fmt.Sprintf("%s:{%s}:ts", s.prefix, key),
-		fmt.Sprintf("%s:{%s}:curr", s.prefix, key),
-		fmt.Sprintf("%s:{%s}:prev", s.prefix, key),
-	}
-
+func (s *SlidingWindowLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string, n int) (core.Result, error) {
 	values, err := runner.EvalScript(
 		ctx,
 		redisScriptSlidingWindow,
-		keys,
+		s.storageKeys(key),
 		int64(s.limit),
-		time.Now().UnixMicro(),
+		s.clock.Now().UnixMicro(),
 		durationToMicros(s.window),
 		durationToMilliseconds(s.stateTTL),
+		int64(n),
 	)
 	if res, retErr, done := failOpenHandler(start, err, s.failOpen, s.metrics, s.limit); done {
 		return res, retErr
@@ -227,6 +227,204 @@ func (s *SlidingWindowLimiter) allowRedis(ctx context.Context, start time.Time,
 	return res, nil
 }
 
+// Peek reports the sliding window state for key without counting a request.
+// Allowed tells whether a single-unit request would currently succeed.
+func (s *SlidingWindowLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if runner, ok := s.store.(redisScriptRunner); ok {
+		return s.peekRedis(ctx, runner, key)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peekGeneric(ctx, key)
+}
+
+func (s *SlidingWindowLimiter) peekGeneric(ctx context.Context, key string) (core.Result, error) {
+	now := s.clock.Now().UnixNano()
+	keys := s.storageKeys(key)
+
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		val, err := s.store.Get(ctx, k)
+		if err != nil {
+			return core.Result{Limit: s.limit}, err
+		}
+		values[i] = val
+	}
+
+	windowStart, currCount, prevCount := s.advance(int64(values[0]), values[1], values[2], now)
+	since := now - windowStart
+	ratio := float64(since) / float64(s.window)
+	slidingCount := prevCount*(1-ratio) + currCount
+
+	remaining := int(float64(s.limit) - slidingCount)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	res := core.Result{
+		Allowed:   slidingCount < float64(s.limit),
+		Limit:     s.limit,
+		Remaining: remaining,
+		Reset:     s.reset(since, prevCount, currCount),
+	}
+	if !res.Allowed {
+		res.RetryAfter = s.retryAfter(since, prevCount, currCount, 1)
+	}
+	return res, nil
+}
+
+func (s *SlidingWindowLimiter) peekRedis(ctx context.Context, runner redisScriptRunner, key string) (core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptSlidingWindowPeek,
+		s.storageKeys(key),
+		int64(s.limit),
+		s.clock.Now().UnixMicro(),
+		durationToMicros(s.window),
+	)
+	if err != nil {
+		return core.Result{Limit: s.limit}, err
+	}
+	return buildRedisScriptResult(s.limit, values)
+}
+
+// advance rolls the stored window forward to now without touching storage.
+// A zero windowStart means the key has no state yet.
+func (s *SlidingWindowLimiter) advance(windowStart int64, currCount, prevCount float64, now int64) (int64, float64, float64) {
+	if windowStart == 0 {
+		return now, 0, 0
+	}
+
+	elapsed := now - windowStart
+	if elapsed < int64(s.window) {
+		return windowStart, currCount, prevCount
+	}
+
+	intervals := elapsed / int64(s.window)
+	nextPrevCount := 0.0
+	if intervals == 1 {
+		nextPrevCount = currCount
+	}
+	return windowStart + intervals*int64(s.window), 0, nextPrevCount
+}
+
+// reset returns how long until both windows have drained, given the counts after the current decision.
+func (s *SlidingWindowLimiter) reset(since int64, prevCount, currCount float64) time.Duration {
+	switch {
+	case currCount > 0:
+		return clampDuration(time.Duration(2*int64(s.window)-since) * time.Nanosecond)
+	case prevCount > 0:
+		return s.untilBoundary(since)
+	}
+	return 0
+}
+
+// retryAfter returns the earliest delay after which a request costing cost could fit.
+func (s *SlidingWindowLimiter) retryAfter(since int64, prevCount, currCount, cost float64) time.Duration {
+	windowUntilBoundary := s.untilBoundary(since)
+	if currCount+cost-1 >= float64(s.limit) || prevCount <= 0 {
+		return windowUntilBoundary
+	}
+
+	ratio := float64(since) / float64(s.window)
+	requiredRatio := 1 - (float64(s.limit)-currCount-cost+1)/prevCount
+	delayRatio := requiredRatio - ratio
+	if delayRatio < 0 {
+		delayRatio = 0
+	}
+	retryAfter := clampDuration(time.Duration(math.Ceil(delayRatio*float64(s.window.Nanoseconds()))) * time.Nanosecond)
+	if retryAfter == 0 {
+		retryAfter = time.Nanosecond
+	}
+	if retryAfter > windowUntilBoundary {
+		retryAfter = windowUntilBoundary
+	}
+	return retryAfter
+}
+
+func (s *SlidingWindowLimiter) untilBoundary(since int64) time.Duration {
+	return clampDuration(time.Duration(int64(s.window)-since) * time.Nanosecond)
+}
+
+func (s *SlidingWindowLimiter) useTaggedKeys() {
+	s.tagged = true
+}
+
+func (s *SlidingWindowLimiter) storageKeys(key string) []string {
+	tag := key
+	if !s.tagged {
+		tag = hashTag(key)
+	}
+	return []string{
+		fmt.Sprintf("%s:%s:ts", s.prefix, tag),
+		fmt.Sprintf("%s:%s:curr", s.prefix, tag),
+		fmt.Sprintf("%s:%s:prev", s.prefix, tag),
+	}
+}
+
+// Refund gives back n units counted for key. The window may have rolled over since the units
+// were counted, so they come off the current window first and off the previous one after that;
+// once two windows have passed there is nothing left to give back. Counters never drop below zero.
+func (s *SlidingWindowLimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	keys := s.storageKeys(key)
+	if runner, ok := s.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(
+			ctx,
+			redisScriptSlidingWindowRefund,
+			keys,
+			s.clock.Now().UnixMicro(),
+			durationToMicros(s.window),
+			durationToMilliseconds(s.stateTTL),
+			int64(n),
+		)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refundGeneric(ctx, keys, n)
+}
+
+func (s *SlidingWindowLimiter) refundGeneric(ctx context.Context, keys []string, n int) error {
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		val, err := s.store.Get(ctx, k)
+		if err != nil {
+			return err
+		}
+		values[i] = val
+	}
+	if values[0] == 0 {
+		return nil
+	}
+
+	windowStart, currCount, prevCount := s.advance(int64(values[0]), values[1], values[2], s.clock.Now().UnixNano())
+	cost := float64(n)
+	fromCurr := math.Min(cost, currCount)
+	fromPrev := math.Min(cost-fromCurr, prevCount)
+	if windowStart == int64(values[0]) && fromCurr+fromPrev == 0 {
+		return nil
+	}
+
+	state := []float64{float64(windowStart), currCount - fromCurr, prevCount - fromPrev}
+	for i, k := range keys {
+		if err := s.store.Set(ctx, k, state[i], s.stateTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset deletes the window timestamp and both counters stored for key.
+func (s *SlidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, s.storageKeys(key)...)
+}
+
 // Close releases resources held by the limiter.
 func (s *SlidingWindowLimiter) Close() error {
 	return s.store.Close()
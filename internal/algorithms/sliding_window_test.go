@@ -3,9 +3,11 @@ package algorithms
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
 )
@@ -32,6 +34,30 @@ func TestSlidingWindow_Basic(t *testing.T) {
 	}
 }
 
+// TestSlidingWindow_ManualClock checks the weighting of the previous window at a controlled instant.
+func TestSlidingWindow_ManualClock(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	limiter := NewSlidingWindowLimiter(core.Config{
+		Limit: 4, Window: time.Minute, Metrics: &core.NoopMetrics{}, Clock: clock,
+	}, store)
+	ctx := context.Background()
+
+	limiter.AllowN(ctx, "k", 4)
+
+	// Halfway through the next window, half of the previous window still counts.
+	clock.Advance(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res, _ := limiter.Allow(ctx, "k"); !res.Allowed {
+			t.Fatalf("req %d: expected allowed with the previous window weighted at one half", i+1)
+		}
+	}
+	if res, _ := limiter.Allow(ctx, "k"); res.Allowed {
+		t.Fatal("expected denied once the weighted count reaches the limit")
+	}
+}
+
 // TestSlidingWindow_WindowSlide verifies that the window slides correctly,
 // allowing expired requests to fall off and new requests to be accepted.
 func TestSlidingWindow_WindowSlide(t *testing.T) {
@@ -519,6 +545,91 @@ func TestSlidingWindow_Close(t *testing.T) {
 	}
 }
 
+// TestSlidingWindow_AllowN verifies that weighted requests consume their full cost and that a
+// denied request leaves the remaining capacity untouched.
+func TestSlidingWindow_AllowN(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewSlidingWindowLimiter(core.Config{
+		Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.AllowN(ctx, "bulk", 3)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 3 to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 2 {
+		t.Fatalf("expected remaining=2, got %d", res.Remaining)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected cost 3 to be denied with 2 units left")
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 2)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 2 to fit the remaining capacity, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected remaining=0, got %d", res.Remaining)
+	}
+}
+
+// TestSlidingWindow_PeekDoesNotConsume verifies that Peek reports the current state without
+// spending capacity or recording metrics.
+func TestSlidingWindow_PeekDoesNotConsume(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	m := &mockMetrics{}
+	limiter := NewSlidingWindowLimiter(core.Config{
+		Limit: 3, Window: time.Minute, Metrics: m,
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 3 {
+		t.Fatalf("expected fresh key to report full capacity, got %+v", res)
+	}
+
+	limiter.Allow(ctx, "dash")
+	limiter.Allow(ctx, "dash")
+	for i := 0; i < 2; i++ {
+		res, err = limiter.Peek(ctx, "dash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.Allowed || res.Remaining != 1 {
+			t.Fatalf("peek %d: expected remaining=1, got %+v", i+1, res)
+		}
+	}
+
+	limiter.Allow(ctx, "dash")
+	res, err = limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected exhausted state, got %+v", res)
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+	if m.allows != 3 || m.denies != 0 {
+		t.Fatalf("peek should not record decisions, got %d allows and %d denies", m.allows, m.denies)
+	}
+}
+
 func BenchmarkSlidingWindow_SingleKey(b *testing.B) {
 	b.ReportAllocs()
 	store := inmem.NewInMemoryStore()
@@ -547,3 +658,62 @@ func BenchmarkSlidingWindow_MultiKey(b *testing.B) {
 		limiter.Allow(ctx, key)
 	}
 }
+
+// TestSlidingWindow_RefundAfterRollover verifies that units counted before the window rolled
+// over are refunded from the previous window, and that nothing is credited once it has expired.
+func TestSlidingWindow_RefundAfterRollover(t *testing.T) {
+	clock := clocktest.NewManual(time.Unix(0, 0).Add(time.Hour))
+	store := inmem.NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	limiter := NewSlidingWindowLimiter(core.Config{
+		Limit: 10, Window: 10 * time.Second, Metrics: &core.NoopMetrics{}, Clock: clock,
+	}, store)
+	ctx := context.Background()
+
+	limiter.AllowN(ctx, "k", 6)
+	clock.Advance(10 * time.Second)
+	limiter.Allow(ctx, "k")
+	if err := limiter.Refund(ctx, "k", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res, _ := limiter.Peek(ctx, "k"); res.Remaining != 5 {
+		t.Fatalf("expected 1 unit refunded from each window, got %d remaining", res.Remaining)
+	}
+
+	limiter.Reset(ctx, "k")
+	limiter.AllowN(ctx, "k", 6)
+	clock.Advance(20 * time.Second)
+	if err := limiter.Refund(ctx, "k", 6); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	limiter.AllowN(ctx, "k", 4)
+	if res, _ := limiter.Peek(ctx, "k"); res.Remaining != 6 {
+		t.Fatalf("expected the expired units not credited, got %d remaining", res.Remaining)
+	}
+}
+
+// TestSlidingWindow_ConcurrentAllowAndRefund ensures a refund never overwrites a request counted
+// at the same time, so every refunded unit comes back exactly once.
+func TestSlidingWindow_ConcurrentAllowAndRefund(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewSlidingWindowLimiter(core.Config{
+		Limit: 10, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, _ := limiter.Allow(ctx, "c"); res.Allowed {
+				limiter.Refund(ctx, "c", 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if res, _ := limiter.Peek(ctx, "c"); res.Remaining != 10 {
+		t.Fatalf("expected every unit refunded, got %d remaining", res.Remaining)
+	}
+}
@@ -13,50 +13,98 @@ import (
 
 // TokenBucketLimiter implements the token bucket algorithm using a minimal Storage API (Get/Set only).
 // State is stored in two separate keys per user: tokens and last refill timestamp.
+// limit is the bucket capacity; state expires after ttl, the time an empty bucket takes to fill.
 type TokenBucketLimiter struct {
 	limit        int
-	window       time.Duration
+	ttl          time.Duration
 	store        storage.Storage
 	prefix       string
 	mu           sync.Mutex
 	metrics      core.MetricsCollector
+	clock        core.Clock
 	timePerToken int64
 	failOpen     bool
+	tagged       bool // keys come from a CompositeLimiter and already carry its hash tag
+	// Configured shape, kept so setLimit can derive the capacity and refill rate again
+	burst  int
+	rate   float64
+	window time.Duration
 }
 
 // NewTokenBucketLimiter constructs a new TokenBucketLimiter.
+// The bucket holds cfg.Burst tokens and refills at cfg.Rate tokens per second; either defaults
+// to Limit and Limit per Window when zero.
 func NewTokenBucketLimiter(cfg core.Config, store storage.Storage) core.Limiter {
-	tpt := cfg.Window.Nanoseconds() / int64(cfg.Limit)
+	t := &TokenBucketLimiter{
+		store:    store,
+		prefix:   "gorl:tb",
+		metrics:  cfg.Metrics,
+		clock:    clockOf(cfg),
+		failOpen: cfg.FailOpen,
+		burst:    cfg.Burst,
+		rate:     cfg.Rate,
+		window:   cfg.Window,
+	}
+	t.setLimit(cfg.Limit)
+	return t
+}
+
+// setLimit derives the capacity and refill rate from limit where Burst and Rate leave them to it.
+func (t *TokenBucketLimiter) setLimit(limit int) {
+	capacity := t.burst
+	if capacity <= 0 {
+		capacity = limit
+	}
+
+	var tpt int64
+	if t.rate > 0 {
+		tpt = int64(float64(time.Second) / t.rate)
+	} else {
+		tpt = t.window.Nanoseconds() / int64(limit)
+	}
 	if tpt <= 0 {
 		tpt = 1
 	}
-	return &TokenBucketLimiter{
-		limit:        cfg.Limit,
-		window:       cfg.Window,
-		store:        store,
-		prefix:       "gorl:tb",
-		metrics:      cfg.Metrics,
-		timePerToken: tpt,
-		failOpen:     cfg.FailOpen,
-	}
+	t.limit = capacity
+	t.ttl = time.Duration(int64(capacity) * tpt)
+	t.timePerToken = tpt
 }
 
 // Allow checks token availability and consumes one token if allowed.
 func (t *TokenBucketLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return t.AllowN(ctx, key, 1)
+}
+
+// AllowN checks token availability and consumes n tokens if all of them are available.
+func (t *TokenBucketLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	runner, _ := t.store.(redisScriptRunner)
+	return t.allow(ctx, runner, key, n)
+}
+
+// AllowMany checks every request, sending their scripts in one round trip with Redis.
+func (t *TokenBucketLimiter) AllowMany(ctx context.Context, reqs []core.BatchRequest) []core.BatchResult {
+	return allowBatch(ctx, t.store, reqs, t.allow)
+}
+
+func (t *TokenBucketLimiter) allow(ctx context.Context, runner redisScriptRunner, key string, n int) (core.Result, error) {
+	if err := validateCost(n, t.limit); err != nil {
+		return core.Result{Limit: t.limit}, err
+	}
+
 	start := time.Now()
-	if runner, ok := t.store.(redisScriptRunner); ok {
-		return t.allowRedis(ctx, start, runner, key)
+	if runner != nil {
+		return t.allowRedis(ctx, start, runner, key, n)
 	}
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	return t.allowGeneric(ctx, start, key)
+	return t.allowGeneric(ctx, start, key, n)
 }
 
-func (t *TokenBucketLimiter) allowGeneric(ctx context.Context, start time.Time, key string) (core.Result, error) {
-	now := time.Now().UnixNano()
-	tokensKey := fmt.Sprintf("%s:{%s}:tokens", t.prefix, key)
-	refillKey := fmt.Sprintf("%s:{%s}:refill", t.prefix, key)
+func (t *TokenBucketLimiter) allowGeneric(ctx context.Context, start time.Time, key string, n int) (core.Result, error) {
+	now := t.clock.Now().UnixNano()
+	keys := t.storageKeys(key)
+	tokensKey, refillKey := keys[0], keys[1]
 
 	// Load current token count
 	tokenVal, err := t.store.Get(ctx, tokensKey)
@@ -70,45 +118,23 @@ func (t *TokenBucketLimiter) allowGeneric(ctx context.Context, start time.Time,
 	if res, retErr, done := failOpenHandler(start, err, t.failOpen, t.metrics, t.limit); done {
 		return res, retErr
 	}
-	lastRefill := int64(lastRefillVal)
-
-	// Initialize on first request
-	if lastRefill == 0 {
-		tokens = int64(t.limit)
-		lastRefill = now
-	} else {
-		// Refill tokens based on elapsed time
-		elapsed := now - lastRefill
-		newTokens := elapsed / t.timePerToken
-		if newTokens > 0 {
-			tokens += newTokens
-			if tokens > int64(t.limit) {
-				tokens = int64(t.limit)
-			}
-			lastRefill += newTokens * t.timePerToken
-		}
-	}
+	tokens, lastRefill := t.refill(tokens, int64(lastRefillVal), now)
 
 	// Check and consume
-	allowed := tokens > 0
+	cost := int64(n)
+	allowed := tokens >= cost
 	if allowed {
-		tokens--
+		tokens -= cost
 	}
 
-	elapsedSinceRefill := now - lastRefill
-	nextTokenDelay := clampDuration(time.Duration(t.timePerToken-elapsedSinceRefill) * time.Nanosecond)
-	missingTokens := int64(t.limit) - tokens
-	reset := time.Duration(0)
-	if missingTokens > 0 {
-		reset = clampDuration(time.Duration(missingTokens*t.timePerToken-elapsedSinceRefill) * time.Nanosecond)
-	}
+	reset, nextTokenDelay := t.timing(tokens, lastRefill, now, cost)
 
 	// Persist updated values
-	err = t.store.Set(ctx, tokensKey, float64(tokens), t.window)
+	err = t.store.Set(ctx, tokensKey, float64(tokens), t.ttl)
 	if res, retErr, done := failOpenHandler(start, err, t.failOpen, t.metrics, t.limit); done {
 		return res, retErr
 	}
-	err = t.store.Set(ctx, refillKey, float64(lastRefill), t.window)
+	err = t.store.Set(ctx, refillKey, float64(lastRefill), t.ttl)
 	if res, retErr, done := failOpenHandler(start, err, t.failOpen, t.metrics, t.limit); done {
 		return res, retErr
 	}
@@ -132,20 +158,16 @@ func (t *TokenBucketLimiter) allowGeneric(ctx context.Context, start time.Time,
 	return res, nil
 }
 
-func (t *TokenBucketLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string) (core.Result, error) {
-	keys := []string{
-		fmt.Sprintf("%s:{%s}:tokens", t.prefix, key),
-		fmt.Sprintf("%s:{%s}:refill", t.prefix, key),
-	}
-
+func (t *TokenBucketLimiter) allowRedis(ctx context.Context, start time.Time, runner redisScriptRunner, key string, n int) (core.Result, error) {
 	values, err := runner.EvalScript(
 		ctx,
 		redisScriptTokenBucket,
-		keys,
+		t.storageKeys(key),
 		int64(t.limit),
-		time.Now().UnixMicro(),
-		durationToMilliseconds(t.window),
+		t.clock.Now().UnixMicro(),
+		durationToMilliseconds(t.ttl),
 		durationToMicros(time.Duration(t.timePerToken)),
+		int64(n),
 	)
 	if res, retErr, done := failOpenHandler(start, err, t.failOpen, t.metrics, t.limit); done {
 		return res, retErr
@@ -166,6 +188,166 @@ func (t *TokenBucketLimiter) allowRedis(ctx context.Context, start time.Time, ru
 	return res, nil
 }
 
+// Peek reports the bucket state for key without consuming a token.
+// Allowed tells whether a single-token request would currently succeed.
+func (t *TokenBucketLimiter) Peek(ctx context.Context, key string) (core.Result, error) {
+	if runner, ok := t.store.(redisScriptRunner); ok {
+		return t.peekRedis(ctx, runner, key)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.peekGeneric(ctx, key)
+}
+
+func (t *TokenBucketLimiter) peekGeneric(ctx context.Context, key string) (core.Result, error) {
+	now := t.clock.Now().UnixNano()
+	keys := t.storageKeys(key)
+
+	tokenVal, err := t.store.Get(ctx, keys[0])
+	if err != nil {
+		return core.Result{Limit: t.limit}, err
+	}
+	lastRefillVal, err := t.store.Get(ctx, keys[1])
+	if err != nil {
+		return core.Result{Limit: t.limit}, err
+	}
+
+	tokens, lastRefill := t.refill(int64(tokenVal), int64(lastRefillVal), now)
+	reset, nextTokenDelay := t.timing(tokens, lastRefill, now, 1)
+
+	res := core.Result{
+		Allowed:   tokens >= 1,
+		Limit:     t.limit,
+		Remaining: int(tokens),
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = nextTokenDelay
+	}
+	return res, nil
+}
+
+func (t *TokenBucketLimiter) peekRedis(ctx context.Context, runner redisScriptRunner, key string) (core.Result, error) {
+	values, err := runner.EvalScript(
+		ctx,
+		redisScriptTokenBucketPeek,
+		t.storageKeys(key),
+		int64(t.limit),
+		t.clock.Now().UnixMicro(),
+		durationToMicros(time.Duration(t.timePerToken)),
+	)
+	if err != nil {
+		return core.Result{Limit: t.limit}, err
+	}
+	return buildRedisScriptResult(t.limit, values)
+}
+
+// refill brings the bucket up to date at now without touching storage.
+// A zero lastRefill means the key has no state yet, so the bucket starts full.
+func (t *TokenBucketLimiter) refill(tokens, lastRefill, now int64) (int64, int64) {
+	if lastRefill == 0 {
+		return int64(t.limit), now
+	}
+
+	elapsed := now - lastRefill
+	newTokens := elapsed / t.timePerToken
+	if newTokens > 0 {
+		tokens += newTokens
+		if tokens > int64(t.limit) {
+			tokens = int64(t.limit)
+		}
+		lastRefill += newTokens * t.timePerToken
+	}
+	return tokens, lastRefill
+}
+
+// timing returns how long until the bucket is full again and how long until cost tokens are available.
+func (t *TokenBucketLimiter) timing(tokens, lastRefill, now, cost int64) (time.Duration, time.Duration) {
+	elapsedSinceRefill := now - lastRefill
+	wait := clampDuration(time.Duration((cost-tokens)*t.timePerToken-elapsedSinceRefill) * time.Nanosecond)
+	missingTokens := int64(t.limit) - tokens
+	reset := time.Duration(0)
+	if missingTokens > 0 {
+		reset = clampDuration(time.Duration(missingTokens*t.timePerToken-elapsedSinceRefill) * time.Nanosecond)
+	}
+	return reset, wait
+}
+
+func (t *TokenBucketLimiter) useTaggedKeys() {
+	t.tagged = true
+}
+
+func (t *TokenBucketLimiter) storageKeys(key string) []string {
+	tag := key
+	if !t.tagged {
+		tag = hashTag(key)
+	}
+	return []string{
+		fmt.Sprintf("%s:%s:tokens", t.prefix, tag),
+		fmt.Sprintf("%s:%s:refill", t.prefix, tag),
+	}
+}
+
+// Refund puts n tokens back into the bucket for key, up to its capacity.
+func (t *TokenBucketLimiter) Refund(ctx context.Context, key string, n int) error {
+	if err := validateRefund(n); err != nil {
+		return err
+	}
+
+	if runner, ok := t.store.(redisScriptRunner); ok {
+		_, err := runner.EvalScript(
+			ctx,
+			redisScriptTokenBucketRefund,
+			t.storageKeys(key),
+			int64(t.limit),
+			t.clock.Now().UnixMicro(),
+			durationToMilliseconds(t.ttl),
+			durationToMicros(time.Duration(t.timePerToken)),
+			int64(n),
+		)
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.refundGeneric(ctx, key, n)
+}
+
+func (t *TokenBucketLimiter) refundGeneric(ctx context.Context, key string, n int) error {
+	now := t.clock.Now().UnixNano()
+	keys := t.storageKeys(key)
+
+	tokenVal, err := t.store.Get(ctx, keys[0])
+	if err != nil {
+		return err
+	}
+	lastRefillVal, err := t.store.Get(ctx, keys[1])
+	if err != nil {
+		return err
+	}
+	if lastRefillVal == 0 {
+		// No state means the bucket is already full.
+		return nil
+	}
+
+	tokens, lastRefill := t.refill(int64(tokenVal), int64(lastRefillVal), now)
+	tokens += int64(n)
+	if tokens > int64(t.limit) {
+		tokens = int64(t.limit)
+	}
+
+	if err := t.store.Set(ctx, keys[0], float64(tokens), t.ttl); err != nil {
+		return err
+	}
+	return t.store.Set(ctx, keys[1], float64(lastRefill), t.ttl)
+}
+
+// Reset deletes the token count and refill timestamp stored for key.
+func (t *TokenBucketLimiter) Reset(ctx context.Context, key string) error {
+	return t.store.Delete(ctx, t.storageKeys(key)...)
+}
+
 // Close releases resources held by the limiter.
 func (t *TokenBucketLimiter) Close() error {
 	return t.store.Close()
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
 )
@@ -203,20 +204,21 @@ func TestTokenBucket_GetRefillError(t *testing.T) {
 // TestTokenBucket_TokensCappedToLimit ensures that refilled tokens never exceed the configured limit,
 // even if a long time has passed since the last request.
 func TestTokenBucket_TokensCappedToLimit(t *testing.T) {
-	store := inmem.NewInMemoryStore()
+	clock := clocktest.NewManual(time.Now())
+	store := inmem.NewInMemoryStoreWithClock(clock)
 	defer store.Close()
 	// Using a configuration where heavy refilling occurs.
 	// limit=3, window=3s -> fill rate = 1 token/s.
 	limiter2 := NewTokenBucketLimiter(core.Config{
-		Limit: 3, Window: 3 * time.Second, Metrics: &core.NoopMetrics{},
+		Limit: 3, Window: 3 * time.Second, Metrics: &core.NoopMetrics{}, Clock: clock,
 	}, store)
 	ctx := context.Background()
 
 	// Init: tokens=3, use 1, stored tokens=2
 	limiter2.Allow(ctx, "cap")
 
-	// Sleep 2s: rate=1/s, so newTokens=2. tokens=2+2=4>3 → cap to 3.
-	time.Sleep(2 * time.Second)
+	// Advance 2s: rate=1/s, so newTokens=2. tokens=2+2=4>3 → cap to 3.
+	clock.Advance(2 * time.Second)
 
 	// Should get exactly 3 tokens
 	for i := 0; i < 3; i++ {
@@ -325,6 +327,91 @@ func TestTokenBucket_MetricsRecording(t *testing.T) {
 	}
 }
 
+// TestTokenBucket_AllowN verifies that weighted requests consume their full cost and that a
+// denied request leaves the remaining capacity untouched.
+func TestTokenBucket_AllowN(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewTokenBucketLimiter(core.Config{
+		Limit: 5, Window: time.Minute, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.AllowN(ctx, "bulk", 3)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 3 to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 2 {
+		t.Fatalf("expected remaining=2, got %d", res.Remaining)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected cost 3 to be denied with 2 units left")
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+
+	res, err = limiter.AllowN(ctx, "bulk", 2)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected cost 2 to fit the remaining capacity, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected remaining=0, got %d", res.Remaining)
+	}
+}
+
+// TestTokenBucket_PeekDoesNotConsume verifies that Peek reports the current state without
+// spending capacity or recording metrics.
+func TestTokenBucket_PeekDoesNotConsume(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	m := &mockMetrics{}
+	limiter := NewTokenBucketLimiter(core.Config{
+		Limit: 3, Window: time.Minute, Metrics: m,
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 3 {
+		t.Fatalf("expected fresh key to report full capacity, got %+v", res)
+	}
+
+	limiter.Allow(ctx, "dash")
+	limiter.Allow(ctx, "dash")
+	for i := 0; i < 2; i++ {
+		res, err = limiter.Peek(ctx, "dash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.Allowed || res.Remaining != 1 {
+			t.Fatalf("peek %d: expected remaining=1, got %+v", i+1, res)
+		}
+	}
+
+	limiter.Allow(ctx, "dash")
+	res, err = limiter.Peek(ctx, "dash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected exhausted state, got %+v", res)
+	}
+	if res.RetryAfter <= 0 {
+		t.Fatalf("expected positive retry_after, got %v", res.RetryAfter)
+	}
+	if m.allows != 3 || m.denies != 0 {
+		t.Fatalf("peek should not record decisions, got %d allows and %d denies", m.allows, m.denies)
+	}
+}
+
 func BenchmarkTokenBucket_SingleKey(b *testing.B) {
 	b.ReportAllocs()
 	store := inmem.NewInMemoryStore()
@@ -353,3 +440,56 @@ func BenchmarkTokenBucket_MultiKey(b *testing.B) {
 		limiter.Allow(ctx, key)
 	}
 }
+
+// TestTokenBucket_BurstAndRate verifies that capacity and refill rate can be set independently.
+func TestTokenBucket_BurstAndRate(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	// Burst of 5, sustained 20/s -> one token every 50ms.
+	limiter := NewTokenBucketLimiter(core.Config{
+		Burst: 5, Rate: 20, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	res, err := limiter.AllowN(ctx, "k", 5)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected full burst to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Limit != 5 || res.Remaining != 0 {
+		t.Fatalf("expected limit=5 remaining=0, got limit=%d remaining=%d", res.Limit, res.Remaining)
+	}
+	if res.Reset <= 200*time.Millisecond || res.Reset > 250*time.Millisecond {
+		t.Fatalf("expected reset close to 5 refill intervals, got %v", res.Reset)
+	}
+
+	denied, _ := limiter.Allow(ctx, "k")
+	if denied.Allowed {
+		t.Fatal("expected denial once the burst is spent")
+	}
+	if denied.RetryAfter <= 40*time.Millisecond || denied.RetryAfter > 50*time.Millisecond {
+		t.Fatalf("expected retry_after close to one refill interval, got %v", denied.RetryAfter)
+	}
+
+	time.Sleep(denied.RetryAfter)
+	if res, _ := limiter.Allow(ctx, "k"); !res.Allowed {
+		t.Fatal("expected a token after one refill interval")
+	}
+}
+
+// TestTokenBucket_BurstAboveLimit keeps Limit/Window as the rate while raising the capacity.
+func TestTokenBucket_BurstAboveLimit(t *testing.T) {
+	store := inmem.NewInMemoryStore()
+	defer store.Close()
+	limiter := NewTokenBucketLimiter(core.Config{
+		Limit: 1, Window: time.Minute, Burst: 3, Metrics: &core.NoopMetrics{},
+	}, store)
+	ctx := context.Background()
+
+	if _, err := limiter.AllowN(ctx, "k", 3); err != nil {
+		t.Fatalf("expected a cost up to the burst to be valid, got %v", err)
+	}
+	res, _ := limiter.Allow(ctx, "k")
+	if res.Allowed || res.RetryAfter <= 59*time.Second {
+		t.Fatalf("expected denial with a minute-long refill, got %v after %v", res.Allowed, res.RetryAfter)
+	}
+}
@@ -4,6 +4,9 @@
 package gorl
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/AliRizaAynaci/gorl/v2/internal/algorithms"
 	"github.com/AliRizaAynaci/gorl/v2/storage"
@@ -11,54 +14,153 @@ import (
 	"github.com/AliRizaAynaci/gorl/v2/storage/redis"
 )
 
-var strategyRegistry = map[core.StrategyType]func(core.Config, storage.Storage) core.Limiter{
+// StrategyConstructor builds a limiter for cfg on top of store. cfg is validated and its Metrics
+// is never nil. The store is shared with other limiters when used through NewResourceLimiter,
+// so its Close may be a no-op.
+type StrategyConstructor func(cfg core.Config, store storage.Storage) core.Limiter
+
+var strategyMu sync.RWMutex
+
+var strategyRegistry = map[core.StrategyType]StrategyConstructor{
 	core.FixedWindow:   algorithms.NewFixedWindowLimiter,
 	core.TokenBucket:   algorithms.NewTokenBucketLimiter,
 	core.SlidingWindow: algorithms.NewSlidingWindowLimiter,
 	core.LeakyBucket:   algorithms.NewLeakyBucketLimiter,
+	core.SlidingLog:    algorithms.NewSlidingLogLimiter,
+	core.GCRA:          algorithms.NewGCRALimiter,
+	core.CalendarQuota: algorithms.NewCalendarQuotaLimiter,
+	core.Concurrency:   algorithms.NewConcurrencyLimiter,
+}
+
+// RegisterStrategy makes a custom algorithm available under name to New, NewResourceLimiter
+// and the config loader. Helpers for writing one live in the
+// strategy package. Registering an empty name, a nil constructor or a name already in use
+// (including built-in ones) returns core.ErrConfigInvalid.
+func RegisterStrategy(name core.StrategyType, constructor StrategyConstructor) error {
+	if name == "" || constructor == nil {
+		return fmt.Errorf("%w: strategy name and constructor are required", core.ErrConfigInvalid)
+	}
+
+	strategyMu.Lock()
+	defer strategyMu.Unlock()
+	if _, exists := strategyRegistry[name]; exists {
+		return fmt.Errorf("%w: strategy %q is already registered", core.ErrConfigInvalid, name)
+	}
+	strategyRegistry[name] = constructor
+	return nil
+}
+
+func lookupStrategy(name core.StrategyType) (StrategyConstructor, bool) {
+	strategyMu.RLock()
+	defer strategyMu.RUnlock()
+	constructor, ok := strategyRegistry[name]
+	return constructor, ok
 }
 
 // New creates a new rate limiter instance using the specified algorithm and storage backend.
 // If cfg.RedisURL is provided, Redis is used as the storage backend. Otherwise, an in-memory backend is used.
-// Supported strategies: FixedWindow, TokenBucket, SlidingWindow, LeakyBucket.
+// Supported strategies: FixedWindow, TokenBucket, SlidingWindow, LeakyBucket, SlidingLog, GCRA,
+// CalendarQuota, Concurrency, plus any strategy added with RegisterStrategy.
+// With cfg.Fallback set, a Redis-backed limiter decides requests on an in-memory limiter while
+// Redis fails; Redis must still be reachable when New is called.
 func New(cfg core.Config) (core.Limiter, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 	cfg.Metrics = normalizeMetrics(cfg.Metrics)
 
-	store, err := newStore(cfg.RedisURL)
+	store, err := newStore(cfg.RedisURL, cfg.Clock)
 	if err != nil {
 		return nil, err
 	}
 
-	constructor, ok := strategyRegistry[cfg.Strategy]
+	constructor, ok := lookupStrategy(cfg.Strategy)
 	if !ok {
 		return nil, core.ErrUnknownStrategy
 	}
-	return constructor(cfg, store), nil
+	limiter := buildLimiter(constructor, cfg, store)
+	if cfg.Fallback.Instances > 0 && cfg.RedisURL != "" {
+		localStore, err := newStore("", cfg.Clock)
+		if err != nil {
+			_ = limiter.Close()
+			return nil, err
+		}
+		limiter = buildFallback(constructor, cfg, limiter, localStore)
+	}
+	return limiter, nil
+}
+
+// buildFallback wraps limiter, built by constructor for cfg, with a breaker that hands requests
+// to a limiter on localStore while the Redis backend fails.
+func buildFallback(constructor StrategyConstructor, cfg core.Config, limiter core.Limiter, localStore storage.Storage) core.Limiter {
+	return algorithms.NewFallbackLimiter(limiter, buildLimiter(constructor, fallbackConfig(cfg), localStore), cfg)
+}
+
+// fallbackConfig returns the configuration of the in-memory limiter that stands in for the
+// Redis-backed one while Redis fails: the same policy with its share of the capacity.
+func fallbackConfig(cfg core.Config) core.Config {
+	instances := cfg.Fallback.Instances
+	local := cfg
+	local.RedisURL = ""
+	local.Fallback = core.FallbackPolicy{}
+	local.PermitLease = core.PermitLeasePolicy{}
+	local.Limit = (cfg.Limit + instances - 1) / instances
+	local.Burst = (cfg.Burst + instances - 1) / instances
+	local.Rate = cfg.Rate / float64(instances)
+	return local
+}
+
+// buildLimiter runs constructor on a validated cfg and wraps the limiter with the penalty box,
+// shadow mode and access lists cfg asks for, in that order.
+func buildLimiter(constructor StrategyConstructor, cfg core.Config, store storage.Storage) core.Limiter {
+	if cfg.Shadow {
+		cfg.Metrics = algorithms.ShadowMetrics(cfg.Metrics)
+	}
+	var limiter core.Limiter
+	if cfg.PermitLease.Batch > 0 {
+		// The lease limiter reports each request; the strategy would report each lease.
+		strategyCfg := cfg
+		strategyCfg.Metrics = &core.NoopMetrics{}
+		limiter = algorithms.NewPermitLeaseLimiter(constructor(strategyCfg, store), cfg)
+	} else {
+		limiter = constructor(cfg, store)
+	}
+	if cfg.Penalty.Threshold > 0 {
+		limiter = algorithms.NewPenaltyBox(limiter, cfg, store)
+	}
+	if cfg.Shadow {
+		limiter = algorithms.NewShadowLimiter(limiter, cfg.OnShadowDeny)
+	}
+	if !cfg.Bypass.Empty() {
+		// cfg has been validated, so the bypass policy compiles.
+		bypass, _ := cfg.Bypass.Compile()
+		limiter = algorithms.NewBypassLimiter(limiter, bypass)
+	}
+	return limiter
 }
 
 // NewResourceLimiter creates a resource-scoped limiter that shares a single storage backend
-// while allowing per-resource policies under the same strategy.
+// while allowing per-resource policies, each of which may override the strategy, fail-open
+// behaviour and metrics collector. With cfg.Fallback set, each policy falls back to an in-memory
+// limiter while Redis fails, as with New.
+// The returned limiter implements core.ReloadableResourceLimiter, so policies can be updated in place.
 func NewResourceLimiter(cfg core.ResourceConfig) (core.ResourceLimiter, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 	cfg.Metrics = normalizeMetrics(cfg.Metrics)
 
-	store, err := newStore(cfg.RedisURL)
+	store, err := newStore(cfg.RedisURL, cfg.Clock)
 	if err != nil {
 		return nil, err
 	}
 
-	constructor, ok := strategyRegistry[cfg.Strategy]
-	if !ok {
+	limiter, err := newResourceRouter(cfg, store)
+	if err != nil {
 		_ = store.Close()
-		return nil, core.ErrUnknownStrategy
+		return nil, err
 	}
-
-	return newResourceRouter(cfg, store, constructor), nil
+	return limiter, nil
 }
 
 func normalizeMetrics(metrics core.MetricsCollector) core.MetricsCollector {
@@ -68,9 +170,14 @@ func normalizeMetrics(metrics core.MetricsCollector) core.MetricsCollector {
 	return metrics
 }
 
-func newStore(redisURL string) (storage.Storage, error) {
+// newStore opens Redis when redisURL is set and an in-memory store otherwise.
+// The in-memory store decides expiry with clock; Redis expires keys on server time.
+func newStore(redisURL string, clock core.Clock) (storage.Storage, error) {
 	if redisURL != "" {
 		return redis.NewRedisStore(redisURL)
 	}
-	return inmem.NewInMemoryStore(), nil
+	if clock == nil {
+		return inmem.NewInMemoryStore(), nil
+	}
+	return inmem.NewInMemoryStoreWithClock(clock), nil
 }
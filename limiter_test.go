@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/strategy"
 )
 
 func TestNew_FixedWindow(t *testing.T) {
@@ -149,6 +152,9 @@ func TestNew_AllStrategiesRespectLimit(t *testing.T) {
 		core.SlidingWindow,
 		core.TokenBucket,
 		core.LeakyBucket,
+		core.SlidingLog,
+		core.GCRA,
+		core.Concurrency,
 	}
 
 	for _, strategy := range strategies {
@@ -178,3 +184,451 @@ func TestNew_AllStrategiesRespectLimit(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_CalendarQuota(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.CalendarQuota,
+		Limit:    1,
+		Period:   core.PeriodDay,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if res, _ := limiter.Allow(ctx, "key"); !res.Allowed {
+		t.Fatal("expected first request to be allowed")
+	}
+	if res, _ := limiter.Allow(ctx, "key"); res.Allowed {
+		t.Fatal("expected daily quota to be exhausted")
+	}
+
+	_, err = New(core.Config{Strategy: core.CalendarQuota, Limit: 1, Window: time.Hour})
+	if !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid without a period, got %v", err)
+	}
+}
+
+func TestNewComposite(t *testing.T) {
+	limiter, err := NewComposite(core.CompositeConfig{
+		Strategy: core.FixedWindow,
+		Policies: []core.ResourcePolicy{
+			{Limit: 3, Window: time.Second},
+			{Limit: 2, Window: time.Hour},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if res, _ := limiter.Allow(ctx, "key"); !res.Allowed {
+			t.Fatalf("req %d: expected allowed", i+1)
+		}
+	}
+	res, _ := limiter.Allow(ctx, "key")
+	if res.Allowed || res.Limit != 2 {
+		t.Fatalf("expected denial by the hourly policy, got allowed=%v limit=%d", res.Allowed, res.Limit)
+	}
+}
+
+func TestNewComposite_InvalidConfig(t *testing.T) {
+	if _, err := NewComposite(core.CompositeConfig{Strategy: core.FixedWindow}); !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid without policies, got %v", err)
+	}
+
+	_, err := NewComposite(core.CompositeConfig{
+		Strategy: core.SlidingLog,
+		Policies: []core.ResourcePolicy{{Limit: 1, Window: time.Second}},
+	})
+	if !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for an unsupported strategy, got %v", err)
+	}
+}
+
+func TestNewHierarchical(t *testing.T) {
+	limiter, err := NewHierarchical(core.HierarchicalConfig{
+		Strategy: core.TokenBucket,
+		Levels: []core.HierarchyLevel{
+			{Name: "global", Policy: core.ResourcePolicy{Limit: 100, Window: time.Minute}},
+			{Name: "tenant", Field: "tenant", Policy: core.ResourcePolicy{Limit: 3, Window: time.Minute}},
+			{Name: "user", Field: "user", Policy: core.ResourcePolicy{Limit: 2, Window: time.Minute}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limiter.AllowN(ctx, core.Descriptor{"tenant": "acme", "user": "alice"}, 2)
+	res, err := limiter.AllowN(ctx, core.Descriptor{"tenant": "acme", "user": "bob"}, 2)
+	if err != nil || res.Allowed || res.Level != "tenant" {
+		t.Fatalf("expected tenant-level denial, got allowed=%v level=%q err=%v", res.Allowed, res.Level, err)
+	}
+}
+
+func TestNewHierarchical_InvalidConfig(t *testing.T) {
+	_, err := NewHierarchical(core.HierarchicalConfig{
+		Strategy: core.FixedWindow,
+		Levels: []core.HierarchyLevel{
+			{Name: "user", Field: "user", Policy: core.ResourcePolicy{Limit: 1, Window: time.Second}},
+			{Name: "user", Field: "user", Policy: core.ResourcePolicy{Limit: 1, Window: time.Second}},
+		},
+	})
+	if !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for duplicate level names, got %v", err)
+	}
+}
+
+// quotaLimiter is a minimal third-party strategy: a counter kept for one window.
+type quotaLimiter struct {
+	cfg   core.Config
+	store storage.Storage
+}
+
+func (q *quotaLimiter) Allow(ctx context.Context, key string) (core.Result, error) {
+	return q.AllowN(ctx, key, 1)
+}
+
+func (q *quotaLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	if err := strategy.ValidateCost(n, q.cfg.Limit); err != nil {
+		return core.Result{Limit: q.cfg.Limit}, err
+	}
+	start := time.Now()
+	used, err := q.store.IncrBy(ctx, "quota:"+key, float64(n), q.cfg.Window)
+	if res, retErr, done := strategy.HandleFailure(start, err, q.cfg.FailOpen, q.cfg.Metrics, q.cfg.Limit); done {
+		return res, retErr
+	}
+	allowed := int(used) <= q.cfg.Limit
+	strategy.RecordDecision(q.cfg.Metrics, start, allowed)
+	return core.Result{Allowed: allowed, Limit: q.cfg.Limit}, nil
+}
+
+func (q *quotaLimiter) Peek(context.Context, string) (core.Result, error) {
+	return core.Result{Limit: q.cfg.Limit}, nil
+}
+func (q *quotaLimiter) Refund(context.Context, string, int) error { return nil }
+func (q *quotaLimiter) Reset(ctx context.Context, key string) error {
+	return q.store.Delete(ctx, "quota:"+key)
+}
+func (q *quotaLimiter) Close() error { return q.store.Close() }
+
+func TestRegisterStrategy(t *testing.T) {
+	const quota core.StrategyType = "test_quota"
+	err := RegisterStrategy(quota, func(cfg core.Config, store storage.Storage) core.Limiter {
+		return &quotaLimiter{cfg: cfg, store: store}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	limiter, err := New(core.Config{Strategy: quota, Limit: 1, Window: time.Minute})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+	if res, _ := limiter.Allow(ctx, "key"); !res.Allowed {
+		t.Fatal("expected first request to be allowed")
+	}
+	if res, _ := limiter.Allow(ctx, "key"); res.Allowed {
+		t.Fatal("expected the custom strategy to deny the second request")
+	}
+
+	resourceLimiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      quota,
+		DefaultPolicy: core.ResourcePolicy{Limit: 2, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("NewResourceLimiter: %v", err)
+	}
+	defer resourceLimiter.Close()
+	if res, _ := resourceLimiter.AllowResourceN(ctx, "api", "key", 2); !res.Allowed || res.Limit != 2 {
+		t.Fatalf("expected the custom strategy behind the resource limiter, got %+v", res)
+	}
+}
+
+func TestRegisterStrategy_Rejected(t *testing.T) {
+	constructor := func(cfg core.Config, store storage.Storage) core.Limiter {
+		return &quotaLimiter{cfg: cfg, store: store}
+	}
+	cases := map[string]error{
+		"builtin":         RegisterStrategy(core.FixedWindow, constructor),
+		"empty name":      RegisterStrategy("", constructor),
+		"nil constructor": RegisterStrategy("test_nil", nil),
+	}
+	for name, err := range cases {
+		if !errors.Is(err, core.ErrConfigInvalid) {
+			t.Errorf("%s: expected ErrConfigInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestNew_TokenBucketBurstAndRate(t *testing.T) {
+	limiter, err := New(core.Config{Strategy: core.TokenBucket, Burst: 3, Rate: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	res, err := limiter.AllowN(context.Background(), "key", 3)
+	if err != nil || !res.Allowed || res.Limit != 3 {
+		t.Fatalf("expected the burst to be allowed with limit=3, got %+v, err %v", res, err)
+	}
+}
+
+func TestNew_Clock(t *testing.T) {
+	clock := clocktest.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Hour,
+		Clock:    clock,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limiter.Allow(ctx, "k")
+	if res, _ := limiter.Allow(ctx, "k"); res.Allowed {
+		t.Fatal("expected denied within the hour")
+	}
+	clock.Advance(time.Hour)
+	if res, _ := limiter.Allow(ctx, "k"); !res.Allowed {
+		t.Fatal("expected allowed after advancing the clock by one window")
+	}
+}
+
+func TestNewAdaptive(t *testing.T) {
+	limiter, err := NewAdaptive(core.AdaptiveConfig{
+		Strategy: core.SlidingWindow,
+		Policy:   core.ResourcePolicy{Limit: 4, Window: time.Minute},
+		MinLimit: 1,
+		MaxLimit: 10,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if err := limiter.Report(ctx, core.Outcome{Err: errors.New("db down")}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed, got %v, err %v", i+1, res.Allowed, err)
+		}
+	}
+	if res, _ := limiter.Allow(ctx, "k"); res.Allowed {
+		t.Fatal("expected denial at the halved limit")
+	}
+}
+
+func TestNewAdaptive_InvalidConfig(t *testing.T) {
+	base := core.AdaptiveConfig{
+		Strategy: core.FixedWindow,
+		Policy:   core.ResourcePolicy{Window: time.Minute},
+		MinLimit: 1,
+		MaxLimit: 10,
+	}
+	cases := map[string]func(*core.AdaptiveConfig){
+		"zero min":          func(c *core.AdaptiveConfig) { c.MinLimit = 0 },
+		"max below min":     func(c *core.AdaptiveConfig) { c.MaxLimit = 0 },
+		"start out of band": func(c *core.AdaptiveConfig) { c.Policy.Limit = 20 },
+		"decrease of one":   func(c *core.AdaptiveConfig) { c.Decrease = 1 },
+		"missing window":    func(c *core.AdaptiveConfig) { c.Policy.Window = 0 },
+		"concurrency":       func(c *core.AdaptiveConfig) { c.Strategy = core.Concurrency },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := base
+			mutate(&cfg)
+			if _, err := NewAdaptive(cfg); !errors.Is(err, core.ErrConfigInvalid) {
+				t.Fatalf("expected ErrConfigInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNew_PenaltyBox(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.TokenBucket,
+		Limit:    1,
+		Window:   time.Second,
+		Penalty:  core.PenaltyPolicy{Threshold: 2, Period: time.Minute, BanDuration: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limiter.Allow(ctx, "k")
+	limiter.Allow(ctx, "k")
+	res, err := limiter.Allow(ctx, "k")
+	if err != nil || res.Allowed {
+		t.Fatalf("expected denial, got %v, err %v", res.Allowed, err)
+	}
+	if res.RetryAfter < 59*time.Minute {
+		t.Fatalf("expected the ban to outlast the bucket refill, got RetryAfter %v", res.RetryAfter)
+	}
+}
+
+func TestNew_PenaltyBoxKeepsLeases(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.Concurrency,
+		Limit:    1,
+		Window:   time.Minute,
+		Penalty:  core.PenaltyPolicy{Threshold: 2, Period: time.Minute, BanDuration: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	leaser, ok := limiter.(core.LeaseLimiter)
+	if !ok {
+		t.Fatal("expected a penalty box over Concurrency to be a LeaseLimiter")
+	}
+	ctx := context.Background()
+	lease, res, err := leaser.Acquire(ctx, "k")
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected a lease, got %v, err %v", res.Allowed, err)
+	}
+	if err := leaser.Release(ctx, lease); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, res, _ := leaser.Acquire(ctx, "k"); !res.Allowed {
+		t.Fatal("expected the released slot to be available")
+	}
+
+	leaser.Acquire(ctx, "k")
+	leaser.Acquire(ctx, "k")
+	lease, res, _ = leaser.Acquire(ctx, "k")
+	if res.Allowed || lease.ID != "" || res.RetryAfter < 59*time.Minute {
+		t.Fatalf("expected the key banned after two denied acquires, got %+v", res)
+	}
+}
+
+func TestNew_InvalidPenalty(t *testing.T) {
+	_, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Second,
+		Penalty:  core.PenaltyPolicy{Threshold: 3, Period: time.Minute},
+	})
+	if !errors.Is(err, core.ErrConfigInvalid) {
+		t.Fatalf("expected ErrConfigInvalid for a ban without duration, got %v", err)
+	}
+}
+
+func TestNew_Bypass(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.Concurrency,
+		Limit:    1,
+		Window:   time.Minute,
+		Bypass: core.BypassPolicy{
+			Allow: core.AccessList{CIDRs: []string{"10.0.0.0/8"}},
+			Deny:  core.AccessList{Prefixes: []string{"banned:"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	leaser, ok := limiter.(core.LeaseLimiter)
+	if !ok {
+		t.Fatal("expected the bypass to keep the lease interface")
+	}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, res, err := leaser.Acquire(ctx, "10.1.1.1"); err != nil || !res.Allowed || !res.Bypassed {
+			t.Fatalf("expected allowlisted key to bypass the limit, got %+v, err %v", res, err)
+		}
+	}
+	if res, err := limiter.Allow(ctx, "banned:bob"); err != nil || res.Allowed || !res.Bypassed {
+		t.Fatalf("expected denylisted key to be rejected, got %+v, err %v", res, err)
+	}
+	if res, _ := limiter.Allow(ctx, "192.0.2.1"); !res.Allowed || res.Bypassed {
+		t.Fatalf("expected unlisted key to be rate limited, got %+v", res)
+	}
+	if res, _ := limiter.Allow(ctx, "192.0.2.1"); res.Allowed {
+		t.Fatal("expected unlisted key to hit the limit")
+	}
+}
+
+func TestNew_Shadow(t *testing.T) {
+	var denied []string
+	limiter, err := New(core.Config{
+		Strategy: core.SlidingWindow,
+		Limit:    1,
+		Window:   time.Minute,
+		Shadow:   true,
+		OnShadowDeny: func(_ context.Context, _, key string, _ core.Result) {
+			denied = append(denied, key)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed || !res.Shadow {
+			t.Fatalf("req %d: expected a shadow admission, got %+v, err %v", i+1, res, err)
+		}
+	}
+	if len(denied) != 2 || denied[0] != "k" {
+		t.Fatalf("expected 2 would-be denials for k, got %v", denied)
+	}
+}
+
+func TestNew_PermitLease(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy:    core.SlidingWindow,
+		Limit:       20,
+		Window:      time.Minute,
+		PermitLease: core.PermitLeasePolicy{Batch: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		if res, err := limiter.Allow(ctx, "k"); err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed, got %+v, err %v", i+1, res, err)
+		}
+	}
+	if res, err := limiter.Allow(ctx, "k"); err != nil || res.Allowed {
+		t.Fatalf("expected denial past the limit, got %+v, err %v", res, err)
+	}
+}
+
+func TestFallbackConfig_SplitsCapacity(t *testing.T) {
+	local := fallbackConfig(core.Config{
+		Strategy: core.TokenBucket,
+		Limit:    10,
+		Window:   time.Second,
+		Burst:    25,
+		Rate:     8,
+		RedisURL: "redis://localhost:6379/0",
+		Fallback: core.FallbackPolicy{Instances: 4},
+	})
+	if local.Limit != 3 || local.Burst != 7 || local.Rate != 2 {
+		t.Fatalf("expected limit 3, burst 7 and rate 2, got %d, %d and %v", local.Limit, local.Burst, local.Rate)
+	}
+	if local.RedisURL != "" || local.Fallback.Instances != 0 {
+		t.Fatalf("expected an in-memory config without fallback, got %+v", local)
+	}
+}
@@ -12,6 +12,8 @@ type PromMetrics struct {
 	allow   prometheus.Counter
 	deny    prometheus.Counter
 	latency prometheus.Histogram
+	limit   prometheus.Gauge
+	shadow  prometheus.Counter
 }
 
 // NewPrometheusCollector creates a PromMetrics instance with the specified namespace and subsystem.
@@ -36,12 +38,24 @@ func NewPrometheusCollector(namespace, subsystem string) *PromMetrics {
 			Help:      "Histogram of request processing durations",
 			// Adjust buckets to fit expected latency distribution as needed
 		}),
+		limit: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "current_limit",
+			Help:      "Current effective limit of adaptive limiters",
+		}),
+		shadow: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "shadow_deny_total",
+			Help:      "Total number of requests shadow mode allowed that would have been denied",
+		}),
 	}
 }
 
 // RegisterPrometheusCollectors  registers the PromMetrics collectors with the default Prometheus registry.
 func RegisterPrometheusCollectors(m *PromMetrics) {
-	prometheus.MustRegister(m.allow, m.deny, m.latency)
+	prometheus.MustRegister(m.allow, m.deny, m.latency, m.limit, m.shadow)
 }
 
 // IncAllow increments the allowed requests counter.
@@ -59,5 +73,19 @@ func (m *PromMetrics) ObserveLatency(d time.Duration) {
 	m.latency.Observe(d.Seconds())
 }
 
-// Ensure PromMetrics implements core.MetricsCollector.
-var _ core.MetricsCollector = (*PromMetrics)(nil)
+// ObserveLimit sets the current limit gauge.
+func (m *PromMetrics) ObserveLimit(limit int) {
+	m.limit.Set(float64(limit))
+}
+
+// IncShadowDeny increments the would-be denied requests counter.
+func (m *PromMetrics) IncShadowDeny() {
+	m.shadow.Inc()
+}
+
+// Ensure PromMetrics implements core.MetricsCollector, core.LimitObserver and core.ShadowObserver.
+var (
+	_ core.MetricsCollector = (*PromMetrics)(nil)
+	_ core.LimitObserver    = (*PromMetrics)(nil)
+	_ core.ShadowObserver   = (*PromMetrics)(nil)
+)
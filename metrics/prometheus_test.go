@@ -6,6 +6,7 @@ import (
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestNewPrometheusCollector(t *testing.T) {
@@ -34,6 +35,23 @@ func TestPromMetrics_ObserveLatency(t *testing.T) {
 	pm.ObserveLatency(100 * time.Millisecond)
 }
 
+func TestPromMetrics_ObserveLimit(t *testing.T) {
+	pm := NewPrometheusCollector("test_limit", "sub")
+	pm.ObserveLimit(42)
+	if got := testutil.ToFloat64(pm.limit); got != 42 {
+		t.Fatalf("expected limit gauge 42, got %v", got)
+	}
+}
+
+func TestPromMetrics_IncShadowDeny(t *testing.T) {
+	pm := NewPrometheusCollector("test_shadow", "sub")
+	pm.IncShadowDeny()
+	pm.IncShadowDeny()
+	if got := testutil.ToFloat64(pm.shadow); got != 2 {
+		t.Fatalf("expected shadow deny counter 2, got %v", got)
+	}
+}
+
 func TestPromMetrics_ImplementsInterface(t *testing.T) {
 	var _ core.MetricsCollector = (*PromMetrics)(nil)
 }
@@ -8,6 +8,7 @@
 package echomw
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -33,6 +34,10 @@ type Config struct {
 	// ErrorHandler is called when the limiter returns an error.
 	// Defaults to a JSON 500 response if nil.
 	ErrorHandler func(c echo.Context, err error) error
+
+	// ShadowHeaders adds X-RateLimit-Shadow (allow or deny), X-RateLimit-Shadow-Limit and
+	// X-RateLimit-Shadow-Remaining to responses of limiters in shadow mode.
+	ShadowHeaders bool
 }
 
 // configDefault fills in zero-value fields with sensible defaults.
@@ -62,14 +67,27 @@ func configDefault(cfg ...Config) Config {
 // It extracts a key per request, calls limiter.Allow, sets standard
 // RateLimit-* headers, and either passes the request through or
 // returns 429 Too Many Requests.
+//
+// If limiter is a core.LeaseLimiter (e.g. the Concurrency strategy), the slot
+// is acquired with Acquire and released as soon as the next handler returns.
 func RateLimit(limiter core.Limiter, cfg ...Config) echo.MiddlewareFunc {
 	c := configDefault(cfg...)
+	leaser, leased := limiter.(core.LeaseLimiter)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
 			key := c.KeyFunc(ctx)
 
-			res, err := limiter.Allow(ctx.Request().Context(), key)
+			var (
+				lease core.Lease
+				res   core.Result
+				err   error
+			)
+			if leased {
+				lease, res, err = leaser.Acquire(ctx.Request().Context(), key)
+			} else {
+				res, err = limiter.Allow(ctx.Request().Context(), key)
+			}
 			if err != nil {
 				if c.ErrorHandler != nil {
 					return c.ErrorHandler(ctx, err)
@@ -80,6 +98,9 @@ func RateLimit(limiter core.Limiter, cfg ...Config) echo.MiddlewareFunc {
 
 			// Set standard rate-limit headers
 			setHeaders(ctx, res)
+			if c.ShadowHeaders {
+				setShadowHeaders(ctx, res)
+			}
 
 			if !res.Allowed {
 				if c.DeniedHandler != nil {
@@ -91,21 +112,39 @@ func RateLimit(limiter core.Limiter, cfg ...Config) echo.MiddlewareFunc {
 				})
 			}
 
+			if leased {
+				// Release even if the client went away; the slot is held until the handler is done.
+				defer func() { _ = leaser.Release(context.WithoutCancel(ctx.Request().Context()), lease) }()
+			}
+
 			return next(ctx)
 		}
 	}
 }
 
 // RateLimitByResource returns an Echo middleware that applies resource-scoped rate limiting.
+//
+// If limiter is a core.ResourceLeaseLimiter, as the one from gorl.NewResourceLimiter is, slots
+// of resources using the Concurrency strategy are released as soon as the next handler returns.
 func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) echo.MiddlewareFunc {
 	c := configDefault(cfg...)
+	leaser, leased := limiter.(core.ResourceLeaseLimiter)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
 			key := c.KeyFunc(ctx)
 			resource := c.ResourceFunc(ctx)
 
-			res, err := limiter.AllowResource(ctx.Request().Context(), resource, key)
+			var (
+				lease core.Lease
+				res   core.Result
+				err   error
+			)
+			if leased {
+				lease, res, err = leaser.AcquireResource(ctx.Request().Context(), resource, key)
+			} else {
+				res, err = limiter.AllowResource(ctx.Request().Context(), resource, key)
+			}
 			if err != nil {
 				if c.ErrorHandler != nil {
 					return c.ErrorHandler(ctx, err)
@@ -115,6 +154,9 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) echo.Middl
 			}
 
 			setHeaders(ctx, res)
+			if c.ShadowHeaders {
+				setShadowHeaders(ctx, res)
+			}
 
 			if !res.Allowed {
 				if c.DeniedHandler != nil {
@@ -126,6 +168,10 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) echo.Middl
 				})
 			}
 
+			if leased {
+				defer func() { _ = leaser.ReleaseResource(context.WithoutCancel(ctx.Request().Context()), resource, lease) }()
+			}
+
 			return next(ctx)
 		}
 	}
@@ -133,6 +179,14 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) echo.Middl
 
 // setHeaders writes standard rate-limit headers to the Echo response.
 func setHeaders(c echo.Context, res core.Result) {
+	// Requests decided by an allowlist or denylist have no rate-limit state to report.
+	if res.Bypassed {
+		return
+	}
+	// Shadow mode does not enforce its decisions, so they are only reported as shadow headers.
+	if res.Shadow {
+		return
+	}
 	h := c.Response().Header()
 	h.Set("RateLimit-Limit", fmt.Sprintf("%d", res.Limit))
 	h.Set("RateLimit-Remaining", fmt.Sprintf("%d", res.Remaining))
@@ -143,3 +197,18 @@ func setHeaders(c echo.Context, res core.Result) {
 		h.Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(res.RetryAfter.Seconds()))))
 	}
 }
+
+// setShadowHeaders writes the decision of a limiter in shadow mode to the Echo response.
+func setShadowHeaders(c echo.Context, res core.Result) {
+	if !res.Shadow {
+		return
+	}
+	decision := "allow"
+	if res.ShadowDenied {
+		decision = "deny"
+	}
+	h := c.Response().Header()
+	h.Set("X-RateLimit-Shadow", decision)
+	h.Set("X-RateLimit-Shadow-Limit", fmt.Sprintf("%d", res.Limit))
+	h.Set("X-RateLimit-Shadow-Remaining", fmt.Sprintf("%d", res.Remaining))
+}
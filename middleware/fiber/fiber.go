@@ -8,6 +8,7 @@
 package fibermw
 
 import (
+	"context"
 	"fmt"
 	"math"
 
@@ -32,6 +33,10 @@ type Config struct {
 	// ErrorHandler is called when the limiter returns an error.
 	// Defaults to a JSON 500 response if nil.
 	ErrorHandler func(c *fiber.Ctx, err error) error
+
+	// ShadowHeaders adds X-RateLimit-Shadow (allow or deny), X-RateLimit-Shadow-Limit and
+	// X-RateLimit-Shadow-Remaining to responses of limiters in shadow mode.
+	ShadowHeaders bool
 }
 
 // configDefault fills in zero-value fields with sensible defaults.
@@ -58,13 +63,26 @@ func configDefault(cfg ...Config) Config {
 // It extracts a key per request, calls limiter.Allow, sets standard
 // RateLimit-* headers, and either passes the request through or
 // returns 429 Too Many Requests.
+//
+// If limiter is a core.LeaseLimiter (e.g. the Concurrency strategy), the slot
+// is acquired with Acquire and released as soon as the next handler returns.
 func RateLimit(limiter core.Limiter, cfg ...Config) fiber.Handler {
 	c := configDefault(cfg...)
+	leaser, leased := limiter.(core.LeaseLimiter)
 
 	return func(ctx *fiber.Ctx) error {
 		key := c.KeyFunc(ctx)
 
-		res, err := limiter.Allow(ctx.UserContext(), key)
+		var (
+			lease core.Lease
+			res   core.Result
+			err   error
+		)
+		if leased {
+			lease, res, err = leaser.Acquire(ctx.UserContext(), key)
+		} else {
+			res, err = limiter.Allow(ctx.UserContext(), key)
+		}
 		if err != nil {
 			if c.ErrorHandler != nil {
 				return c.ErrorHandler(ctx, err)
@@ -75,6 +93,9 @@ func RateLimit(limiter core.Limiter, cfg ...Config) fiber.Handler {
 
 		// Set standard rate-limit headers
 		setHeaders(ctx, res)
+		if c.ShadowHeaders {
+			setShadowHeaders(ctx, res)
+		}
 
 		if !res.Allowed {
 			if c.DeniedHandler != nil {
@@ -87,19 +108,37 @@ func RateLimit(limiter core.Limiter, cfg ...Config) fiber.Handler {
 				})
 		}
 
+		if leased {
+			// Release even if the client went away; the slot is held until the handler is done.
+			defer func() { _ = leaser.Release(context.WithoutCancel(ctx.UserContext()), lease) }()
+		}
+
 		return ctx.Next()
 	}
 }
 
 // RateLimitByResource returns a Fiber middleware that applies resource-scoped rate limiting.
+//
+// If limiter is a core.ResourceLeaseLimiter, as the one from gorl.NewResourceLimiter is, slots
+// of resources using the Concurrency strategy are released as soon as the next handler returns.
 func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) fiber.Handler {
 	c := configDefault(cfg...)
+	leaser, leased := limiter.(core.ResourceLeaseLimiter)
 
 	return func(ctx *fiber.Ctx) error {
 		key := c.KeyFunc(ctx)
 		resource := c.ResourceFunc(ctx)
 
-		res, err := limiter.AllowResource(ctx.UserContext(), resource, key)
+		var (
+			lease core.Lease
+			res   core.Result
+			err   error
+		)
+		if leased {
+			lease, res, err = leaser.AcquireResource(ctx.UserContext(), resource, key)
+		} else {
+			res, err = limiter.AllowResource(ctx.UserContext(), resource, key)
+		}
 		if err != nil {
 			if c.ErrorHandler != nil {
 				return c.ErrorHandler(ctx, err)
@@ -109,6 +148,9 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) fiber.Hand
 		}
 
 		setHeaders(ctx, res)
+		if c.ShadowHeaders {
+			setShadowHeaders(ctx, res)
+		}
 
 		if !res.Allowed {
 			if c.DeniedHandler != nil {
@@ -121,12 +163,24 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) fiber.Hand
 				})
 		}
 
+		if leased {
+			defer func() { _ = leaser.ReleaseResource(context.WithoutCancel(ctx.UserContext()), resource, lease) }()
+		}
+
 		return ctx.Next()
 	}
 }
 
 // setHeaders writes standard rate-limit headers to the Fiber response.
 func setHeaders(c *fiber.Ctx, res core.Result) {
+	// Requests decided by an allowlist or denylist have no rate-limit state to report.
+	if res.Bypassed {
+		return
+	}
+	// Shadow mode does not enforce its decisions, so they are only reported as shadow headers.
+	if res.Shadow {
+		return
+	}
 	c.Set("RateLimit-Limit", fmt.Sprintf("%d", res.Limit))
 	c.Set("RateLimit-Remaining", fmt.Sprintf("%d", res.Remaining))
 	if res.Reset > 0 {
@@ -136,3 +190,17 @@ func setHeaders(c *fiber.Ctx, res core.Result) {
 		c.Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(res.RetryAfter.Seconds()))))
 	}
 }
+
+// setShadowHeaders writes the decision of a limiter in shadow mode to the Fiber response.
+func setShadowHeaders(c *fiber.Ctx, res core.Result) {
+	if !res.Shadow {
+		return
+	}
+	decision := "allow"
+	if res.ShadowDenied {
+		decision = "deny"
+	}
+	c.Set("X-RateLimit-Shadow", decision)
+	c.Set("X-RateLimit-Shadow-Limit", fmt.Sprintf("%d", res.Limit))
+	c.Set("X-RateLimit-Shadow-Remaining", fmt.Sprintf("%d", res.Remaining))
+}
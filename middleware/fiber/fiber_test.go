@@ -21,6 +21,18 @@ type mockLimiter struct {
 func (m *mockLimiter) Allow(_ context.Context, _ string) (core.Result, error) {
 	return m.result, m.err
 }
+func (m *mockLimiter) AllowN(ctx context.Context, key string, _ int) (core.Result, error) {
+	return m.Allow(ctx, key)
+}
+
+func (m *mockLimiter) Peek(_ context.Context, _ string) (core.Result, error) {
+	return m.result, m.err
+}
+
+func (m *mockLimiter) Refund(_ context.Context, _ string, _ int) error { return nil }
+
+func (m *mockLimiter) Reset(_ context.Context, _ string) error { return nil }
+
 func (m *mockLimiter) Close() error { return nil }
 
 type mockResourceLimiter struct {
@@ -35,8 +47,57 @@ func (m *mockResourceLimiter) AllowResource(_ context.Context, resource, key str
 	m.key = key
 	return m.result, m.err
 }
+func (m *mockResourceLimiter) AllowResourceN(ctx context.Context, resource, key string, _ int) (core.Result, error) {
+	return m.AllowResource(ctx, resource, key)
+}
+
+func (m *mockResourceLimiter) PeekResource(_ context.Context, _, _ string) (core.Result, error) {
+	return m.result, m.err
+}
+
+func (m *mockResourceLimiter) RefundResource(_ context.Context, _, _ string, _ int) error { return nil }
+
+func (m *mockResourceLimiter) ResetResource(_ context.Context, _, _ string) error { return nil }
+
 func (m *mockResourceLimiter) Close() error { return nil }
 
+type mockLeaseLimiter struct {
+	mockLimiter
+	held     int
+	released int
+}
+
+func (m *mockLeaseLimiter) Acquire(_ context.Context, key string) (core.Lease, core.Result, error) {
+	m.held++
+	return core.Lease{Key: key, ID: "1"}, core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (m *mockLeaseLimiter) Release(_ context.Context, lease core.Lease) error {
+	if lease.ID == "1" {
+		m.released++
+	}
+	return nil
+}
+
+type mockResourceLeaseLimiter struct {
+	mockResourceLimiter
+	held     int
+	released int
+}
+
+func (m *mockResourceLeaseLimiter) AcquireResource(_ context.Context, resource, key string) (core.Lease, core.Result, error) {
+	m.resource = resource
+	m.held++
+	return core.Lease{Key: key, ID: "1"}, core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (m *mockResourceLeaseLimiter) ReleaseResource(_ context.Context, resource string, lease core.Lease) error {
+	if resource == m.resource && lease.ID == "1" {
+		m.released++
+	}
+	return nil
+}
+
 func TestRateLimit_Allowed(t *testing.T) {
 	app := fiber.New()
 	limiter := &mockLimiter{result: core.Result{
@@ -165,3 +226,62 @@ func TestRateLimitByResource_UsesRequestPath(t *testing.T) {
 		t.Fatal("expected key to be populated")
 	}
 }
+
+func TestRateLimit_BypassedSkipsHeaders(t *testing.T) {
+	app := fiber.New()
+	limiter := &mockLimiter{result: core.Result{Allowed: true, Bypassed: true}}
+	app.Use(RateLimit(limiter))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("RateLimit-Limit") != "" {
+		t.Error("headers should not be set for bypassed requests")
+	}
+}
+
+func TestRateLimit_ReleasesLeaseWhenHandlerReturns(t *testing.T) {
+	app := fiber.New()
+	limiter := &mockLeaseLimiter{}
+	app.Use(RateLimit(limiter))
+	app.Get("/", func(c *fiber.Ctx) error {
+		if limiter.held != 1 || limiter.released != 0 {
+			t.Errorf("expected lease to be held while the handler runs, held=%d released=%d", limiter.held, limiter.released)
+		}
+		return c.SendStatus(http.StatusOK)
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.released != 1 {
+		t.Fatalf("expected lease to be released once, got %d", limiter.released)
+	}
+}
+
+func TestRateLimitByResource_ReleasesLeaseWhenHandlerReturns(t *testing.T) {
+	app := fiber.New()
+	limiter := &mockResourceLeaseLimiter{}
+	app.Use(RateLimitByResource(limiter))
+	app.Get("/report", func(c *fiber.Ctx) error {
+		if limiter.held != 1 || limiter.released != 0 {
+			t.Errorf("expected lease to be held while the handler runs, held=%d released=%d", limiter.held, limiter.released)
+		}
+		return c.SendStatus(http.StatusOK)
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/report", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.released != 1 {
+		t.Fatalf("expected lease to be released once, got %d", limiter.released)
+	}
+}
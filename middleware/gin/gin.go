@@ -8,6 +8,7 @@
 package ginmw
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -33,6 +34,10 @@ type Config struct {
 	// ErrorHandler is called when the limiter returns an error.
 	// Defaults to a JSON 500 response if nil.
 	ErrorHandler func(c *gin.Context, err error)
+
+	// ShadowHeaders adds X-RateLimit-Shadow (allow or deny), X-RateLimit-Shadow-Limit and
+	// X-RateLimit-Shadow-Remaining to responses of limiters in shadow mode.
+	ShadowHeaders bool
 }
 
 // configDefault fills in zero-value fields with sensible defaults.
@@ -62,13 +67,26 @@ func configDefault(cfg ...Config) Config {
 // It extracts a key per request, calls limiter.Allow, sets standard
 // RateLimit-* headers, and either passes the request through or
 // returns 429 Too Many Requests.
+//
+// If limiter is a core.LeaseLimiter (e.g. the Concurrency strategy), the slot
+// is acquired with Acquire and released as soon as the next handler returns.
 func RateLimit(limiter core.Limiter, cfg ...Config) gin.HandlerFunc {
 	c := configDefault(cfg...)
+	leaser, leased := limiter.(core.LeaseLimiter)
 
 	return func(ctx *gin.Context) {
 		key := c.KeyFunc(ctx)
 
-		res, err := limiter.Allow(ctx.Request.Context(), key)
+		var (
+			lease core.Lease
+			res   core.Result
+			err   error
+		)
+		if leased {
+			lease, res, err = leaser.Acquire(ctx.Request.Context(), key)
+		} else {
+			res, err = limiter.Allow(ctx.Request.Context(), key)
+		}
 		if err != nil {
 			if c.ErrorHandler != nil {
 				c.ErrorHandler(ctx, err)
@@ -82,6 +100,9 @@ func RateLimit(limiter core.Limiter, cfg ...Config) gin.HandlerFunc {
 
 		// Set standard rate-limit headers
 		setHeaders(ctx, res)
+		if c.ShadowHeaders {
+			setShadowHeaders(ctx, res)
+		}
 
 		if !res.Allowed {
 			if c.DeniedHandler != nil {
@@ -96,19 +117,37 @@ func RateLimit(limiter core.Limiter, cfg ...Config) gin.HandlerFunc {
 			return
 		}
 
+		if leased {
+			// Release even if the client went away; the slot is held until the handler is done.
+			defer func() { _ = leaser.Release(context.WithoutCancel(ctx.Request.Context()), lease) }()
+		}
+
 		ctx.Next()
 	}
 }
 
 // RateLimitByResource returns a Gin middleware that applies resource-scoped rate limiting.
+//
+// If limiter is a core.ResourceLeaseLimiter, as the one from gorl.NewResourceLimiter is, slots
+// of resources using the Concurrency strategy are released as soon as the next handler returns.
 func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) gin.HandlerFunc {
 	c := configDefault(cfg...)
+	leaser, leased := limiter.(core.ResourceLeaseLimiter)
 
 	return func(ctx *gin.Context) {
 		key := c.KeyFunc(ctx)
 		resource := c.ResourceFunc(ctx)
 
-		res, err := limiter.AllowResource(ctx.Request.Context(), resource, key)
+		var (
+			lease core.Lease
+			res   core.Result
+			err   error
+		)
+		if leased {
+			lease, res, err = leaser.AcquireResource(ctx.Request.Context(), resource, key)
+		} else {
+			res, err = limiter.AllowResource(ctx.Request.Context(), resource, key)
+		}
 		if err != nil {
 			if c.ErrorHandler != nil {
 				c.ErrorHandler(ctx, err)
@@ -121,6 +160,9 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) gin.Handle
 		}
 
 		setHeaders(ctx, res)
+		if c.ShadowHeaders {
+			setShadowHeaders(ctx, res)
+		}
 
 		if !res.Allowed {
 			if c.DeniedHandler != nil {
@@ -135,12 +177,24 @@ func RateLimitByResource(limiter core.ResourceLimiter, cfg ...Config) gin.Handle
 			return
 		}
 
+		if leased {
+			defer func() { _ = leaser.ReleaseResource(context.WithoutCancel(ctx.Request.Context()), resource, lease) }()
+		}
+
 		ctx.Next()
 	}
 }
 
 // setHeaders writes standard rate-limit headers to the Gin response.
 func setHeaders(c *gin.Context, res core.Result) {
+	// Requests decided by an allowlist or denylist have no rate-limit state to report.
+	if res.Bypassed {
+		return
+	}
+	// Shadow mode does not enforce its decisions, so they are only reported as shadow headers.
+	if res.Shadow {
+		return
+	}
 	c.Header("RateLimit-Limit", fmt.Sprintf("%d", res.Limit))
 	c.Header("RateLimit-Remaining", fmt.Sprintf("%d", res.Remaining))
 	if res.Reset > 0 {
@@ -150,3 +204,17 @@ func setHeaders(c *gin.Context, res core.Result) {
 		c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(res.RetryAfter.Seconds()))))
 	}
 }
+
+// setShadowHeaders writes the decision of a limiter in shadow mode to the Gin response.
+func setShadowHeaders(c *gin.Context, res core.Result) {
+	if !res.Shadow {
+		return
+	}
+	decision := "allow"
+	if res.ShadowDenied {
+		decision = "deny"
+	}
+	c.Header("X-RateLimit-Shadow", decision)
+	c.Header("X-RateLimit-Shadow-Limit", fmt.Sprintf("%d", res.Limit))
+	c.Header("X-RateLimit-Shadow-Remaining", fmt.Sprintf("%d", res.Remaining))
+}
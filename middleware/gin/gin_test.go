@@ -20,6 +20,18 @@ type mockLimiter struct {
 func (m *mockLimiter) Allow(_ context.Context, _ string) (core.Result, error) {
 	return m.result, m.err
 }
+func (m *mockLimiter) AllowN(ctx context.Context, key string, _ int) (core.Result, error) {
+	return m.Allow(ctx, key)
+}
+
+func (m *mockLimiter) Peek(_ context.Context, _ string) (core.Result, error) {
+	return m.result, m.err
+}
+
+func (m *mockLimiter) Refund(_ context.Context, _ string, _ int) error { return nil }
+
+func (m *mockLimiter) Reset(_ context.Context, _ string) error { return nil }
+
 func (m *mockLimiter) Close() error { return nil }
 
 type mockResourceLimiter struct {
@@ -34,8 +46,57 @@ func (m *mockResourceLimiter) AllowResource(_ context.Context, resource, key str
 	m.key = key
 	return m.result, m.err
 }
+func (m *mockResourceLimiter) AllowResourceN(ctx context.Context, resource, key string, _ int) (core.Result, error) {
+	return m.AllowResource(ctx, resource, key)
+}
+
+func (m *mockResourceLimiter) PeekResource(_ context.Context, _, _ string) (core.Result, error) {
+	return m.result, m.err
+}
+
+func (m *mockResourceLimiter) RefundResource(_ context.Context, _, _ string, _ int) error { return nil }
+
+func (m *mockResourceLimiter) ResetResource(_ context.Context, _, _ string) error { return nil }
+
 func (m *mockResourceLimiter) Close() error { return nil }
 
+type mockLeaseLimiter struct {
+	mockLimiter
+	held     int
+	released int
+}
+
+func (m *mockLeaseLimiter) Acquire(_ context.Context, key string) (core.Lease, core.Result, error) {
+	m.held++
+	return core.Lease{Key: key, ID: "1"}, core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (m *mockLeaseLimiter) Release(_ context.Context, lease core.Lease) error {
+	if lease.ID == "1" {
+		m.released++
+	}
+	return nil
+}
+
+type mockResourceLeaseLimiter struct {
+	mockResourceLimiter
+	held     int
+	released int
+}
+
+func (m *mockResourceLeaseLimiter) AcquireResource(_ context.Context, resource, key string) (core.Lease, core.Result, error) {
+	m.resource = resource
+	m.held++
+	return core.Lease{Key: key, ID: "1"}, core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (m *mockResourceLeaseLimiter) ReleaseResource(_ context.Context, resource string, lease core.Lease) error {
+	if resource == m.resource && lease.ID == "1" {
+		m.released++
+	}
+	return nil
+}
+
 func init() {
 	gin.SetMode(gin.TestMode)
 }
@@ -151,3 +212,57 @@ func TestRateLimitByResource_UsesRoutePattern(t *testing.T) {
 		t.Fatalf("expected key 10.0.0.1, got %q", limiter.key)
 	}
 }
+
+func TestRateLimit_BypassedSkipsHeaders(t *testing.T) {
+	limiter := &mockLimiter{result: core.Result{Allowed: false, Bypassed: true}}
+	r := gin.New()
+	r.Use(RateLimit(limiter))
+	r.GET("/", func(c *gin.Context) { c.String(200, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "" || rec.Header().Get("Retry-After") != "" {
+		t.Error("headers should not be set for bypassed requests")
+	}
+}
+
+func TestRateLimit_ReleasesLeaseWhenHandlerReturns(t *testing.T) {
+	limiter := &mockLeaseLimiter{}
+	r := gin.New()
+	r.Use(RateLimit(limiter))
+	r.GET("/", func(c *gin.Context) {
+		if limiter.held != 1 || limiter.released != 0 {
+			t.Errorf("expected lease to be held while the handler runs, held=%d released=%d", limiter.held, limiter.released)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if limiter.released != 1 {
+		t.Fatalf("expected lease to be released once, got %d", limiter.released)
+	}
+}
+
+func TestRateLimitByResource_ReleasesLeaseWhenHandlerReturns(t *testing.T) {
+	limiter := &mockResourceLeaseLimiter{}
+	r := gin.New()
+	r.Use(RateLimitByResource(limiter))
+	r.GET("/report", func(c *gin.Context) {
+		if limiter.held != 1 || limiter.released != 0 {
+			t.Errorf("expected lease to be held while the handler runs, held=%d released=%d", limiter.held, limiter.released)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/report", nil))
+
+	if limiter.released != 1 {
+		t.Fatalf("expected lease to be released once, got %d", limiter.released)
+	}
+}
@@ -57,6 +57,10 @@ type Options struct {
 	// SetHeaders controls whether standard rate-limit headers are added to every response.
 	// Defaults to true.
 	SetHeaders *bool
+
+	// ShadowHeaders adds X-RateLimit-Shadow (allow or deny), X-RateLimit-Shadow-Limit and
+	// X-RateLimit-Shadow-Remaining to responses of limiters in shadow mode.
+	ShadowHeaders bool
 }
 
 // shouldSetHeaders returns whether rate-limit headers should be added.
@@ -71,6 +75,8 @@ func (o Options) shouldSetHeaders() bool {
 
 // KeyByIP returns a KeyFunc that extracts the client IP address.
 // It checks X-Forwarded-For and X-Real-Ip headers before falling back to RemoteAddr.
+// Clients can set those headers themselves, so only use it with an IP allowlist behind a proxy
+// that overwrites them.
 func KeyByIP() KeyFunc {
 	return func(r *http.Request) string {
 		// Check X-Forwarded-For first (proxied requests)
@@ -120,6 +126,14 @@ func ResourceByPath() ResourceFunc {
 	}
 }
 
+// ResourceByMethodAndPath returns a ResourceFunc that qualifies the request path with its method,
+// as in "GET /api/v1/users", so resource rules can match on the method.
+func ResourceByMethodAndPath() ResourceFunc {
+	return func(r *http.Request) string {
+		return r.Method + " " + r.URL.Path
+	}
+}
+
 // --- Middleware ---
 
 // RateLimit returns an http.Handler middleware that applies rate limiting.
@@ -129,12 +143,27 @@ func ResourceByPath() ResourceFunc {
 // or returns a 429 Too Many Requests response.
 //
 // Standard rate-limit headers (RateLimit-Limit, RateLimit-Remaining,
-// RateLimit-Reset, Retry-After) are set on all responses by default.
+// RateLimit-Reset, Retry-After) are set on all responses by default, except
+// for requests an allowlist or denylist decided (Result.Bypassed).
+//
+// If limiter is a core.LeaseLimiter (e.g. the Concurrency strategy), the slot
+// is acquired with Acquire and released as soon as the next handler returns.
 func RateLimit(limiter core.Limiter, opts Options, next http.Handler) http.Handler {
+	leaser, leased := limiter.(core.LeaseLimiter)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := opts.KeyFunc(r)
 
-		res, err := limiter.Allow(r.Context(), key)
+		var (
+			lease core.Lease
+			res   core.Result
+			err   error
+		)
+		if leased {
+			lease, res, err = leaser.Acquire(r.Context(), key)
+		} else {
+			res, err = limiter.Allow(r.Context(), key)
+		}
 		if err != nil {
 			if opts.OnError != nil {
 				opts.OnError(w, r, err)
@@ -148,6 +177,9 @@ func RateLimit(limiter core.Limiter, opts Options, next http.Handler) http.Handl
 		if opts.shouldSetHeaders() {
 			setRateLimitHeaders(w, res)
 		}
+		if opts.ShadowHeaders {
+			setShadowHeaders(w, res)
+		}
 
 		if !res.Allowed {
 			if opts.OnDenied != nil {
@@ -160,6 +192,11 @@ func RateLimit(limiter core.Limiter, opts Options, next http.Handler) http.Handl
 			return
 		}
 
+		if leased {
+			// Release even if the client went away; the slot is held until the handler is done.
+			defer func() { _ = leaser.Release(context.WithoutCancel(r.Context()), lease) }()
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -169,14 +206,27 @@ func RateLimit(limiter core.Limiter, opts Options, next http.Handler) http.Handl
 // For every incoming request, it extracts both a resource using opts.ResourceFunc
 // and a key using opts.KeyFunc, calls limiter.AllowResource, and either passes the
 // request to the next handler or returns a 429 Too Many Requests response.
+//
+// If limiter is a core.ResourceLeaseLimiter, as the one from gorl.NewResourceLimiter is, slots
+// of resources using the Concurrency strategy are released as soon as the next handler returns.
 func RateLimitByResource(limiter core.ResourceLimiter, opts Options, next http.Handler) http.Handler {
 	resourceFunc := opts.resourceFunc()
+	leaser, leased := limiter.(core.ResourceLeaseLimiter)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := opts.KeyFunc(r)
 		resource := resourceFunc(r)
 
-		res, err := limiter.AllowResource(r.Context(), resource, key)
+		var (
+			lease core.Lease
+			res   core.Result
+			err   error
+		)
+		if leased {
+			lease, res, err = leaser.AcquireResource(r.Context(), resource, key)
+		} else {
+			res, err = limiter.AllowResource(r.Context(), resource, key)
+		}
 		if err != nil {
 			if opts.OnError != nil {
 				opts.OnError(w, r, err)
@@ -189,6 +239,9 @@ func RateLimitByResource(limiter core.ResourceLimiter, opts Options, next http.H
 		if opts.shouldSetHeaders() {
 			setRateLimitHeaders(w, res)
 		}
+		if opts.ShadowHeaders {
+			setShadowHeaders(w, res)
+		}
 
 		if !res.Allowed {
 			if opts.OnDenied != nil {
@@ -201,6 +254,10 @@ func RateLimitByResource(limiter core.ResourceLimiter, opts Options, next http.H
 			return
 		}
 
+		if leased {
+			defer func() { _ = leaser.ReleaseResource(context.WithoutCancel(r.Context()), resource, lease) }()
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -239,6 +296,14 @@ func NewResourceMiddleware(limiter core.ResourceLimiter, opts Options) func(http
 
 // setRateLimitHeaders writes standard rate-limit headers to the response.
 func setRateLimitHeaders(w http.ResponseWriter, res core.Result) {
+	// Requests decided by an allowlist or denylist have no rate-limit state to report.
+	if res.Bypassed {
+		return
+	}
+	// Shadow mode does not enforce its decisions, so they are only reported as shadow headers.
+	if res.Shadow {
+		return
+	}
 	w.Header().Set("RateLimit-Limit", fmt.Sprintf("%d", res.Limit))
 	w.Header().Set("RateLimit-Remaining", fmt.Sprintf("%d", res.Remaining))
 
@@ -269,3 +334,17 @@ func (o Options) resourceFunc() ResourceFunc {
 	}
 	return o.ResourceFunc
 }
+
+// setShadowHeaders writes the decision of a limiter in shadow mode to the response.
+func setShadowHeaders(w http.ResponseWriter, res core.Result) {
+	if !res.Shadow {
+		return
+	}
+	decision := "allow"
+	if res.ShadowDenied {
+		decision = "deny"
+	}
+	w.Header().Set("X-RateLimit-Shadow", decision)
+	w.Header().Set("X-RateLimit-Shadow-Limit", fmt.Sprintf("%d", res.Limit))
+	w.Header().Set("X-RateLimit-Shadow-Remaining", fmt.Sprintf("%d", res.Remaining))
+}
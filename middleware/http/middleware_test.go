@@ -22,6 +22,18 @@ func (m *mockLimiter) Allow(_ context.Context, _ string) (core.Result, error) {
 	return m.result, m.err
 }
 
+func (m *mockLimiter) AllowN(ctx context.Context, key string, _ int) (core.Result, error) {
+	return m.Allow(ctx, key)
+}
+
+func (m *mockLimiter) Peek(_ context.Context, _ string) (core.Result, error) {
+	return m.result, m.err
+}
+
+func (m *mockLimiter) Refund(_ context.Context, _ string, _ int) error { return nil }
+
+func (m *mockLimiter) Reset(_ context.Context, _ string) error { return nil }
+
 func (m *mockLimiter) Close() error { return nil }
 
 type mockResourceLimiter struct {
@@ -37,6 +49,18 @@ func (m *mockResourceLimiter) AllowResource(_ context.Context, resource, key str
 	return m.result, m.err
 }
 
+func (m *mockResourceLimiter) AllowResourceN(ctx context.Context, resource, key string, _ int) (core.Result, error) {
+	return m.AllowResource(ctx, resource, key)
+}
+
+func (m *mockResourceLimiter) PeekResource(_ context.Context, _, _ string) (core.Result, error) {
+	return m.result, m.err
+}
+
+func (m *mockResourceLimiter) RefundResource(_ context.Context, _, _ string, _ int) error { return nil }
+
+func (m *mockResourceLimiter) ResetResource(_ context.Context, _, _ string) error { return nil }
+
 func (m *mockResourceLimiter) Close() error { return nil }
 
 // --- Tests ---
@@ -319,3 +343,140 @@ func TestRateLimit_OmitsZeroDurationHeaders(t *testing.T) {
 		t.Fatalf("expected Retry-After to be omitted, got %q", rec.Header().Get("Retry-After"))
 	}
 }
+
+type mockLeaseLimiter struct {
+	mockLimiter
+	held     int
+	released int
+}
+
+func (m *mockLeaseLimiter) Acquire(_ context.Context, key string) (core.Lease, core.Result, error) {
+	m.held++
+	return core.Lease{Key: key, ID: "1"}, core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (m *mockLeaseLimiter) Release(_ context.Context, lease core.Lease) error {
+	if lease.ID == "1" {
+		m.released++
+	}
+	return nil
+}
+
+func TestRateLimit_ReleasesLeaseWhenHandlerReturns(t *testing.T) {
+	limiter := &mockLeaseLimiter{}
+
+	handler := RateLimit(limiter, Options{KeyFunc: KeyByIP()}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter.held != 1 || limiter.released != 0 {
+			t.Errorf("expected lease to be held while the handler runs, held=%d released=%d", limiter.held, limiter.released)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/report", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if limiter.released != 1 {
+		t.Fatalf("expected lease to be released once, got %d", limiter.released)
+	}
+}
+
+type mockResourceLeaseLimiter struct {
+	mockResourceLimiter
+	held     int
+	released int
+}
+
+func (m *mockResourceLeaseLimiter) AcquireResource(_ context.Context, resource, key string) (core.Lease, core.Result, error) {
+	m.resource = resource
+	m.held++
+	return core.Lease{Key: key, ID: "1"}, core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (m *mockResourceLeaseLimiter) ReleaseResource(_ context.Context, resource string, lease core.Lease) error {
+	if resource == m.resource && lease.ID == "1" {
+		m.released++
+	}
+	return nil
+}
+
+func TestRateLimitByResource_ReleasesLeaseWhenHandlerReturns(t *testing.T) {
+	limiter := &mockResourceLeaseLimiter{}
+
+	handler := RateLimitByResource(limiter, Options{KeyFunc: KeyByIP()}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter.held != 1 || limiter.released != 0 {
+			t.Errorf("expected lease to be held while the handler runs, held=%d released=%d", limiter.held, limiter.released)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/report", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if limiter.released != 1 {
+		t.Fatalf("expected lease to be released once, got %d", limiter.released)
+	}
+}
+
+func TestRateLimit_BypassedSkipsHeaders(t *testing.T) {
+	limiter := &mockLimiter{result: core.Result{Allowed: true, Bypassed: true}}
+
+	handler := RateLimit(limiter, Options{KeyFunc: KeyByIP()},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "1.2.3.4:80"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "" {
+		t.Error("headers should not be set for bypassed requests")
+	}
+}
+
+func TestRateLimit_ShadowHeaders(t *testing.T) {
+	limiter := &mockLimiter{result: core.Result{
+		Allowed: true, Shadow: true, ShadowDenied: true, Limit: 10, RetryAfter: time.Second,
+	}}
+
+	handler := RateLimit(limiter, Options{KeyFunc: KeyByIP(), ShadowHeaders: true},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "1.2.3.4:80"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "" {
+		t.Error("standard headers should not advertise shadow limits")
+	}
+	if rec.Header().Get("X-RateLimit-Shadow") != "deny" || rec.Header().Get("X-RateLimit-Shadow-Limit") != "10" {
+		t.Errorf("unexpected shadow headers: %v", rec.Header())
+	}
+}
+
+func TestResourceByMethodAndPath(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/users", nil)
+	if got := ResourceByMethodAndPath()(req); got != "POST /api/v1/users" {
+		t.Errorf("expected 'POST /api/v1/users', got %s", got)
+	}
+}
@@ -2,23 +2,35 @@ package gorl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
 	"github.com/AliRizaAynaci/gorl/v2/storage"
 )
 
 type resourceRouter struct {
-	defaultLimiter core.Limiter
-	limiters       map[string]core.Limiter
-	store          storage.Storage
-	closeOnce      sync.Once
-	closeErr       error
+	routes    atomic.Pointer[resourceRoutes]
+	store     storage.Storage
+	local     storage.Storage // In-memory store of the fallback limiters, created on first use
+	updateMu  sync.Mutex
+	closeOnce sync.Once
+	closeErr  error
 }
 
-type evalScriptCapable interface {
-	EvalScript(ctx context.Context, name string, keys []string, args ...int64) ([]int64, error)
+// resourceRoutes is one immutable set of policies. Update replaces the whole set at once, so a
+// request is served entirely by either the old or the new policies.
+type resourceRoutes struct {
+	cfg            core.ResourceConfig
+	bypass         *core.Bypass
+	defaultLimiter core.Limiter
+	limiters       map[string]core.Limiter
+	rules          *core.ResourceMatcher
+	ruleLimiters   []core.Limiter
 }
 
 type sharedStore struct {
@@ -31,7 +43,7 @@ func (s sharedStore) Close() error {
 
 type sharedScriptStore struct {
 	storage.Storage
-	runner evalScriptCapable
+	runner storage.ScriptRunner
 }
 
 func (s sharedScriptStore) Close() error {
@@ -42,52 +54,331 @@ func (s sharedScriptStore) EvalScript(ctx context.Context, name string, keys []s
 	return s.runner.EvalScript(ctx, name, keys, args...)
 }
 
-func newResourceRouter(
-	cfg core.ResourceConfig,
-	store storage.Storage,
-	constructor func(core.Config, storage.Storage) core.Limiter,
-) core.ResourceLimiter {
-	defaultLimiter := constructor(resourceConfigToCore(cfg, cfg.DefaultPolicy), wrapSharedStore(store))
+type sharedPipelineStore struct {
+	sharedScriptStore
+	pipeliner storage.ScriptPipeliner
+}
+
+func (s sharedPipelineStore) EvalScripts(ctx context.Context, calls []storage.ScriptCall) []storage.ScriptReply {
+	return s.pipeliner.EvalScripts(ctx, calls)
+}
+
+func newResourceRouter(cfg core.ResourceConfig, store storage.Storage) (core.ResourceLimiter, error) {
+	r := &resourceRouter{store: store}
+	local, err := r.fallbackStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := newResourceRoutes(cfg, store, local, nil)
+	if err != nil {
+		if r.local != nil {
+			_ = r.local.Close()
+		}
+		return nil, err
+	}
+	r.routes.Store(routes)
+	return r, nil
+}
+
+// fallbackStore returns the in-memory store for the fallback limiters of cfg, or nil when cfg
+// asks for none. r.updateMu must be held once r is in use.
+func (r *resourceRouter) fallbackStore(cfg core.ResourceConfig) (storage.Storage, error) {
+	if cfg.Fallback.Instances == 0 || cfg.RedisURL == "" {
+		return nil, nil
+	}
+	if r.local == nil {
+		local, err := newStore("", cfg.Clock)
+		if err != nil {
+			return nil, err
+		}
+		r.local = local
+	}
+	return r.local, nil
+}
+
+// newResourceRoutes builds a limiter per policy of a validated cfg, each with the strategy,
+// fail-open behaviour and metrics collector the policy overrides or inherits. With a local store,
+// each limiter falls back to one on it while store fails. Limiters in prev whose configuration
+// is unchanged are kept rather than rebuilt.
+func newResourceRoutes(cfg core.ResourceConfig, store, local storage.Storage, prev map[string]builtLimiter) (*resourceRoutes, error) {
+	defaultLimiter, err := newPolicyLimiter(cfg, cfg.DefaultPolicy, store, local, prev[defaultRouteName])
+	if err != nil {
+		return nil, err
+	}
 	limiters := make(map[string]core.Limiter, len(cfg.Resources))
 	for resource, policy := range cfg.Resources {
-		limiters[resource] = constructor(resourceConfigToCore(cfg, policy), wrapSharedStore(store))
+		limiter, err := newPolicyLimiter(cfg, policy, store, local, prev[resourceRouteName(resource)])
+		if err != nil {
+			return nil, fmt.Errorf("resource %q: %w", resource, err)
+		}
+		limiters[resource] = limiter
 	}
-
-	return &resourceRouter{
+	ruleLimiters := make([]core.Limiter, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		limiter, err := newPolicyLimiter(cfg, rule.Policy, store, local, prev[ruleRouteName(rule.Name)])
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		ruleLimiters[i] = limiter
+	}
+	// cfg has been validated, so the bypass policy and the rules compile.
+	bypass, _ := cfg.Bypass.Compile()
+	rules, _ := core.CompileRules(cfg.Rules)
+	return &resourceRoutes{
+		cfg:            cfg,
+		bypass:         bypass,
 		defaultLimiter: defaultLimiter,
 		limiters:       limiters,
-		store:          store,
+		rules:          rules,
+		ruleLimiters:   ruleLimiters,
+	}, nil
+}
+
+// newPolicyLimiter builds the limiter of policy, or returns prev.limiter when prev was built
+// from the same configuration.
+func newPolicyLimiter(cfg core.ResourceConfig, policy core.ResourcePolicy, store, local storage.Storage, prev builtLimiter) (core.Limiter, error) {
+	limiterCfg := resourceConfigToCore(cfg, policy)
+	if prev.limiter != nil && sameLimiterConfig(prev.cfg, limiterCfg) {
+		return prev.limiter, nil
+	}
+	constructor, ok := lookupStrategy(limiterCfg.Strategy)
+	if !ok {
+		return nil, core.ErrUnknownStrategy
+	}
+	limiter := buildLimiter(constructor, limiterCfg, wrapSharedStore(store))
+	if local != nil {
+		limiter = buildFallback(constructor, limiterCfg, limiter, wrapSharedStore(local))
+	}
+	return limiter, nil
+}
+
+// builtLimiter is a policy limiter together with the configuration it was built from.
+type builtLimiter struct {
+	cfg     core.Config
+	limiter core.Limiter
+}
+
+const defaultRouteName = "default"
+
+func resourceRouteName(resource string) string { return "resource:" + resource }
+
+func ruleRouteName(name string) string { return "rule:" + name }
+
+// built returns the limiters of routes by route name, for the next set to keep.
+func (routes *resourceRoutes) built() map[string]builtLimiter {
+	built := make(map[string]builtLimiter, 1+len(routes.limiters)+len(routes.ruleLimiters))
+	built[defaultRouteName] = builtLimiter{resourceConfigToCore(routes.cfg, routes.cfg.DefaultPolicy), routes.defaultLimiter}
+	for resource, limiter := range routes.limiters {
+		built[resourceRouteName(resource)] = builtLimiter{resourceConfigToCore(routes.cfg, routes.cfg.Resources[resource]), limiter}
+	}
+	for i, rule := range routes.cfg.Rules {
+		built[ruleRouteName(rule.Name)] = builtLimiter{resourceConfigToCore(routes.cfg, rule.Policy), routes.ruleLimiters[i]}
+	}
+	return built
+}
+
+// limiterSpec holds the settings of a limiter configuration that can be compared with ==.
+// core.Config itself cannot be: its policies hold slices, and a collector may be of an
+// uncomparable type.
+type limiterSpec struct {
+	strategy core.StrategyType
+	limit    int
+	window   time.Duration
+	period   core.Period
+	burst    int
+	rate     float64
+	shadow   bool
+	location *time.Location
+	failOpen bool
+	fallback core.FallbackPolicy
+}
+
+func specOf(cfg core.Config) limiterSpec {
+	return limiterSpec{
+		strategy: cfg.Strategy,
+		limit:    cfg.Limit,
+		window:   cfg.Window,
+		period:   cfg.Period,
+		burst:    cfg.Burst,
+		rate:     cfg.Rate,
+		shadow:   cfg.Shadow,
+		location: cfg.Location,
+		failOpen: cfg.FailOpen,
+		fallback: cfg.Fallback,
+	}
+}
+
+// sameLimiterConfig reports whether a and b, as built by resourceConfigToCore, build the same
+// limiter.
+func sameLimiterConfig(a, b core.Config) bool {
+	return specOf(a) == specOf(b) && sameValue(a.Metrics, b.Metrics) && sameValue(a.Clock, b.Clock)
+}
+
+// sameValue reports whether a and b hold the same value, treating values of uncomparable
+// types as different instead of panicking.
+func sameValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
 	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// Update swaps in the policies of cfg. Requests already running finish on the old policies;
+// state stored per resource and key carries over and is read under the new limits. Limiters of
+// policies that did not change are kept; the others are rebuilt, so state their strategy keeps
+// in process memory, such as Sliding Log and Concurrency without Redis, starts over.
+func (r *resourceRouter) Update(cfg core.ResourceConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+	current := r.routes.Load().cfg
+	if cfg.RedisURL != current.RedisURL {
+		return fmt.Errorf("%w: redis url cannot change without a new limiter", core.ErrConfigInvalid)
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = current.Metrics
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = current.Clock
+	}
+	local, err := r.fallbackStore(cfg)
+	if err != nil {
+		return err
+	}
+	routes, err := newResourceRoutes(cfg, r.store, local, r.routes.Load().built())
+	if err != nil {
+		return err
+	}
+	r.routes.Store(routes)
+	return nil
 }
 
 func (r *resourceRouter) AllowResource(ctx context.Context, resource, key string) (core.Result, error) {
-	limiter, ok := r.limiters[resource]
-	if !ok {
-		limiter = r.defaultLimiter
+	return r.AllowResourceN(ctx, resource, key, 1)
+}
+
+func (r *resourceRouter) AllowResourceN(ctx context.Context, resource, key string, n int) (core.Result, error) {
+	routes := r.routes.Load()
+	if res, ok := routes.bypass.Check(key); ok {
+		return res, nil
+	}
+	limiter, storageKey := routes.route(resource, key)
+	res, err := limiter.AllowN(ctx, storageKey, n)
+	if err == nil && res.ShadowDenied && routes.cfg.OnShadowDeny != nil {
+		routes.cfg.OnShadowDeny(ctx, resource, key, res)
+	}
+	return res, err
+}
+
+func (r *resourceRouter) AcquireResource(ctx context.Context, resource, key string) (core.Lease, core.Result, error) {
+	routes := r.routes.Load()
+	if res, ok := routes.bypass.Check(key); ok {
+		return core.Lease{}, res, nil
+	}
+	limiter, storageKey := routes.route(resource, key)
+	var lease core.Lease
+	var res core.Result
+	var err error
+	if leaser, ok := limiter.(core.LeaseLimiter); ok {
+		lease, res, err = leaser.Acquire(ctx, storageKey)
+	} else {
+		res, err = limiter.AllowN(ctx, storageKey, 1)
+	}
+	if err == nil && res.ShadowDenied && routes.cfg.OnShadowDeny != nil {
+		routes.cfg.OnShadowDeny(ctx, resource, key, res)
 	}
-	return limiter.Allow(ctx, buildResourceKey(resource, key))
+	return lease, res, err
+}
+
+// ReleaseResource gives lease back to the limiter of resource. Lease.Key already holds the
+// storage key the lease was acquired under.
+func (r *resourceRouter) ReleaseResource(ctx context.Context, resource string, lease core.Lease) error {
+	if lease.ID == "" {
+		return nil
+	}
+	limiter, _ := r.routes.Load().route(resource, lease.Key)
+	if leaser, ok := limiter.(core.LeaseLimiter); ok {
+		return leaser.Release(ctx, lease)
+	}
+	return nil
+}
+
+func (r *resourceRouter) PeekResource(ctx context.Context, resource, key string) (core.Result, error) {
+	routes := r.routes.Load()
+	if res, ok := routes.bypass.Check(key); ok {
+		return res, nil
+	}
+	limiter, storageKey := routes.route(resource, key)
+	return limiter.Peek(ctx, storageKey)
+}
+
+func (r *resourceRouter) RefundResource(ctx context.Context, resource, key string, n int) error {
+	limiter, storageKey := r.routes.Load().route(resource, key)
+	return limiter.Refund(ctx, storageKey, n)
+}
+
+func (r *resourceRouter) ResetResource(ctx context.Context, resource, key string) error {
+	limiter, storageKey := r.routes.Load().route(resource, key)
+	return limiter.Reset(ctx, storageKey)
+}
+
+// route returns the limiter for resource and the key to store the state of key under: an exact
+// resource first, then the first matching rule, whose name becomes the namespace, then the default.
+func (routes *resourceRoutes) route(resource, key string) (core.Limiter, string) {
+	if limiter, ok := routes.limiters[resource]; ok {
+		return limiter, buildResourceKey(resource, key)
+	}
+	if i := routes.rules.Match(resource); i >= 0 {
+		return routes.ruleLimiters[i], buildRuleKey(routes.cfg.Rules[i].Name, key)
+	}
+	return routes.defaultLimiter, buildResourceKey(resource, key)
 }
 
 func (r *resourceRouter) Close() error {
 	r.closeOnce.Do(func() {
+		r.updateMu.Lock()
+		local := r.local
+		r.updateMu.Unlock()
 		r.closeErr = r.store.Close()
+		if local != nil {
+			r.closeErr = errors.Join(r.closeErr, local.Close())
+		}
 	})
 	return r.closeErr
 }
 
+// resourceConfigToCore returns the limiter configuration of policy, applying its overrides of cfg.
 func resourceConfigToCore(cfg core.ResourceConfig, policy core.ResourcePolicy) core.Config {
+	metrics := cfg.Metrics
+	if policy.Metrics != nil {
+		metrics = policy.Metrics
+	}
 	return core.Config{
-		Strategy: cfg.Strategy,
+		Strategy: cfg.StrategyFor(policy),
 		Limit:    policy.Limit,
 		Window:   policy.Window,
+		Period:   policy.Period,
+		Burst:    policy.Burst,
+		Rate:     policy.Rate,
+		Shadow:   policy.Shadow,
+		Location: cfg.Location,
 		RedisURL: cfg.RedisURL,
-		FailOpen: cfg.FailOpen,
-		Metrics:  cfg.Metrics,
+		FailOpen: cfg.FailOpenFor(policy),
+		Fallback: cfg.Fallback,
+		Metrics:  metrics,
+		Clock:    cfg.Clock,
 	}
 }
 
 func wrapSharedStore(store storage.Storage) storage.Storage {
-	if runner, ok := store.(evalScriptCapable); ok {
+	if pipeliner, ok := store.(storage.ScriptPipeliner); ok {
+		return sharedPipelineStore{sharedScriptStore: sharedScriptStore{Storage: store, runner: pipeliner}, pipeliner: pipeliner}
+	}
+	if runner, ok := store.(storage.ScriptRunner); ok {
 		return sharedScriptStore{Storage: store, runner: runner}
 	}
 	return sharedStore{Storage: store}
@@ -96,3 +387,9 @@ func wrapSharedStore(store storage.Storage) storage.Storage {
 func buildResourceKey(resource, key string) string {
 	return fmt.Sprintf("%d:%s:%s", len(resource), resource, key)
 }
+
+// buildRuleKey namespaces the keys of a rule apart from every resource key, which starts with a
+// digit, so a resource named like a rule never shares its state.
+func buildRuleKey(name, key string) string {
+	return "rule:" + buildResourceKey(name, key)
+}
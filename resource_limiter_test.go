@@ -3,10 +3,13 @@ package gorl
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/AliRizaAynaci/gorl/v2/core"
+	"github.com/AliRizaAynaci/gorl/v2/storage"
+	"github.com/AliRizaAynaci/gorl/v2/storage/inmem"
 )
 
 func TestNewResourceLimiter_AllStrategies(t *testing.T) {
@@ -15,6 +18,9 @@ func TestNewResourceLimiter_AllStrategies(t *testing.T) {
 		core.SlidingWindow,
 		core.TokenBucket,
 		core.LeakyBucket,
+		core.SlidingLog,
+		core.GCRA,
+		core.Concurrency,
 	}
 
 	for _, strategy := range strategies {
@@ -121,3 +127,540 @@ func TestNewResourceLimiter_UnknownStrategy(t *testing.T) {
 		t.Fatalf("expected ErrUnknownStrategy, got %v", err)
 	}
 }
+
+func TestNewResourceLimiter_AllowResourceN(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.TokenBucket,
+		DefaultPolicy: core.ResourcePolicy{Limit: 10, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"export": {Limit: 50, Window: time.Minute},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	res, err := limiter.AllowResourceN(ctx, "export", "tenant-a", 50)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected export cost to be allowed, got %v, err %v", res.Allowed, err)
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected export capacity to be exhausted, got remaining=%d", res.Remaining)
+	}
+
+	if _, err := limiter.AllowResourceN(ctx, "search", "tenant-a", 50); !errors.Is(err, core.ErrCostExceedsLimit) {
+		t.Fatalf("expected default policy to reject cost above its limit, got %v", err)
+	}
+}
+
+func TestNewResourceLimiter_PeekResourceUsesResourcePolicy(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.SlidingWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 10, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"login": {Limit: 2, Window: time.Minute},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if _, err := limiter.AllowResource(ctx, "login", "user-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := limiter.PeekResource(ctx, "login", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Limit != 2 || res.Remaining != 1 {
+		t.Fatalf("expected login policy state with one unit left, got %+v", res)
+	}
+
+	res, err = limiter.PeekResource(ctx, "login", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Remaining != 1 {
+		t.Fatalf("expected repeated peeks to leave state untouched, got %+v", res)
+	}
+}
+
+func TestNewResourceLimiter_ResetResourceOnlyClearsThatResource(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.TokenBucket,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for _, resource := range []string{"login", "search"} {
+		if _, err := limiter.AllowResource(ctx, resource, "user-123"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := limiter.ResetResource(ctx, "login", "user-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	login, err := limiter.AllowResource(ctx, "login", "user-123")
+	if err != nil || !login.Allowed {
+		t.Fatalf("expected login to be admitted after reset, got %v, err %v", login.Allowed, err)
+	}
+	search, err := limiter.AllowResource(ctx, "search", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if search.Allowed {
+		t.Fatal("expected search to stay throttled")
+	}
+}
+
+func TestResourceLimiter_Update(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+	reloadable, ok := limiter.(core.ReloadableResourceLimiter)
+	if !ok {
+		t.Fatal("expected the resource limiter to be reloadable")
+	}
+
+	ctx := context.Background()
+	limiter.AllowResource(ctx, "search", "user-123")
+	if res, _ := limiter.AllowResource(ctx, "search", "user-123"); res.Allowed {
+		t.Fatal("expected search to be throttled by the default policy")
+	}
+
+	err = reloadable.Update(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"search": {Limit: 3, Window: time.Minute},
+		},
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// The request counted before the update still counts against the new limit.
+	for i := 0; i < 2; i++ {
+		if res, err := limiter.AllowResource(ctx, "search", "user-123"); err != nil || !res.Allowed {
+			t.Fatalf("req %d: expected allowed under the new policy, got %v, err %v", i+1, res.Allowed, err)
+		}
+	}
+	res, err := limiter.AllowResource(ctx, "search", "user-123")
+	if err != nil || res.Allowed || res.Limit != 3 {
+		t.Fatalf("expected denial at the new limit of 3, got allowed=%v limit=%d err=%v", res.Allowed, res.Limit, err)
+	}
+}
+
+func TestResourceLimiter_UpdateKeepsUnchangedLimiters(t *testing.T) {
+	cfg := core.ResourceConfig{
+		Strategy:      core.SlidingLog,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"login":  {Limit: 1, Window: time.Minute},
+			"search": {Limit: 1, Window: time.Minute},
+		},
+	}
+	limiter, err := NewResourceLimiter(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for _, resource := range []string{"login", "search", "other"} {
+		limiter.AllowResource(ctx, resource, "user-1")
+	}
+
+	cfg.Resources = map[string]core.ResourcePolicy{
+		"login":  {Limit: 1, Window: time.Minute},
+		"search": {Limit: 1, Window: time.Hour},
+	}
+	if err := limiter.(core.ReloadableResourceLimiter).Update(cfg); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// Sliding logs live in process memory: unchanged policies keep theirs, a changed one starts over.
+	if res, _ := limiter.AllowResource(ctx, "login", "user-1"); res.Allowed {
+		t.Fatal("expected login to keep its log across the update")
+	}
+	if res, _ := limiter.AllowResource(ctx, "other", "user-1"); res.Allowed {
+		t.Fatal("expected the default policy to keep its log across the update")
+	}
+	if res, _ := limiter.AllowResource(ctx, "search", "user-1"); !res.Allowed {
+		t.Fatal("expected search to be rebuilt under its new window")
+	}
+}
+
+func TestSameValue(t *testing.T) {
+	collector := &countingMetrics{}
+	if !sameValue(collector, collector) || sameValue(collector, &countingMetrics{}) {
+		t.Fatal("expected pointers to compare by identity")
+	}
+	if !sameValue(nil, nil) || sameValue(collector, nil) {
+		t.Fatal("expected nil to only match nil")
+	}
+	uncomparable := []int{1}
+	if sameValue(uncomparable, uncomparable) {
+		t.Fatal("expected uncomparable values to never match")
+	}
+}
+
+func TestResourceLimiter_UpdateRejectsInvalidConfig(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+	reloadable := limiter.(core.ReloadableResourceLimiter)
+
+	invalid := []core.ResourceConfig{
+		{Strategy: core.FixedWindow, DefaultPolicy: core.ResourcePolicy{Limit: 0, Window: time.Minute}},
+		{Strategy: core.FixedWindow, DefaultPolicy: core.ResourcePolicy{Limit: 5, Window: time.Minute}, RedisURL: "redis://localhost:6379"},
+	}
+	for _, cfg := range invalid {
+		if err := reloadable.Update(cfg); !errors.Is(err, core.ErrConfigInvalid) {
+			t.Fatalf("expected ErrConfigInvalid, got %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	limiter.AllowResource(ctx, "search", "user-123")
+	if res, _ := limiter.AllowResource(ctx, "search", "user-123"); res.Allowed {
+		t.Fatal("expected the original policy to stay in place")
+	}
+}
+
+func TestResourceLimiter_UpdateDuringTraffic(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.TokenBucket,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1000, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+	reloadable := limiter.(core.ReloadableResourceLimiter)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := limiter.AllowResource(ctx, "search", "user-123"); err != nil {
+					t.Errorf("unexpected error during update: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 20; i++ {
+		err := reloadable.Update(core.ResourceConfig{
+			Strategy:      core.TokenBucket,
+			DefaultPolicy: core.ResourcePolicy{Limit: 1000 + i, Window: time.Minute},
+		})
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestResourceLimiter_Bypass(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Bypass:        core.BypassPolicy{Allow: core.AccessList{Keys: []string{"monitor"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if res, err := limiter.AllowResource(ctx, "search", "monitor"); err != nil || !res.Allowed || !res.Bypassed {
+			t.Fatalf("req %d: expected allowlisted key to bypass the limit, got %+v, err %v", i+1, res, err)
+		}
+	}
+
+	// Reloading replaces the lists along with the policies.
+	err = limiter.(core.ReloadableResourceLimiter).Update(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Bypass:        core.BypassPolicy{Deny: core.AccessList{Keys: []string{"monitor"}}},
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if res, err := limiter.AllowResource(ctx, "search", "monitor"); err != nil || res.Allowed || !res.Bypassed {
+		t.Fatalf("expected denylisted key to be rejected, got %+v, err %v", res, err)
+	}
+}
+
+func TestResourceLimiter_ShadowPolicy(t *testing.T) {
+	var denied []string
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"search": {Limit: 1, Window: time.Minute, Shadow: true},
+		},
+		OnShadowDeny: func(_ context.Context, resource, key string, _ core.Result) {
+			denied = append(denied, resource+"/"+key)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		limiter.AllowResource(ctx, "search", "user-1")
+		limiter.AllowResource(ctx, "login", "user-1")
+	}
+	if res, _ := limiter.AllowResource(ctx, "search", "user-1"); !res.Allowed || !res.ShadowDenied {
+		t.Fatalf("expected search to be shadowed, got %+v", res)
+	}
+	if res, _ := limiter.AllowResource(ctx, "login", "user-1"); res.Allowed {
+		t.Fatal("expected login to stay enforced")
+	}
+	if len(denied) != 2 || denied[0] != "search/user-1" {
+		t.Fatalf("expected the hook to see the resource and caller key, got %v", denied)
+	}
+}
+
+// countingMetrics counts the decisions reported to it.
+type countingMetrics struct {
+	mu     sync.Mutex
+	allows int
+	denies int
+}
+
+func (m *countingMetrics) IncAllow()                      { m.mu.Lock(); m.allows++; m.mu.Unlock() }
+func (m *countingMetrics) IncDeny()                       { m.mu.Lock(); m.denies++; m.mu.Unlock() }
+func (m *countingMetrics) ObserveLatency(_ time.Duration) {}
+
+func TestResourceLimiter_PolicyOverrides(t *testing.T) {
+	failClosed, failOpen := false, true
+	loginMetrics := &countingMetrics{}
+	defaultMetrics := &countingMetrics{}
+	cfg := core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		FailOpen:      true,
+		DefaultPolicy: core.ResourcePolicy{Limit: 10, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"login":  {Limit: 2, Window: time.Minute, Strategy: core.SlidingLog, FailOpen: &failClosed, Metrics: loginMetrics},
+			"search": {Limit: 5, Window: time.Second, Strategy: core.TokenBucket, FailOpen: &failOpen},
+		},
+		Metrics: defaultMetrics,
+	}
+
+	login := resourceConfigToCore(cfg, cfg.Resources["login"])
+	if login.Strategy != core.SlidingLog || login.FailOpen || login.Metrics != loginMetrics {
+		t.Fatalf("expected login overrides to apply, got %+v", login)
+	}
+	if other := resourceConfigToCore(cfg, cfg.DefaultPolicy); other.Strategy != core.FixedWindow || !other.FailOpen || other.Metrics != defaultMetrics {
+		t.Fatalf("expected the default policy to inherit the config, got %+v", other)
+	}
+
+	limiter, err := NewResourceLimiter(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		limiter.AllowResource(ctx, "login", "user-1")
+	}
+	limiter.AllowResource(ctx, "other", "user-1")
+	if loginMetrics.allows != 2 || loginMetrics.denies != 1 {
+		t.Fatalf("expected login decisions on its own collector, got %d allows and %d denies", loginMetrics.allows, loginMetrics.denies)
+	}
+	if defaultMetrics.allows != 1 || defaultMetrics.denies != 0 {
+		t.Fatalf("expected other decisions on the config collector, got %d allows and %d denies", defaultMetrics.allows, defaultMetrics.denies)
+	}
+}
+
+func TestResourceLimiter_UnknownPolicyStrategy(t *testing.T) {
+	_, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"login": {Limit: 1, Window: time.Minute, Strategy: "nope"},
+		},
+	})
+	if !errors.Is(err, core.ErrUnknownStrategy) {
+		t.Fatalf("expected ErrUnknownStrategy, got %v", err)
+	}
+}
+
+func TestResourceLimiter_Rules(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 100, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"/api/v1/users/me": {Limit: 100, Window: time.Minute},
+		},
+		Rules: []core.ResourceRule{
+			{Name: "users", Match: core.MatchPrefix, Pattern: "/api/v1/users/", Policy: core.ResourcePolicy{Limit: 2, Window: time.Minute}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	// Every matched resource counts against the rule's namespace.
+	limiter.AllowResource(ctx, "/api/v1/users/1", "k")
+	limiter.AllowResource(ctx, "/api/v1/users/2", "k")
+	res, err := limiter.AllowResource(ctx, "/api/v1/users/3", "k")
+	if err != nil || res.Allowed || res.Limit != 2 {
+		t.Fatalf("expected the rule to deny the third user request, got %+v, err %v", res, err)
+	}
+	if res, _ := limiter.PeekResource(ctx, "/api/v1/users/9", "k"); res.Allowed {
+		t.Fatal("expected Peek to read the rule's shared state")
+	}
+
+	// Exact resources take precedence over rules.
+	if res, _ := limiter.AllowResource(ctx, "/api/v1/users/me", "k"); !res.Allowed || res.Limit != 100 {
+		t.Fatalf("expected the exact resource to win, got %+v", res)
+	}
+
+	if err := limiter.ResetResource(ctx, "/api/v1/users/42", "k"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if res, _ := limiter.AllowResource(ctx, "/api/v1/users/1", "k"); !res.Allowed {
+		t.Fatal("expected Reset through any matched resource to clear the rule's state")
+	}
+}
+
+func TestResourceLimiter_RuleNamespaceIsolated(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 1, Window: time.Minute},
+		Rules: []core.ResourceRule{
+			{Name: "users", Match: core.MatchPrefix, Pattern: "/users/", Policy: core.ResourcePolicy{Limit: 1, Window: time.Minute}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if res, _ := limiter.AllowResource(ctx, "/users/1", "k"); !res.Allowed {
+		t.Fatal("expected the rule to admit the first request")
+	}
+	for _, resource := range []string{"users", "rule:users"} {
+		if res, _ := limiter.AllowResource(ctx, resource, "k"); !res.Allowed {
+			t.Fatalf("expected default resource %q not to share the rule's state", resource)
+		}
+	}
+}
+
+func TestResourceLimiter_AcquireAndReleaseResource(t *testing.T) {
+	limiter, err := NewResourceLimiter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 10, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"report": {Limit: 1, Window: time.Minute, Strategy: core.Concurrency},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+	leaser := limiter.(core.ResourceLeaseLimiter)
+	ctx := context.Background()
+
+	lease, res, err := leaser.AcquireResource(ctx, "report", "user-1")
+	if err != nil || !res.Allowed || lease.ID == "" {
+		t.Fatalf("expected a lease, got %+v, %+v, err %v", lease, res, err)
+	}
+	if _, res, _ := leaser.AcquireResource(ctx, "report", "user-1"); res.Allowed {
+		t.Fatal("expected the only slot to be held")
+	}
+	if err := leaser.ReleaseResource(ctx, "report", lease); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, res, _ := leaser.AcquireResource(ctx, "report", "user-1"); !res.Allowed {
+		t.Fatal("expected the released slot to be available")
+	}
+
+	lease, res, err = leaser.AcquireResource(ctx, "search", "user-1")
+	if err != nil || !res.Allowed || lease.ID != "" {
+		t.Fatalf("expected a plain admission for a non-lease resource, got %+v, %+v, err %v", lease, res, err)
+	}
+	if err := leaser.ReleaseResource(ctx, "search", lease); err != nil {
+		t.Fatalf("releasing the zero lease should be a no-op, got %v", err)
+	}
+}
+
+// downStore fails every counter call, standing in for an unreachable Redis.
+type downStore struct {
+	storage.Storage
+}
+
+func (downStore) IncrBy(context.Context, string, float64, time.Duration) (float64, error) {
+	return 0, errors.New("backend down")
+}
+
+func (downStore) Get(context.Context, string) (float64, error) {
+	return 0, errors.New("backend down")
+}
+
+func TestResourceLimiter_Fallback(t *testing.T) {
+	limiter, err := newResourceRouter(core.ResourceConfig{
+		Strategy:      core.FixedWindow,
+		DefaultPolicy: core.ResourcePolicy{Limit: 8, Window: time.Minute},
+		Resources: map[string]core.ResourcePolicy{
+			"login": {Limit: 4, Window: time.Minute},
+		},
+		RedisURL: "redis://localhost:6379/0",
+		Fallback: core.FallbackPolicy{Instances: 2},
+		Metrics:  &core.NoopMetrics{},
+	}, downStore{inmem.NewInMemoryStore()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		res, err := limiter.AllowResource(ctx, "login", "k")
+		if err != nil || !res.Allowed || !res.Fallback || res.Limit != 2 {
+			t.Fatalf("req %d: expected a local admission under half the limit, got %+v, err %v", i+1, res, err)
+		}
+	}
+	if res, _ := limiter.AllowResource(ctx, "login", "k"); res.Allowed || !res.Fallback {
+		t.Fatalf("expected the local limit to deny, got %+v", res)
+	}
+	if res, _ := limiter.AllowResource(ctx, "search", "k"); !res.Allowed || res.Limit != 4 {
+		t.Fatalf("expected the default policy to fall back separately, got %+v", res)
+	}
+}
@@ -14,8 +14,9 @@ import (
 const defaultGCInterval = 1 * time.Minute
 
 type inMemoryStore struct {
-	data sync.Map // Map of string -> *item
-	done chan struct{}
+	data  sync.Map // Map of string -> *item
+	clock storage.Clock // nil -> time.Now
+	done  chan struct{}
 }
 
 type item struct {
@@ -26,13 +27,28 @@ type item struct {
 // NewInMemoryStore returns a storage with a background garbage collector
 // that cleans up expired entries every minute.
 func NewInMemoryStore() storage.Storage {
+	return NewInMemoryStoreWithClock(nil)
+}
+
+// NewInMemoryStoreWithClock is like NewInMemoryStore but decides expiry with clock.
+// The garbage collector still runs on real time; it only drops entries clock considers expired.
+// A nil clock means the system clock.
+func NewInMemoryStoreWithClock(clock storage.Clock) storage.Storage {
 	s := &inMemoryStore{
-		done: make(chan struct{}),
+		clock: clock,
+		done:  make(chan struct{}),
 	}
 	go s.gc(defaultGCInterval)
 	return s
 }
 
+func (s *inMemoryStore) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
 // gc periodically removes expired entries from the store.
 func (s *inMemoryStore) gc(interval time.Duration) {
 	ticker := time.NewTicker(interval)
@@ -49,7 +65,7 @@ func (s *inMemoryStore) gc(interval time.Duration) {
 
 // removeExpired deletes all entries whose TTL has passed.
 func (s *inMemoryStore) removeExpired() {
-	now := time.Now().UnixNano()
+	now := s.now().UnixNano()
 	s.data.Range(func(key, value any) bool {
 		it := value.(*item)
 		if it.expiresAt < now {
@@ -63,34 +79,40 @@ func (s *inMemoryStore) removeExpired() {
 
 // Incr atomically increments the value at key by 1.
 // If missing or expired, initializes to 1 with the given TTL.
-func (s *inMemoryStore) Incr(_ context.Context, key string, ttl time.Duration) (float64, error) {
+func (s *inMemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (float64, error) {
+	return s.IncrBy(ctx, key, 1, ttl)
+}
+
+// IncrBy atomically adds delta to the value at key.
+// If missing or expired, initializes to delta with the given TTL.
+func (s *inMemoryStore) IncrBy(_ context.Context, key string, delta float64, ttl time.Duration) (float64, error) {
 	for {
 		val, loaded := s.data.Load(key)
 		if !loaded {
 			// Try to initialize
 			newItem := &item{
-				value:     math.Float64bits(1.0),
-				expiresAt: time.Now().Add(ttl).UnixNano(),
+				value:     math.Float64bits(delta),
+				expiresAt: s.now().Add(ttl).UnixNano(),
 			}
 			actual, loaded := s.data.LoadOrStore(key, newItem)
 			if !loaded {
-				return 1.0, nil
+				return delta, nil
 			}
 			val = actual // Use the item that won the race
 		}
 
 		it := val.(*item)
-		now := time.Now().UnixNano()
+		now := s.now().UnixNano()
 
 		// Check expiry
 		if it.expiresAt < now {
 			newItem := &item{
-				value:     math.Float64bits(1.0),
-				expiresAt: time.Now().Add(ttl).UnixNano(),
+				value:     math.Float64bits(delta),
+				expiresAt: s.now().Add(ttl).UnixNano(),
 			}
 			// Atomic replacement
 			if s.data.CompareAndSwap(key, val, newItem) {
-				return 1.0, nil
+				return delta, nil
 			}
 			continue // Reload and retry
 		}
@@ -99,7 +121,7 @@ func (s *inMemoryStore) Incr(_ context.Context, key string, ttl time.Duration) (
 		for {
 			oldBits := atomic.LoadUint64(&it.value)
 			oldVal := math.Float64frombits(oldBits)
-			newVal := oldVal + 1.0
+			newVal := oldVal + delta
 			newBits := math.Float64bits(newVal)
 
 			if atomic.CompareAndSwapUint64(&it.value, oldBits, newBits) {
@@ -118,7 +140,7 @@ func (s *inMemoryStore) Get(_ context.Context, key string) (float64, error) {
 	}
 
 	it := val.(*item)
-	now := time.Now().UnixNano()
+	now := s.now().UnixNano()
 	if it.expiresAt < now {
 		return 0, nil
 	}
@@ -131,12 +153,20 @@ func (s *inMemoryStore) Get(_ context.Context, key string) (float64, error) {
 func (s *inMemoryStore) Set(_ context.Context, key string, val float64, ttl time.Duration) error {
 	newItem := &item{
 		value:     math.Float64bits(val),
-		expiresAt: time.Now().Add(ttl).UnixNano(),
+		expiresAt: s.now().Add(ttl).UnixNano(),
 	}
 	s.data.Store(key, newItem)
 	return nil
 }
 
+// Delete removes the given keys.
+func (s *inMemoryStore) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		s.data.Delete(key)
+	}
+	return nil
+}
+
 // Close stops the background garbage collector goroutine.
 func (s *inMemoryStore) Close() error {
 	close(s.done)
@@ -5,6 +5,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/clocktest"
 )
 
 func TestInMemoryStore_SetAndGet(t *testing.T) {
@@ -61,6 +63,29 @@ func TestInMemoryStore_GetExpired(t *testing.T) {
 	}
 }
 
+func TestInMemoryStore_ExpiryFollowsClock(t *testing.T) {
+	clock := clocktest.NewManual(time.Now())
+	store := NewInMemoryStoreWithClock(clock)
+	defer store.Close()
+	ctx := context.Background()
+
+	store.Set(ctx, "value", 10.0, time.Hour)
+	store.IncrBy(ctx, "counter", 3, time.Hour)
+
+	clock.Advance(59 * time.Minute)
+	if val, _ := store.Get(ctx, "value"); val != 10 {
+		t.Fatalf("expected value to survive before its TTL, got %f", val)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if val, _ := store.Get(ctx, "value"); val != 0 {
+		t.Fatalf("expected value to expire on the clock, got %f", val)
+	}
+	if val, _ := store.IncrBy(ctx, "counter", 1, time.Hour); val != 1 {
+		t.Fatalf("expected counter to restart after expiry, got %f", val)
+	}
+}
+
 func TestInMemoryStore_Incr_NewKey(t *testing.T) {
 	store := NewInMemoryStore()
 	defer store.Close()
@@ -91,6 +116,50 @@ func TestInMemoryStore_Incr_ExistingKey(t *testing.T) {
 	}
 }
 
+func TestInMemoryStore_IncrBy(t *testing.T) {
+	store := NewInMemoryStore()
+	defer store.Close()
+	ctx := context.Background()
+
+	val, err := store.IncrBy(ctx, "counter", 5, time.Minute)
+	if err != nil {
+		t.Fatalf("IncrBy failed: %v", err)
+	}
+	if val != 5 {
+		t.Fatalf("expected 5, got %f", val)
+	}
+
+	val, err = store.IncrBy(ctx, "counter", -2, time.Minute)
+	if err != nil {
+		t.Fatalf("IncrBy failed: %v", err)
+	}
+	if val != 3 {
+		t.Fatalf("expected 3 after negative delta, got %f", val)
+	}
+}
+
+func TestInMemoryStore_Delete(t *testing.T) {
+	store := NewInMemoryStore()
+	defer store.Close()
+	ctx := context.Background()
+
+	store.Set(ctx, "a", 1, time.Minute)
+	store.Set(ctx, "b", 2, time.Minute)
+	if err := store.Delete(ctx, "a", "b", "missing"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		val, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if val != 0 {
+			t.Fatalf("expected %q to be deleted, got %f", key, val)
+		}
+	}
+}
+
 func TestInMemoryStore_Incr_ExpiredKey(t *testing.T) {
 	store := NewInMemoryStore()
 	defer store.Close()
@@ -0,0 +1,29 @@
+-- One AIMD step on a shared adaptive limit.
+-- ARGV: signal (1 = success, 0 = failure), start, min, max,
+--       increase and decrease factor in millionths, state ttl in ms.
+local success = tonumber(ARGV[1]) == 1
+local start_limit = tonumber(ARGV[2])
+local min_limit = tonumber(ARGV[3])
+local max_limit = tonumber(ARGV[4])
+local increase = tonumber(ARGV[5]) / 1000000
+local decrease = tonumber(ARGV[6]) / 1000000
+local ttl_ms = tonumber(ARGV[7])
+
+local limit = tonumber(redis.call("GET", KEYS[1]) or "0")
+if limit == 0 then
+  limit = start_limit
+end
+
+if success then
+  limit = limit + increase
+else
+  limit = limit * decrease
+end
+if limit < min_limit then
+  limit = min_limit
+elseif limit > max_limit then
+  limit = max_limit
+end
+
+redis.call("SET", KEYS[1], string.format("%.6f", limit), "PX", ttl_ms)
+return {math.floor(limit * 1000000)}
@@ -0,0 +1,314 @@
+-- Checks every policy of a composite limiter and charges all of them only if all allow the request.
+local now_us = tonumber(ARGV[1])
+local cost = tonumber(ARGV[2])
+local count = tonumber(ARGV[3])
+
+-- Each evaluator reads the state of one policy and returns whether it would allow the request,
+-- plus a finish function. finish persists the state, charging cost only when consume is true,
+-- and returns remaining, reset_us and retry_after_us exactly like the policy's own script.
+-- Keep them in step with those scripts; TestRedisAtomicAlgorithms_CompositeMatchesStrategyScripts
+-- compares the results of both.
+local evaluators = {}
+
+-- Fixed window and calendar quota: a = window_end_us, b = ttl_ms.
+evaluators[1] = function(keys, limit, window_end_us, ttl_ms)
+  local used = tonumber(redis.call("GET", keys[1]) or "0")
+  local allowed = used + cost <= limit
+
+  return allowed, function(consume)
+    if consume then
+      used = redis.call("INCRBY", keys[1], cost)
+      redis.call("PEXPIRE", keys[1], ttl_ms)
+    end
+
+    local remaining = limit - used
+    if remaining < 0 then
+      remaining = 0
+    end
+    local reset_us = window_end_us - now_us
+    if reset_us < 0 then
+      reset_us = 0
+    end
+    local retry_after_us = 0
+    if not allowed then
+      retry_after_us = reset_us
+    end
+    return remaining, reset_us, retry_after_us
+  end
+end
+
+-- Sliding window: a = window_us, b = ttl_ms.
+evaluators[2] = function(keys, limit, window_us, ttl_ms)
+  local window_start = tonumber(redis.call("GET", keys[1]) or "0")
+  local curr = tonumber(redis.call("GET", keys[2]) or "0")
+  local prev = tonumber(redis.call("GET", keys[3]) or "0")
+
+  if window_start == 0 then
+    window_start = now_us
+    curr = 0
+    prev = 0
+  else
+    local elapsed = now_us - window_start
+    if elapsed >= window_us then
+      local intervals = math.floor(elapsed / window_us)
+      if intervals == 1 then
+        prev = curr
+      else
+        prev = 0
+      end
+      curr = 0
+      window_start = window_start + (intervals * window_us)
+    end
+  end
+
+  local since = now_us - window_start
+  if since < 0 then
+    since = 0
+  end
+  local ratio = since / window_us
+  local sliding = (prev * (1 - ratio)) + curr
+  local allowed = sliding + cost - 1 < limit
+
+  return allowed, function(consume)
+    if consume then
+      curr = curr + cost
+      sliding = sliding + cost
+    end
+
+    redis.call("SET", keys[1], window_start, "PX", ttl_ms)
+    redis.call("SET", keys[2], curr, "PX", ttl_ms)
+    redis.call("SET", keys[3], prev, "PX", ttl_ms)
+
+    local remaining = math.floor(limit - sliding)
+    if remaining < 0 then
+      remaining = 0
+    end
+
+    local window_until_boundary = window_us - since
+    if window_until_boundary < 0 then
+      window_until_boundary = 0
+    end
+
+    local reset_us = 0
+    if curr > 0 then
+      reset_us = (2 * window_us) - since
+      if reset_us < 0 then
+        reset_us = 0
+      end
+    elseif prev > 0 then
+      reset_us = window_until_boundary
+    end
+
+    local retry_after_us = 0
+    if not allowed then
+      if curr + cost - 1 >= limit then
+        retry_after_us = window_until_boundary
+      elseif prev > 0 then
+        local required_ratio = 1 - ((limit - curr - cost + 1) / prev)
+        local delay_ratio = required_ratio - ratio
+        if delay_ratio < 0 then
+          delay_ratio = 0
+        end
+        retry_after_us = math.ceil(delay_ratio * window_us)
+        if retry_after_us <= 0 then
+          retry_after_us = 1
+        end
+        if retry_after_us > window_until_boundary then
+          retry_after_us = window_until_boundary
+        end
+      else
+        retry_after_us = window_until_boundary
+      end
+    end
+    return remaining, reset_us, retry_after_us
+  end
+end
+
+-- Token bucket: a = ttl_ms, b = time_per_token_us.
+evaluators[3] = function(keys, limit, ttl_ms, time_per_token_us)
+  local tokens = tonumber(redis.call("GET", keys[1]) or "0")
+  local last_refill = tonumber(redis.call("GET", keys[2]) or "0")
+
+  if last_refill == 0 then
+    tokens = limit
+    last_refill = now_us
+  else
+    local elapsed = now_us - last_refill
+    local new_tokens = math.floor(elapsed / time_per_token_us)
+    if new_tokens > 0 then
+      tokens = tokens + new_tokens
+      if tokens > limit then
+        tokens = limit
+      end
+      last_refill = last_refill + (new_tokens * time_per_token_us)
+    end
+  end
+  local allowed = tokens >= cost
+
+  return allowed, function(consume)
+    if consume then
+      tokens = tokens - cost
+    end
+
+    local elapsed_since_refill = now_us - last_refill
+    local missing_tokens = limit - tokens
+    local reset_us = 0
+    if missing_tokens > 0 then
+      reset_us = (missing_tokens * time_per_token_us) - elapsed_since_refill
+      if reset_us < 0 then
+        reset_us = 0
+      end
+    end
+
+    redis.call("SET", keys[1], tokens, "PX", ttl_ms)
+    redis.call("SET", keys[2], last_refill, "PX", ttl_ms)
+
+    local retry_after_us = 0
+    if not allowed then
+      retry_after_us = ((cost - tokens) * time_per_token_us) - elapsed_since_refill
+      if retry_after_us < 0 then
+        retry_after_us = 0
+      end
+    end
+    return tokens, reset_us, retry_after_us
+  end
+end
+
+-- Leaky bucket: a = window_us, b = ttl_ms.
+evaluators[4] = function(keys, limit, window_us, ttl_ms)
+  local us_per_token = math.floor(window_us / limit)
+  if us_per_token < 1 then
+    us_per_token = 1
+  end
+
+  local water = tonumber(redis.call("GET", keys[1]) or "0")
+  local last_leak = tonumber(redis.call("GET", keys[2]) or "0")
+
+  if last_leak == 0 then
+    water = 0
+    last_leak = now_us
+  else
+    local elapsed = now_us - last_leak
+    local leaked = math.floor(elapsed / us_per_token)
+    if leaked > 0 then
+      water = water - leaked
+      if water < 0 then
+        water = 0
+      end
+      last_leak = last_leak + (leaked * us_per_token)
+    end
+  end
+  local allowed = water + cost <= limit
+
+  return allowed, function(consume)
+    if consume then
+      water = water + cost
+    end
+
+    local elapsed_since_leak = now_us - last_leak
+    local reset_us = 0
+    if water > 0 then
+      reset_us = (water * us_per_token) - elapsed_since_leak
+      if reset_us < 0 then
+        reset_us = 0
+      end
+    end
+
+    local remaining = limit - water
+    if remaining < 0 then
+      remaining = 0
+    end
+
+    redis.call("SET", keys[1], water, "PX", ttl_ms)
+    redis.call("SET", keys[2], last_leak, "PX", ttl_ms)
+
+    local retry_after_us = 0
+    if not allowed then
+      retry_after_us = ((water + cost - limit) * us_per_token) - elapsed_since_leak
+      if retry_after_us < 0 then
+        retry_after_us = 0
+      end
+    end
+    return remaining, reset_us, retry_after_us
+  end
+end
+
+-- GCRA: a = emission_us, b is unused.
+evaluators[5] = function(keys, limit, emission_us, _)
+  local period_us = emission_us * limit
+  local tat = tonumber(redis.call("GET", keys[1]) or "0")
+  if tat < now_us then
+    tat = now_us
+  end
+
+  local new_tat = tat + (emission_us * cost)
+  local allow_at = new_tat - period_us
+  local allowed = allow_at <= now_us
+
+  return allowed, function(consume)
+    if consume then
+      tat = new_tat
+      -- The key expires exactly when it has fully recovered.
+      redis.call("SET", keys[1], tat, "PX", math.ceil((tat - now_us) / 1000))
+    end
+
+    local retry_after_us = 0
+    if not allowed then
+      retry_after_us = allow_at - now_us
+    end
+    local reset_us = tat - now_us
+    local remaining = math.floor((period_us - reset_us) / emission_us)
+    return remaining, reset_us, retry_after_us
+  end
+end
+
+local key_counts = {1, 3, 2, 2, 1}
+local policies = {}
+local all_allowed = true
+local next_key = 1
+
+for i = 1, count do
+  local base = 3 + ((i - 1) * 4)
+  local kind = tonumber(ARGV[base + 1])
+  local keys = {}
+  for k = 1, key_counts[kind] do
+    keys[k] = KEYS[next_key]
+    next_key = next_key + 1
+  end
+
+  local allowed, finish = evaluators[kind](keys, tonumber(ARGV[base + 2]), tonumber(ARGV[base + 3]), tonumber(ARGV[base + 4]))
+  policies[i] = {allowed = allowed, finish = finish}
+  all_allowed = all_allowed and allowed
+end
+
+-- Report the most restrictive policy: among denials the longest retry, otherwise the least
+-- remaining capacity (then the longest reset).
+local best = 0
+local best_remaining = 0
+local best_reset = 0
+local best_retry = 0
+
+for i = 1, count do
+  local remaining, reset_us, retry_after_us = policies[i].finish(all_allowed)
+
+  local better
+  if all_allowed then
+    better = best == 0 or remaining < best_remaining or (remaining == best_remaining and reset_us > best_reset)
+  else
+    better = (not policies[i].allowed) and (best == 0 or retry_after_us > best_retry)
+  end
+
+  if better then
+    best = i
+    best_remaining = remaining
+    best_reset = reset_us
+    best_retry = retry_after_us
+  end
+end
+
+local allowed = 0
+if all_allowed then
+  allowed = 1
+end
+
+return {allowed, best_remaining, math.floor(best_reset), math.floor(best_retry), best - 1}
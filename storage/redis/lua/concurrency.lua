@@ -0,0 +1,38 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local ttl_us = tonumber(ARGV[3])
+local ttl_ms = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5] or "1")
+
+-- Leases are scored by their expiry, so anything at or before now has been abandoned.
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[2])
+local held = redis.call("ZCARD", KEYS[1])
+
+local allowed = 0
+local lease_id = 0
+if held + cost <= limit then
+  allowed = 1
+  local last_id = redis.call("INCRBY", KEYS[2], cost)
+  lease_id = last_id - cost + 1
+  for id = lease_id, last_id do
+    redis.call("ZADD", KEYS[1], now_us + ttl_us, id)
+  end
+  held = held + cost
+  redis.call("PEXPIRE", KEYS[1], ttl_ms)
+  redis.call("PEXPIRE", KEYS[2], ttl_ms)
+end
+
+local reset_us = 0
+if held > 0 then
+  local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+  reset_us = tonumber(newest[2]) - now_us
+end
+
+local retry_after_us = 0
+if allowed == 0 then
+  local needed = held + cost - limit
+  local blocking = redis.call("ZRANGE", KEYS[1], needed - 1, needed - 1, "WITHSCORES")
+  retry_after_us = tonumber(blocking[2]) - now_us
+end
+
+return {allowed, limit - held, reset_us, retry_after_us, lease_id}
@@ -0,0 +1,23 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+
+local live = redis.call("ZRANGEBYSCORE", KEYS[1], "(" .. ARGV[2], "+inf", "WITHSCORES")
+local held = #live / 2
+
+local allowed = 0
+if held < limit then
+  allowed = 1
+end
+
+local reset_us = 0
+if held > 0 then
+  reset_us = tonumber(live[#live]) - now_us
+end
+
+local retry_after_us = 0
+if allowed == 0 then
+  local needed = held + 1 - limit
+  retry_after_us = tonumber(live[needed * 2]) - now_us
+end
+
+return {allowed, limit - held, reset_us, retry_after_us}
@@ -0,0 +1,6 @@
+local cost = tonumber(ARGV[2])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+local popped = redis.call("ZPOPMIN", KEYS[1], cost)
+
+return {#popped / 2}
@@ -0,0 +1,31 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local window_end_us = tonumber(ARGV[3])
+local ttl_ms = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+
+local allowed = 0
+if count + cost <= limit then
+  allowed = 1
+  count = redis.call("INCRBY", KEYS[1], cost)
+  redis.call("PEXPIRE", KEYS[1], ttl_ms)
+end
+
+local remaining = limit - count
+if remaining < 0 then
+  remaining = 0
+end
+
+local reset_us = window_end_us - now_us
+if reset_us < 0 then
+  reset_us = 0
+end
+
+local retry_after_us = 0
+if allowed == 0 then
+  retry_after_us = reset_us
+end
+
+return {allowed, remaining, reset_us, retry_after_us}
@@ -0,0 +1,11 @@
+local cost = tonumber(ARGV[1])
+
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+
+local refunded = math.min(cost, count)
+if refunded > 0 then
+  -- DECRBY keeps the window TTL untouched.
+  count = redis.call("DECRBY", KEYS[1], refunded)
+end
+
+return {count}
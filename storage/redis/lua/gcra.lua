@@ -0,0 +1,29 @@
+local emission_us = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local now_us = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4] or "1")
+local period_us = emission_us * limit
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tat < now_us then
+  tat = now_us
+end
+
+local new_tat = tat + (emission_us * cost)
+local allow_at = new_tat - period_us
+
+local allowed = 0
+local retry_after_us = 0
+if allow_at <= now_us then
+  allowed = 1
+  tat = new_tat
+  -- The key expires exactly when it has fully recovered.
+  redis.call("SET", KEYS[1], tat, "PX", math.ceil((tat - now_us) / 1000))
+else
+  retry_after_us = allow_at - now_us
+end
+
+local reset_us = tat - now_us
+local remaining = math.floor((period_us - reset_us) / emission_us)
+
+return {allowed, remaining, reset_us, retry_after_us}
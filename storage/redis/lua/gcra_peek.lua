@@ -0,0 +1,23 @@
+local emission_us = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local now_us = tonumber(ARGV[3])
+local period_us = emission_us * limit
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tat < now_us then
+  tat = now_us
+end
+
+local allow_at = tat + emission_us - period_us
+
+local allowed = 1
+local retry_after_us = 0
+if allow_at > now_us then
+  allowed = 0
+  retry_after_us = allow_at - now_us
+end
+
+local reset_us = tat - now_us
+local remaining = math.floor((period_us - reset_us) / emission_us)
+
+return {allowed, remaining, reset_us, retry_after_us}
@@ -0,0 +1,14 @@
+local emission_us = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or "0")
+tat = tat - (emission_us * cost)
+
+if tat <= now_us then
+  redis.call("DEL", KEYS[1])
+  return {0}
+end
+
+redis.call("SET", KEYS[1], tat, "PX", math.ceil((tat - now_us) / 1000))
+return {tat - now_us}
@@ -0,0 +1,7 @@
+local delta = ARGV[1]
+local ttl_ms = tonumber(ARGV[2])
+
+local value = redis.call("INCRBYFLOAT", KEYS[1], delta)
+redis.call("PEXPIRE", KEYS[1], ttl_ms)
+
+return value
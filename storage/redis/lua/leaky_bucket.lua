@@ -2,6 +2,7 @@ local limit = tonumber(ARGV[1])
 local now_us = tonumber(ARGV[2])
 local window_us = tonumber(ARGV[3])
 local ttl_ms = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5] or "1")
 
 local us_per_token = math.floor(window_us / limit)
 if us_per_token < 1 then
@@ -27,13 +28,13 @@ else
 end
 
 local allowed = 0
-if water < limit then
+if water + cost <= limit then
   allowed = 1
-  water = water + 1
+  water = water + cost
 end
 
 local elapsed_since_leak = now_us - last_leak
-local next_leak = us_per_token - elapsed_since_leak
+local next_leak = ((water + cost - limit) * us_per_token) - elapsed_since_leak
 if next_leak < 0 then
   next_leak = 0
 end
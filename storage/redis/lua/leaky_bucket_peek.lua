@@ -0,0 +1,56 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local window_us = tonumber(ARGV[3])
+
+local us_per_token = math.floor(window_us / limit)
+if us_per_token < 1 then
+  us_per_token = 1
+end
+
+local water = tonumber(redis.call("GET", KEYS[1]) or "0")
+local last_leak = tonumber(redis.call("GET", KEYS[2]) or "0")
+
+if last_leak == 0 then
+  water = 0
+  last_leak = now_us
+else
+  local elapsed = now_us - last_leak
+  local leaked = math.floor(elapsed / us_per_token)
+  if leaked > 0 then
+    water = water - leaked
+    if water < 0 then
+      water = 0
+    end
+    last_leak = last_leak + (leaked * us_per_token)
+  end
+end
+
+local allowed = 0
+if water < limit then
+  allowed = 1
+end
+
+local elapsed_since_leak = now_us - last_leak
+
+local reset_us = 0
+if water > 0 then
+  reset_us = (water * us_per_token) - elapsed_since_leak
+  if reset_us < 0 then
+    reset_us = 0
+  end
+end
+
+local remaining = limit - water
+if remaining < 0 then
+  remaining = 0
+end
+
+local retry_after_us = 0
+if allowed == 0 then
+  retry_after_us = ((water + 1 - limit) * us_per_token) - elapsed_since_leak
+  if retry_after_us < 0 then
+    retry_after_us = 0
+  end
+end
+
+return {allowed, remaining, reset_us, retry_after_us}
@@ -0,0 +1,35 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local window_us = tonumber(ARGV[3])
+local ttl_ms = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+
+local us_per_token = math.floor(window_us / limit)
+if us_per_token < 1 then
+  us_per_token = 1
+end
+
+local water = tonumber(redis.call("GET", KEYS[1]) or "0")
+local last_leak = tonumber(redis.call("GET", KEYS[2]) or "0")
+
+-- No state means the bucket is already empty.
+if last_leak == 0 then
+  return {0}
+end
+
+local elapsed = now_us - last_leak
+local leaked = math.floor(elapsed / us_per_token)
+if leaked > 0 then
+  water = water - leaked
+  last_leak = last_leak + (leaked * us_per_token)
+end
+
+water = water - cost
+if water < 0 then
+  water = 0
+end
+
+redis.call("SET", KEYS[1], water, "PX", ttl_ms)
+redis.call("SET", KEYS[2], last_leak, "PX", ttl_ms)
+
+return {water}
@@ -0,0 +1,33 @@
+-- Records a denial for a key and bans it once it reaches the threshold.
+-- KEYS: strikes, offences, ban.
+-- ARGV: now_us, threshold, period_ms, ban_us, escalation in millionths,
+--       max_ban_us (0 = unbounded), offence memory in ms.
+local now_us = tonumber(ARGV[1])
+local threshold = tonumber(ARGV[2])
+local period_ms = tonumber(ARGV[3])
+local ban_us = tonumber(ARGV[4])
+local escalation = tonumber(ARGV[5]) / 1000000
+local max_ban_us = tonumber(ARGV[6])
+local memory_ms = tonumber(ARGV[7])
+
+local strikes = redis.call("INCR", KEYS[1])
+if strikes == 1 then
+  redis.call("PEXPIRE", KEYS[1], period_ms)
+end
+if strikes < threshold then
+  return {0}
+end
+
+redis.call("DEL", KEYS[1])
+local offences = redis.call("INCR", KEYS[2])
+redis.call("PEXPIRE", KEYS[2], memory_ms)
+
+ban_us = ban_us * (escalation ^ (offences - 1))
+if max_ban_us > 0 and ban_us > max_ban_us then
+  ban_us = max_ban_us
+end
+ban_us = math.floor(ban_us)
+
+local until_us = now_us + ban_us
+redis.call("SET", KEYS[3], string.format("%d", until_us), "PX", math.max(1, math.ceil(ban_us / 1000)))
+return {until_us}
@@ -0,0 +1,36 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local window_us = tonumber(ARGV[3])
+local ttl_ms = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5] or "1")
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now_us - window_us)
+local count = redis.call("ZCARD", KEYS[1])
+
+local allowed = 0
+if count + cost <= limit then
+  allowed = 1
+  -- Members come from a counter so requests logged in the same microsecond stay distinct.
+  local last_id = redis.call("INCRBY", KEYS[2], cost)
+  for id = last_id - cost + 1, last_id do
+    redis.call("ZADD", KEYS[1], now_us, id)
+  end
+  count = count + cost
+  redis.call("PEXPIRE", KEYS[1], ttl_ms)
+  redis.call("PEXPIRE", KEYS[2], ttl_ms)
+end
+
+local reset_us = 0
+if count > 0 then
+  local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+  reset_us = tonumber(newest[2]) + window_us - now_us
+end
+
+local retry_after_us = 0
+if allowed == 0 then
+  local needed = count + cost - limit
+  local blocking = redis.call("ZRANGE", KEYS[1], needed - 1, needed - 1, "WITHSCORES")
+  retry_after_us = tonumber(blocking[2]) + window_us - now_us
+end
+
+return {allowed, limit - count, reset_us, retry_after_us}
@@ -0,0 +1,25 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local window_us = tonumber(ARGV[3])
+
+local cutoff = string.format("(%d", now_us - window_us)
+local live = redis.call("ZRANGEBYSCORE", KEYS[1], cutoff, "+inf", "WITHSCORES")
+local count = #live / 2
+
+local allowed = 0
+if count < limit then
+  allowed = 1
+end
+
+local reset_us = 0
+if count > 0 then
+  reset_us = tonumber(live[#live]) + window_us - now_us
+end
+
+local retry_after_us = 0
+if allowed == 0 then
+  local needed = count + 1 - limit
+  retry_after_us = tonumber(live[needed * 2]) + window_us - now_us
+end
+
+return {allowed, limit - count, reset_us, retry_after_us}
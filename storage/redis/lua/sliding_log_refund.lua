@@ -0,0 +1,8 @@
+local now_us = tonumber(ARGV[1])
+local window_us = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now_us - window_us)
+local popped = redis.call("ZPOPMAX", KEYS[1], cost)
+
+return {#popped / 2}
@@ -2,6 +2,7 @@ local limit = tonumber(ARGV[1])
 local now_us = tonumber(ARGV[2])
 local window_us = tonumber(ARGV[3])
 local ttl_ms = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5] or "1")
 
 local window_start = tonumber(redis.call("GET", KEYS[1]) or "0")
 local curr = tonumber(redis.call("GET", KEYS[2]) or "0")
@@ -35,10 +36,10 @@ local sliding = (prev * (1 - ratio)) + curr
 
 local allowed = 0
 local sliding_after = sliding
-if sliding < limit then
+if sliding + cost - 1 < limit then
   allowed = 1
-  curr = curr + 1
-  sliding_after = sliding_after + 1
+  curr = curr + cost
+  sliding_after = sliding_after + cost
 end
 
 redis.call("SET", KEYS[1], window_start, "PX", ttl_ms)
@@ -67,10 +68,10 @@ end
 
 local retry_after_us = 0
 if allowed == 0 then
-  if curr >= limit then
+  if curr + cost - 1 >= limit then
     retry_after_us = window_until_boundary
   elseif prev > 0 then
-    local required_ratio = 1 - ((limit - curr) / prev)
+    local required_ratio = 1 - ((limit - curr - cost + 1) / prev)
     local delay_ratio = required_ratio - ratio
     if delay_ratio < 0 then
       delay_ratio = 0
@@ -0,0 +1,37 @@
+local now_us = tonumber(ARGV[1])
+local window_us = tonumber(ARGV[2])
+local ttl_ms = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local window_start = tonumber(redis.call("GET", KEYS[1]) or "0")
+if window_start == 0 then
+  return {0, 0}
+end
+
+local curr = tonumber(redis.call("GET", KEYS[2]) or "0")
+local prev = tonumber(redis.call("GET", KEYS[3]) or "0")
+
+-- Roll the windows forward first, so units counted before the rollover come off the previous
+-- window, and units from older windows, already expired, are not credited anywhere.
+local elapsed = now_us - window_start
+if elapsed >= window_us then
+  local intervals = math.floor(elapsed / window_us)
+  if intervals == 1 then
+    prev = curr
+  else
+    prev = 0
+  end
+  curr = 0
+  window_start = window_start + (intervals * window_us)
+end
+
+local from_curr = math.min(cost, curr)
+local from_prev = math.min(cost - from_curr, prev)
+curr = curr - from_curr
+prev = prev - from_prev
+
+redis.call("SET", KEYS[1], window_start, "PX", ttl_ms)
+redis.call("SET", KEYS[2], curr, "PX", ttl_ms)
+redis.call("SET", KEYS[3], prev, "PX", ttl_ms)
+
+return {curr, prev}
@@ -2,6 +2,7 @@ local limit = tonumber(ARGV[1])
 local now_us = tonumber(ARGV[2])
 local ttl_ms = tonumber(ARGV[3])
 local time_per_token_us = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5] or "1")
 
 local tokens = tonumber(redis.call("GET", KEYS[1]) or "0")
 local last_refill = tonumber(redis.call("GET", KEYS[2]) or "0")
@@ -22,13 +23,13 @@ else
 end
 
 local allowed = 0
-if tokens > 0 then
+if tokens >= cost then
   allowed = 1
-  tokens = tokens - 1
+  tokens = tokens - cost
 end
 
 local elapsed_since_refill = now_us - last_refill
-local next_token_delay = time_per_token_us - elapsed_since_refill
+local next_token_delay = ((cost - tokens) * time_per_token_us) - elapsed_since_refill
 if next_token_delay < 0 then
   next_token_delay = 0
 end
@@ -0,0 +1,30 @@
+local limit = tonumber(ARGV[1])
+local now_us = tonumber(ARGV[2])
+local ttl_ms = tonumber(ARGV[3])
+local time_per_token_us = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+
+local tokens = tonumber(redis.call("GET", KEYS[1]) or "0")
+local last_refill = tonumber(redis.call("GET", KEYS[2]) or "0")
+
+-- No state means the bucket is already full.
+if last_refill == 0 then
+  return {limit}
+end
+
+local elapsed = now_us - last_refill
+local new_tokens = math.floor(elapsed / time_per_token_us)
+if new_tokens > 0 then
+  tokens = tokens + new_tokens
+  last_refill = last_refill + (new_tokens * time_per_token_us)
+end
+
+tokens = tokens + cost
+if tokens > limit then
+  tokens = limit
+end
+
+redis.call("SET", KEYS[1], tokens, "PX", ttl_ms)
+redis.call("SET", KEYS[2], last_refill, "PX", ttl_ms)
+
+return {tokens}
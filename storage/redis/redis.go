@@ -53,6 +53,25 @@ func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (f
 	return float64(val), nil
 }
 
+// IncrBy atomically adds delta to the numeric value at key.
+// If the key is missing or expired, initializes it to delta and sets TTL.
+func (s *RedisStore) IncrBy(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error) {
+	raw, err := s.runScript(ctx, scriptIncrByWithTTL, []string{key}, strconv.FormatFloat(delta, 'f', -1, 64), ttlMilliseconds(ttl))
+	if err != nil {
+		return 0, err
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected increment result type %T", raw)
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse increment result: %w", err)
+	}
+	return val, nil
+}
+
 // Get retrieves the numeric value at key, or 0 if not found/expired.
 func (s *RedisStore) Get(ctx context.Context, key string) (float64, error) {
 	str, err := s.client.Get(ctx, key).Result()
@@ -74,6 +93,15 @@ func (s *RedisStore) Set(ctx context.Context, key string, val float64, ttl time.
 	return s.client.Set(ctx, key, val, ttl).Err()
 }
 
+// Delete removes the given keys.
+// Callers deleting several keys in Redis Cluster must keep them in one hash slot.
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
 // Close closes the underlying Redis client connection.
 func (s *RedisStore) Close() error {
 	return s.client.Close()
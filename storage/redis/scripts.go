@@ -6,16 +6,43 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/AliRizaAynaci/gorl/v2/storage"
 	goredis "github.com/redis/go-redis/v9"
 )
 
 const (
 	scriptIncrWithTTL   = "incr_with_ttl"
+	scriptIncrByWithTTL = "incr_by_with_ttl"
+	scriptFixedWindow   = "fixed_window"
 	scriptSlidingWindow = "sliding_window"
 	scriptTokenBucket   = "token_bucket"
 	scriptLeakyBucket   = "leaky_bucket"
+	scriptConcurrency   = "concurrency"
+	scriptGCRA          = "gcra"
+	scriptSlidingLog    = "sliding_log"
+	scriptComposite     = "composite"
+	scriptAdaptiveLimit = "adaptive_limit"
+	scriptPenaltyStrike = "penalty_strike"
+
+	scriptSlidingWindowPeek = "sliding_window_peek"
+	scriptTokenBucketPeek   = "token_bucket_peek"
+	scriptLeakyBucketPeek   = "leaky_bucket_peek"
+	scriptConcurrencyPeek   = "concurrency_peek"
+	scriptGCRAPeek          = "gcra_peek"
+	scriptSlidingLogPeek    = "sliding_log_peek"
+
+	scriptFixedWindowRefund   = "fixed_window_refund"
+	scriptSlidingWindowRefund = "sliding_window_refund"
+	scriptTokenBucketRefund   = "token_bucket_refund"
+	scriptLeakyBucketRefund   = "leaky_bucket_refund"
+	scriptConcurrencyRefund   = "concurrency_refund"
+	scriptGCRARefund          = "gcra_refund"
+	scriptSlidingLogRefund    = "sliding_log_refund"
+
+	scriptConcurrencyRelease = "concurrency_release"
 )
 
 // Embed the whole script directory so editor/go list glob resolution does not
@@ -25,9 +52,53 @@ var luaScriptsFS embed.FS
 
 var scriptRegistry = map[string]*goredis.Script{
 	scriptIncrWithTTL:   goredis.NewScript(mustReadLuaScript("lua/incr_with_ttl.lua")),
+	scriptIncrByWithTTL: goredis.NewScript(mustReadLuaScript("lua/incr_by_with_ttl.lua")),
+	scriptFixedWindow:   goredis.NewScript(mustReadLuaScript("lua/fixed_window.lua")),
 	scriptSlidingWindow: goredis.NewScript(mustReadLuaScript("lua/sliding_window.lua")),
 	scriptTokenBucket:   goredis.NewScript(mustReadLuaScript("lua/token_bucket.lua")),
 	scriptLeakyBucket:   goredis.NewScript(mustReadLuaScript("lua/leaky_bucket.lua")),
+	scriptConcurrency:   goredis.NewScript(mustReadLuaScript("lua/concurrency.lua")),
+	scriptGCRA:          goredis.NewScript(mustReadLuaScript("lua/gcra.lua")),
+	scriptSlidingLog:    goredis.NewScript(mustReadLuaScript("lua/sliding_log.lua")),
+	scriptComposite:     goredis.NewScript(mustReadLuaScript("lua/composite.lua")),
+	scriptAdaptiveLimit: goredis.NewScript(mustReadLuaScript("lua/adaptive_limit.lua")),
+	scriptPenaltyStrike: goredis.NewScript(mustReadLuaScript("lua/penalty_strike.lua")),
+
+	scriptSlidingWindowPeek: goredis.NewScript(mustReadLuaScript("lua/sliding_window_peek.lua")),
+	scriptTokenBucketPeek:   goredis.NewScript(mustReadLuaScript("lua/token_bucket_peek.lua")),
+	scriptLeakyBucketPeek:   goredis.NewScript(mustReadLuaScript("lua/leaky_bucket_peek.lua")),
+	scriptConcurrencyPeek:   goredis.NewScript(mustReadLuaScript("lua/concurrency_peek.lua")),
+	scriptGCRAPeek:          goredis.NewScript(mustReadLuaScript("lua/gcra_peek.lua")),
+	scriptSlidingLogPeek:    goredis.NewScript(mustReadLuaScript("lua/sliding_log_peek.lua")),
+
+	scriptFixedWindowRefund:   goredis.NewScript(mustReadLuaScript("lua/fixed_window_refund.lua")),
+	scriptSlidingWindowRefund: goredis.NewScript(mustReadLuaScript("lua/sliding_window_refund.lua")),
+	scriptTokenBucketRefund:   goredis.NewScript(mustReadLuaScript("lua/token_bucket_refund.lua")),
+	scriptLeakyBucketRefund:   goredis.NewScript(mustReadLuaScript("lua/leaky_bucket_refund.lua")),
+	scriptConcurrencyRefund:   goredis.NewScript(mustReadLuaScript("lua/concurrency_refund.lua")),
+	scriptGCRARefund:          goredis.NewScript(mustReadLuaScript("lua/gcra_refund.lua")),
+	scriptSlidingLogRefund:    goredis.NewScript(mustReadLuaScript("lua/sliding_log_refund.lua")),
+
+	scriptConcurrencyRelease: goredis.NewScript(mustReadLuaScript("lua/concurrency_release.lua")),
+}
+
+var scriptMu sync.RWMutex
+
+// RegisterScript makes a Lua script available to EvalScript under name, so third-party
+// strategies can run their own atomic transitions. Scripts should return an array of integers.
+// Registering an empty name or source, or a name already in use, returns an error.
+func RegisterScript(name, source string) error {
+	if name == "" || source == "" {
+		return fmt.Errorf("redis script name and source must not be empty")
+	}
+
+	scriptMu.Lock()
+	defer scriptMu.Unlock()
+	if _, exists := scriptRegistry[name]; exists {
+		return fmt.Errorf("redis script %q is already registered", name)
+	}
+	scriptRegistry[name] = goredis.NewScript(source)
+	return nil
 }
 
 func mustReadLuaScript(path string) string {
@@ -46,17 +117,14 @@ func ttlMilliseconds(ttl time.Duration) int64 {
 	return ms
 }
 
-func (s *RedisStore) runScript(ctx context.Context, name string, keys []string, args ...int64) (interface{}, error) {
+func (s *RedisStore) runScript(ctx context.Context, name string, keys []string, argv ...interface{}) (interface{}, error) {
+	scriptMu.RLock()
 	script, ok := scriptRegistry[name]
+	scriptMu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("unknown redis script %q", name)
 	}
 
-	argv := make([]interface{}, len(args))
-	for i, arg := range args {
-		argv[i] = arg
-	}
-
 	res, err := script.Run(ctx, s.client, keys, argv...).Result()
 	if err != nil {
 		return nil, err
@@ -66,11 +134,63 @@ func (s *RedisStore) runScript(ctx context.Context, name string, keys []string,
 
 // EvalScript runs a named Lua script and converts its result array into int64 values.
 func (s *RedisStore) EvalScript(ctx context.Context, name string, keys []string, args ...int64) ([]int64, error) {
-	raw, err := s.runScript(ctx, name, keys, args...)
+	raw, err := s.runScript(ctx, name, keys, scriptArgs(args)...)
 	if err != nil {
 		return nil, err
 	}
+	return int64Values(raw)
+}
+
+// EvalScripts runs many named Lua scripts in one pipelined round trip of EVALSHA commands.
+// Scripts the server has not cached yet are retried on their own, which loads them.
+func (s *RedisStore) EvalScripts(ctx context.Context, calls []storage.ScriptCall) []storage.ScriptReply {
+	replies := make([]storage.ScriptReply, len(calls))
+	scripts := make([]*goredis.Script, len(calls))
+	scriptMu.RLock()
+	for i, call := range calls {
+		scripts[i] = scriptRegistry[call.Name]
+	}
+	scriptMu.RUnlock()
+
+	cmds := make([]*goredis.Cmd, len(calls))
+	pipe := s.client.Pipeline()
+	for i, call := range calls {
+		if scripts[i] == nil {
+			replies[i].Err = fmt.Errorf("unknown redis script %q", call.Name)
+			continue
+		}
+		cmds[i] = scripts[i].EvalSha(ctx, pipe, call.Keys, scriptArgs(call.Args)...)
+	}
+	// Errors are reported per command below.
+	_, _ = pipe.Exec(ctx)
+
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		raw, err := cmd.Result()
+		if err != nil && goredis.HasErrorPrefix(err, "NOSCRIPT") {
+			raw, err = scripts[i].Run(ctx, s.client, calls[i].Keys, scriptArgs(calls[i].Args)...).Result()
+		}
+		if err != nil {
+			replies[i].Err = err
+			continue
+		}
+		replies[i].Values, replies[i].Err = int64Values(raw)
+	}
+	return replies
+}
+
+func scriptArgs(args []int64) []interface{} {
+	argv := make([]interface{}, len(args))
+	for i, arg := range args {
+		argv[i] = arg
+	}
+	return argv
+}
 
+// int64Values converts a script result array into int64 values.
+func int64Values(raw interface{}) ([]int64, error) {
 	items, ok := raw.([]interface{})
 	if !ok {
 		return nil, fmt.Errorf("unexpected redis script result type %T", raw)
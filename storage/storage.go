@@ -8,13 +8,17 @@ import (
 )
 
 // Storage defines a minimal key-value interface for rate limiting.
-// Implementations need only support Get, Set, Incr with TTL, and Close.
+// Implementations need only support Get, Set, Incr/IncrBy with TTL, Delete, and Close.
 // All complex algorithm logic lives in the algorithms package.
 type Storage interface {
 	// Incr atomically increments the numeric value at key by 1.
 	// If the key is missing or expired, it initializes it to 1 and applies TTL.
 	Incr(ctx context.Context, key string, ttl time.Duration) (float64, error)
 
+	// IncrBy atomically adds delta (which may be negative) to the numeric value at key.
+	// If the key is missing or expired, it initializes it to delta and applies TTL.
+	IncrBy(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error)
+
 	// Get retrieves the numeric value stored at key.
 	// Returns 0 if the key does not exist or has expired.
 	Get(ctx context.Context, key string) (float64, error)
@@ -22,6 +26,43 @@ type Storage interface {
 	// Set stores the numeric value at key with the given TTL.
 	Set(ctx context.Context, key string, val float64, ttl time.Duration) error
 
+	// Delete removes the given keys. Missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+
 	// Close releases any resources held by the storage backend.
 	Close() error
 }
+
+// Clock tells backends that keep their own expiry what time it is. core.Clock satisfies it.
+type Clock interface {
+	Now() time.Time
+}
+
+// ScriptRunner is implemented by backends that can run named server-side scripts atomically,
+// such as the Redis store. Limiters type-assert their store to ScriptRunner to take an atomic
+// path and fall back to the Storage methods otherwise.
+type ScriptRunner interface {
+	// EvalScript runs the script registered under name and returns its result array as int64 values.
+	EvalScript(ctx context.Context, name string, keys []string, args ...int64) ([]int64, error)
+}
+
+// ScriptCall is one named script invocation sent through a ScriptPipeliner.
+type ScriptCall struct {
+	Name string
+	Keys []string
+	Args []int64
+}
+
+// ScriptReply is the outcome of one ScriptCall.
+type ScriptReply struct {
+	Values []int64
+	Err    error
+}
+
+// ScriptPipeliner is implemented by backends that can run many scripts in one round trip,
+// such as the Redis store. Each script runs atomically, but the calls are not atomic as a group.
+type ScriptPipeliner interface {
+	ScriptRunner
+	// EvalScripts runs every call and returns one reply per call, in order.
+	EvalScripts(ctx context.Context, calls []ScriptCall) []ScriptReply
+}
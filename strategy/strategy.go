@@ -0,0 +1,84 @@
+// Package strategy provides the helpers built-in algorithms use, so that third-party strategies
+// registered with gorl.RegisterStrategy can behave the same way: validate costs, honour
+// Config.FailOpen, report metrics and parse results of atomic Redis scripts.
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// ValidateCost rejects weighted requests that can never be granted.
+// A cost larger than the limit would otherwise be denied forever with a misleading RetryAfter.
+func ValidateCost(n, limit int) error {
+	if n <= 0 {
+		return fmt.Errorf("%w: cost must be greater than 0, got %d", core.ErrInvalidCost, n)
+	}
+	if n > limit {
+		return fmt.Errorf("%w: cost %d is greater than limit %d", core.ErrCostExceedsLimit, n, limit)
+	}
+	return nil
+}
+
+// ValidateRefund rejects refunds that would not give anything back.
+func ValidateRefund(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("%w: refund must be greater than 0, got %d", core.ErrInvalidCost, n)
+	}
+	return nil
+}
+
+// HandleFailure centralizes fail-open logic.
+//   - start: timestamp when Allow began (for latency metrics)
+//   - err: storage/algorithm error
+//   - failOpen: cfg.FailOpen flag
+//   - m: metrics collector
+//
+// Returns (result, retErr, done):
+//   - done=true: caller should return immediately with (result, retErr)
+//   - done=false: no error, continue normal flow
+func HandleFailure(start time.Time, err error, failOpen bool, m core.MetricsCollector, limit int) (core.Result, error, bool) {
+	if err == nil {
+		return core.Result{}, nil, false
+	}
+	if failOpen {
+		m.ObserveLatency(time.Since(start))
+		m.IncAllow()
+		return core.Result{Allowed: true, Limit: limit}, nil, true
+	}
+	return core.Result{Allowed: false, Limit: limit}, err, true
+}
+
+// RecordDecision reports the latency since start and the outcome of an admission decision.
+func RecordDecision(m core.MetricsCollector, start time.Time, allowed bool) {
+	m.ObserveLatency(time.Since(start))
+	if allowed {
+		m.IncAllow()
+	} else {
+		m.IncDeny()
+	}
+}
+
+// ScriptResult converts the result of an admission script into a Result.
+// Scripts return {allowed (0 or 1), remaining, reset_us, retry_after_us}.
+func ScriptResult(limit int, values []int64) (core.Result, error) {
+	if len(values) != 4 {
+		return core.Result{}, fmt.Errorf("unexpected redis script result length: %d", len(values))
+	}
+	return core.Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(values[1]),
+		Reset:      microsToDuration(values[2]),
+		RetryAfter: microsToDuration(values[3]),
+	}, nil
+}
+
+func microsToDuration(us int64) time.Duration {
+	if us <= 0 {
+		return 0
+	}
+	return time.Duration(us) * time.Microsecond
+}
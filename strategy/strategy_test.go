@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+type countingMetrics struct {
+	allows, denies, latencies int
+}
+
+func (m *countingMetrics) IncAllow()                      { m.allows++ }
+func (m *countingMetrics) IncDeny()                       { m.denies++ }
+func (m *countingMetrics) ObserveLatency(_ time.Duration) { m.latencies++ }
+
+func TestHandleFailure(t *testing.T) {
+	m := &countingMetrics{}
+	if _, _, done := HandleFailure(time.Now(), nil, true, m, 10); done {
+		t.Fatal("expected done=false without an error")
+	}
+
+	res, err, done := HandleFailure(time.Now(), errors.New("down"), true, m, 10)
+	if !done || err != nil || !res.Allowed || m.allows != 1 {
+		t.Fatalf("expected fail-open allow, got %+v, err %v, done %v", res, err, done)
+	}
+
+	res, err, done = HandleFailure(time.Now(), errors.New("down"), false, m, 10)
+	if !done || err == nil || res.Allowed || res.Limit != 10 {
+		t.Fatalf("expected fail-closed error, got %+v, err %v, done %v", res, err, done)
+	}
+}
+
+func TestRecordDecision(t *testing.T) {
+	m := &countingMetrics{}
+	RecordDecision(m, time.Now(), true)
+	RecordDecision(m, time.Now(), false)
+	if m.allows != 1 || m.denies != 1 || m.latencies != 2 {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+}
+
+func TestValidateCostAndRefund(t *testing.T) {
+	if err := ValidateCost(0, 5); !errors.Is(err, core.ErrInvalidCost) {
+		t.Fatalf("expected ErrInvalidCost, got %v", err)
+	}
+	if err := ValidateCost(6, 5); !errors.Is(err, core.ErrCostExceedsLimit) {
+		t.Fatalf("expected ErrCostExceedsLimit, got %v", err)
+	}
+	if err := ValidateCost(5, 5); err != nil {
+		t.Fatalf("expected cost equal to the limit to be valid, got %v", err)
+	}
+	if err := ValidateRefund(-1); !errors.Is(err, core.ErrInvalidCost) {
+		t.Fatalf("expected ErrInvalidCost, got %v", err)
+	}
+}
+
+func TestScriptResult(t *testing.T) {
+	res, err := ScriptResult(10, []int64{0, 3, 2000, 500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Limit != 10 || res.Remaining != 3 ||
+		res.Reset != 2*time.Millisecond || res.RetryAfter != 500*time.Microsecond {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	if _, err := ScriptResult(10, []int64{1, 2}); err == nil {
+		t.Fatal("expected an error for a short result")
+	}
+}
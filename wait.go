@@ -0,0 +1,239 @@
+package gorl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+// minWaitDelay keeps Wait from spinning when a denial carries no usable RetryAfter.
+const minWaitDelay = time.Millisecond
+
+// Wait blocks until limiter grants one unit for key.
+// See WaitN for the cancellation and deadline semantics.
+func Wait(ctx context.Context, limiter core.Limiter, key string) (core.Result, error) {
+	return WaitN(ctx, limiter, key, 1)
+}
+
+// WaitN blocks until limiter grants n units for key, sleeping for the RetryAfter
+// reported by each denial before trying again.
+//
+// It returns ctx.Err() if the context is done while sleeping, and an error wrapping
+// core.ErrWouldExceedDeadline without sleeping when the reported RetryAfter already
+// reaches past the context deadline. A denylisted key is never retried: WaitN returns
+// core.ErrKeyDenied at once. Limiter errors are returned as-is.
+func WaitN(ctx context.Context, limiter core.Limiter, key string, n int) (core.Result, error) {
+	for {
+		res, err := limiter.AllowN(ctx, key, n)
+		if err != nil || res.Allowed {
+			return res, err
+		}
+		if res.Bypassed {
+			return res, core.ErrKeyDenied
+		}
+		if err := sleepUntil(ctx, time.Now().Add(retryDelay(res)), nil); err != nil {
+			return res, err
+		}
+	}
+}
+
+// Reservation describes a permit requested through Reserve or ReserveN.
+//
+// A reservation is either granted immediately, in which case the permit has
+// already been consumed, or pending, in which case TimeToAct reports when the
+// limiter expects to have capacity again. Pending reservations acquire their
+// permit through Wait. Cancel releases the reservation in either state.
+type Reservation struct {
+	limiter core.Limiter
+	key     string
+	n       int
+
+	mu        sync.Mutex
+	result    core.Result
+	timeToAct time.Time
+	granted   bool
+	canceled  bool
+	cancelCh  chan struct{}
+}
+
+// Reserve asks limiter for one unit for key without blocking.
+func Reserve(ctx context.Context, limiter core.Limiter, key string) (*Reservation, error) {
+	return ReserveN(ctx, limiter, key, 1)
+}
+
+// ReserveN asks limiter for n units for key without blocking.
+// The returned reservation reports when the caller may act; limiter errors are returned as-is.
+func ReserveN(ctx context.Context, limiter core.Limiter, key string, n int) (*Reservation, error) {
+	res, err := limiter.AllowN(ctx, key, n)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reservation{
+		limiter:   limiter,
+		key:       key,
+		n:         n,
+		result:    res,
+		timeToAct: time.Now(),
+		granted:   res.Allowed,
+		cancelCh:  make(chan struct{}),
+	}
+	if !res.Allowed {
+		r.timeToAct = r.timeToAct.Add(retryDelay(res))
+	}
+	return r, nil
+}
+
+// Granted reports whether the permit has already been consumed on the caller's behalf.
+func (r *Reservation) Granted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.granted
+}
+
+// Result returns the limiter decision behind the reservation's current state.
+func (r *Reservation) Result() core.Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result
+}
+
+// TimeToAct returns the earliest time at which the caller may act on the reservation.
+func (r *Reservation) TimeToAct() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.timeToAct
+}
+
+// Delay returns how long the caller must wait before acting, or 0 if the permit is granted.
+func (r *Reservation) Delay() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.granted {
+		return 0
+	}
+	return clampDelay(time.Until(r.timeToAct))
+}
+
+// Wait blocks until the reservation's permit is granted.
+// It follows the same deadline, cancellation and denylist rules as WaitN and returns
+// core.ErrReservationCanceled once Cancel has been called.
+func (r *Reservation) Wait(ctx context.Context) error {
+	for {
+		r.mu.Lock()
+		canceled, granted, timeToAct, denied := r.canceled, r.granted, r.timeToAct, r.result.Bypassed
+		r.mu.Unlock()
+
+		switch {
+		case canceled:
+			return core.ErrReservationCanceled
+		case granted:
+			return nil
+		case denied:
+			return core.ErrKeyDenied
+		}
+
+		if err := sleepUntil(ctx, timeToAct, r.cancelCh); err != nil {
+			return err
+		}
+		if err := r.acquire(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// acquire retries the limiter once and records the outcome.
+// The limiter is called without holding the lock, so Cancel and the accessors never wait on a
+// slow backend. A permit granted after Cancel, or after another Wait already got one, is handed
+// straight back.
+func (r *Reservation) acquire(ctx context.Context) error {
+	r.mu.Lock()
+	done := r.canceled || r.granted
+	r.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	res, err := r.limiter.AllowN(ctx, r.key, r.n)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.canceled || r.granted {
+		r.mu.Unlock()
+		if res.Allowed {
+			return r.limiter.Refund(context.WithoutCancel(ctx), r.key, r.n)
+		}
+		return nil
+	}
+	r.result = res
+	r.granted = res.Allowed
+	if !res.Allowed {
+		r.timeToAct = time.Now().Add(retryDelay(res))
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// Cancel gives up the reservation. A pending reservation stops trying to acquire
+// its permit and wakes any goroutine blocked in Wait; a granted permit is handed
+// back to the limiter through Refund. Calling Cancel more than once is a no-op.
+func (r *Reservation) Cancel(ctx context.Context) error {
+	r.mu.Lock()
+	if r.canceled {
+		r.mu.Unlock()
+		return nil
+	}
+	r.canceled = true
+	close(r.cancelCh)
+	granted := r.granted
+	r.granted = false
+	r.mu.Unlock()
+
+	if !granted {
+		return nil
+	}
+	return r.limiter.Refund(ctx, r.key, r.n)
+}
+
+// sleepUntil waits until t, failing fast when t lies beyond the context deadline.
+// A nil cancel channel never fires.
+func sleepUntil(ctx context.Context, t time.Time, cancel <-chan struct{}) error {
+	if deadline, ok := ctx.Deadline(); ok && t.After(deadline) {
+		return fmt.Errorf("%w: permit available in %v", core.ErrWouldExceedDeadline, clampDelay(time.Until(t)))
+	}
+
+	delay := time.Until(t)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-cancel:
+		return core.ErrReservationCanceled
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryDelay(res core.Result) time.Duration {
+	if res.RetryAfter < minWaitDelay {
+		return minWaitDelay
+	}
+	return res.RetryAfter
+}
+
+func clampDelay(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,307 @@
+package gorl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliRizaAynaci/gorl/v2/core"
+)
+
+func TestWait_BlocksUntilPermitIsGranted(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.TokenBucket,
+		Limit:    2,
+		Window:   200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := Wait(ctx, limiter, "worker"); err != nil {
+			t.Fatalf("unexpected error on request %d: %v", i+1, err)
+		}
+	}
+
+	start := time.Now()
+	res, err := Wait(ctx, limiter, "worker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed {
+		t.Fatal("expected Wait to return an allowed result")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected Wait to block for the refill interval, returned after %v", elapsed)
+	}
+}
+
+func TestWait_FailsFastWhenRetryAfterExceedsDeadline(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, err := limiter.Allow(context.Background(), "worker"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = Wait(ctx, limiter, "worker")
+	if !errors.Is(err, core.ErrWouldExceedDeadline) {
+		t.Fatalf("expected ErrWouldExceedDeadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected Wait to fail without sleeping, took %v", elapsed)
+	}
+}
+
+func TestWait_StopsWhenContextIsCanceled(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, err := limiter.Allow(context.Background(), "worker"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if _, err := Wait(ctx, limiter, "worker"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestReserve_GrantedImmediately(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.LeakyBucket,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	r, err := Reserve(context.Background(), limiter, "worker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Granted() {
+		t.Fatal("expected first reservation to be granted")
+	}
+	if r.Delay() != 0 {
+		t.Fatalf("expected zero delay, got %v", r.Delay())
+	}
+	if err := r.Wait(context.Background()); err != nil {
+		t.Fatalf("expected Wait on a granted reservation to return immediately, got %v", err)
+	}
+}
+
+func TestReserve_PendingReportsDelayAndWaits(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.TokenBucket,
+		Limit:    1,
+		Window:   100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if _, err := limiter.Allow(ctx, "worker"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := Reserve(ctx, limiter, "worker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Granted() {
+		t.Fatal("expected reservation to be pending")
+	}
+	if delay := r.Delay(); delay <= 0 || delay > 100*time.Millisecond {
+		t.Fatalf("expected delay within the refill interval, got %v", delay)
+	}
+	if r.Result().RetryAfter <= 0 {
+		t.Fatalf("expected pending result to carry retry_after, got %v", r.Result().RetryAfter)
+	}
+
+	if err := r.Wait(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Granted() {
+		t.Fatal("expected reservation to be granted after Wait")
+	}
+}
+
+func TestReserve_CancelWakesWaiter(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if _, err := limiter.Allow(ctx, "worker"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := Reserve(ctx, limiter, "worker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.AfterFunc(20*time.Millisecond, func() { r.Cancel(ctx) })
+
+	if err := r.Wait(ctx); !errors.Is(err, core.ErrReservationCanceled) {
+		t.Fatalf("expected ErrReservationCanceled, got %v", err)
+	}
+	if r.Granted() {
+		t.Fatal("canceled reservation must not be granted")
+	}
+}
+
+func TestReserve_CancelRefundsGrantedPermit(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	r, err := Reserve(ctx, limiter, "worker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Granted() {
+		t.Fatal("expected reservation to be granted")
+	}
+
+	if err := r.Cancel(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := limiter.Allow(ctx, "worker")
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected canceled permit to be available again, got %v, err %v", res.Allowed, err)
+	}
+}
+
+// slowLimiter blocks AllowN until release is closed and counts refunds.
+type slowLimiter struct {
+	core.Limiter
+	entered chan struct{}
+	release chan struct{}
+	refunds int
+}
+
+func (l *slowLimiter) AllowN(ctx context.Context, key string, n int) (core.Result, error) {
+	close(l.entered)
+	<-l.release
+	return core.Result{Allowed: true, Limit: 1}, nil
+}
+
+func (l *slowLimiter) Refund(ctx context.Context, key string, n int) error {
+	l.refunds++
+	return nil
+}
+
+func TestReserve_CancelDoesNotWaitOnLimiter(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limiter.Allow(ctx, "worker")
+	r, err := Reserve(ctx, limiter, "worker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slow := &slowLimiter{Limiter: limiter, entered: make(chan struct{}), release: make(chan struct{})}
+	r.limiter = slow
+	r.timeToAct = time.Now()
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- r.Wait(ctx) }()
+
+	<-slow.entered
+	canceled := make(chan error, 1)
+	go func() { canceled <- r.Cancel(ctx) }()
+	select {
+	case err := <-canceled:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Cancel blocked on the limiter call")
+	}
+
+	close(slow.release)
+	if err := <-waitErr; !errors.Is(err, core.ErrReservationCanceled) {
+		t.Fatalf("expected ErrReservationCanceled, got %v", err)
+	}
+	if r.Granted() || slow.refunds != 1 {
+		t.Fatalf("expected the late permit handed back, got granted=%v refunds=%d", r.Granted(), slow.refunds)
+	}
+}
+
+func TestWait_ReturnsErrKeyDeniedForDenylistedKey(t *testing.T) {
+	limiter, err := New(core.Config{
+		Strategy: core.FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+		Bypass:   core.BypassPolicy{Deny: core.AccessList{Keys: []string{"blocked"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := Wait(ctx, limiter, "blocked"); !errors.Is(err, core.ErrKeyDenied) {
+		t.Fatalf("expected ErrKeyDenied from Wait, got %v", err)
+	}
+
+	r, err := Reserve(ctx, limiter, "blocked")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Wait(ctx); !errors.Is(err, core.ErrKeyDenied) {
+		t.Fatalf("expected ErrKeyDenied from Reservation.Wait, got %v", err)
+	}
+}